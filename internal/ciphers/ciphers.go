@@ -0,0 +1,317 @@
+// Package ciphers evaluates OpenSSL style cipher strings into the cipher suites tmm would offer
+package ciphers
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Entry is a suite offered for a protocol, tmm listing each suite once per protocol
+type Entry struct {
+	Suite
+	Protocol string
+}
+
+// Ordering options of cipher groups
+const (
+	OrderingDefault  = "default"
+	OrderingSpeed    = "speed"
+	OrderingStrength = "strength"
+)
+
+type matcher func(e Entry, version int) bool
+
+func suiteMatcher(match func(s Suite) bool) matcher {
+	return func(e Entry, _ int) bool {
+		return match(e.Suite)
+	}
+}
+
+func protocolMatcher(protocol string) matcher {
+	return func(e Entry, _ int) bool {
+		return e.Protocol == protocol
+	}
+}
+
+func kxMatcher(keyExchanges ...string) matcher {
+	return suiteMatcher(func(s Suite) bool {
+		return slices.Contains(keyExchanges, s.KeyExchange)
+	})
+}
+
+func cipherMatcher(ciphers ...string) matcher {
+	return suiteMatcher(func(s Suite) bool {
+		return slices.Contains(ciphers, s.Cipher) && !s.tripleDES()
+	})
+}
+
+func macMatcher(mac string) matcher {
+	return suiteMatcher(func(s Suite) bool {
+		return s.Mac == mac
+	})
+}
+
+func strengthMatcher(strength string) matcher {
+	return suiteMatcher(func(s Suite) bool {
+		return s.Strength == strength
+	})
+}
+
+func none(Entry, int) bool {
+	return false
+}
+
+func isDefault(e Entry, version int) bool {
+	return e.Protocol != ProtocolSSL3 && e.inDefault(version)
+}
+
+func isAll(e Entry, _ int) bool {
+	return e.Strength != StrengthNull
+}
+
+var (
+	tripleDES = suiteMatcher(Suite.tripleDES)
+	aes       = cipherMatcher("AES", "AES-GCM")
+)
+
+// keywords are matched case insensitively, unlike OpenSSL, as tmm does
+var keywords = map[string]matcher{
+	"ALL":                 isAll,
+	"DEFAULT":             isDefault,
+	"COMPLEMENTOFDEFAULT": func(e Entry, version int) bool { return isAll(e, version) && !isDefault(e, version) },
+	"COMPLEMENTOFALL":     strengthMatcher(StrengthNull),
+	"NATIVE":              isAll,
+	"COMPAT":              none,
+	"HIGH":                strengthMatcher(StrengthHigh),
+	"MEDIUM":              strengthMatcher(StrengthMedium),
+	"LOW":                 strengthMatcher(StrengthLow),
+	"EXPORT":              none,
+	"EXP":                 none,
+	"NULL":                strengthMatcher(StrengthNull),
+	"ENULL":               strengthMatcher(StrengthNull),
+	"ANULL":               none,
+	"ADH":                 none,
+	"AECDH":               none,
+	"PSK":                 none,
+	"SRP":                 none,
+	"RSA":                 kxMatcher("RSA"),
+	"KRSA":                kxMatcher("RSA"),
+	"ARSA":                kxMatcher("RSA", "ECDHE_RSA", "EDH/RSA"),
+	"DHE":                 kxMatcher("EDH/RSA"),
+	"EDH":                 kxMatcher("EDH/RSA"),
+	"KDHE":                kxMatcher("EDH/RSA"),
+	"KEDH":                kxMatcher("EDH/RSA"),
+	"DH":                  kxMatcher("EDH/RSA"),
+	"ECDHE":               kxMatcher("ECDHE_RSA", "ECDHE_ECDSA", "ECDHE"),
+	"EECDH":               kxMatcher("ECDHE_RSA", "ECDHE_ECDSA", "ECDHE"),
+	"KECDHE":              kxMatcher("ECDHE_RSA", "ECDHE_ECDSA", "ECDHE"),
+	"KEECDH":              kxMatcher("ECDHE_RSA", "ECDHE_ECDSA", "ECDHE"),
+	"ECDHE_RSA":           kxMatcher("ECDHE_RSA"),
+	"ECDHE_ECDSA":         kxMatcher("ECDHE_ECDSA"),
+	"ECDSA":               kxMatcher("ECDHE_ECDSA"),
+	"AECDSA":              kxMatcher("ECDHE_ECDSA"),
+	"DSS":                 none,
+	"ADSS":                none,
+	"AES":                 aes,
+	"AES128":              func(e Entry, v int) bool { return aes(e, v) && e.Bits == 128 },
+	"AES256":              func(e Entry, v int) bool { return aes(e, v) && e.Bits == 256 },
+	"AESGCM":              cipherMatcher("AES-GCM"),
+	"AES-GCM":             cipherMatcher("AES-GCM"),
+	"CHACHA20":            cipherMatcher("CHACHA20-POLY1305"),
+	"CHACHA20-POLY1305":   cipherMatcher("CHACHA20-POLY1305"),
+	"3DES":                tripleDES,
+	"DES-CBC3":            tripleDES,
+	"DES":                 cipherMatcher("DES"),
+	"RC4":                 cipherMatcher("RC4"),
+	"CAMELLIA":            none,
+	"ARIA":                none,
+	"SHA":                 macMatcher("SHA1"),
+	"SHA1":                macMatcher("SHA1"),
+	"SHA256":              macMatcher("SHA256"),
+	"SHA384":              macMatcher("SHA384"),
+	"MD5":                 macMatcher("MD5"),
+	"SSLV3":               protocolMatcher(ProtocolSSL3),
+	"TLSV1":               protocolMatcher(ProtocolTLS1),
+	"TLSV1_1":             protocolMatcher(ProtocolTLS11),
+	"TLSV1.1":             protocolMatcher(ProtocolTLS11),
+	"TLSV1_2":             protocolMatcher(ProtocolTLS12),
+	"TLSV1.2":             protocolMatcher(ProtocolTLS12),
+	"TLSV1_3":             protocolMatcher(ProtocolTLS13),
+	"TLSV1.3":             protocolMatcher(ProtocolTLS13),
+}
+
+// resolve returns the matcher of a keyword or suite name
+func resolve(word string) (matcher, bool) {
+	upper := strings.ToUpper(word)
+	if m, ok := keywords[upper]; ok {
+		return m, true
+	}
+	for _, s := range suites {
+		if strings.ToUpper(s.Name) == upper || slices.ContainsFunc(s.Aliases, func(alias string) bool {
+			return strings.ToUpper(alias) == upper
+		}) {
+			id := s.ID
+			return suiteMatcher(func(s Suite) bool { return s.ID == id }), true
+		}
+	}
+	return nil, false
+}
+
+// Entries returns every suite and protocol supported by a TMOS major version, in native order
+func Entries(version int) []Entry {
+	var entries []Entry
+	for _, s := range suites {
+		if version < s.Since {
+			continue
+		}
+		for _, protocol := range s.Protocols {
+			entries = append(entries, Entry{Suite: s, Protocol: protocol})
+		}
+	}
+	return entries
+}
+
+func sameEntry(a, b Entry) bool {
+	return a.ID == b.ID && a.Protocol == b.Protocol
+}
+
+// Evaluate expands a cipher string into the ordered suites a TMOS major version offers for it
+func Evaluate(cipherString string, version int) ([]Entry, error) {
+	items := strings.FieldsFunc(cipherString, func(r rune) bool {
+		return r == ':' || r == ',' || r == ' '
+	})
+	if len(items) == 0 {
+		return nil, fmt.Errorf("cipher string is empty")
+	}
+
+	available := Entries(version)
+	var list, banned []Entry
+	contains := func(entries []Entry, e Entry) bool {
+		return slices.ContainsFunc(entries, func(o Entry) bool { return sameEntry(o, e) })
+	}
+
+	for _, item := range items {
+		switch strings.ToUpper(item) {
+		case "@STRENGTH":
+			Sort(list, OrderingStrength)
+			continue
+		case "@SPEED":
+			Sort(list, OrderingSpeed)
+			continue
+		}
+
+		op := item[0]
+		word := item
+		if op == '!' || op == '-' || op == '+' {
+			word = item[1:]
+		}
+
+		var matchers []matcher
+		for _, part := range strings.Split(word, "+") {
+			m, ok := resolve(part)
+			if !ok {
+				return nil, fmt.Errorf("invalid cipher string '%s': unknown keyword '%s'", cipherString, part)
+			}
+			matchers = append(matchers, m)
+		}
+		matches := func(e Entry) bool {
+			for _, m := range matchers {
+				if !m(e, version) {
+					return false
+				}
+			}
+			return true
+		}
+
+		switch op {
+		case '!':
+			list = slices.DeleteFunc(list, matches)
+			for _, e := range available {
+				if matches(e) && !contains(banned, e) {
+					banned = append(banned, e)
+				}
+			}
+		case '-':
+			list = slices.DeleteFunc(list, matches)
+		case '+':
+			var moved []Entry
+			list = slices.DeleteFunc(list, func(e Entry) bool {
+				if matches(e) {
+					moved = append(moved, e)
+					return true
+				}
+				return false
+			})
+			list = append(list, moved...)
+		default:
+			for _, e := range available {
+				if matches(e) && !contains(list, e) && !contains(banned, e) {
+					list = append(list, e)
+				}
+			}
+		}
+	}
+	return list, nil
+}
+
+// Validate checks the syntax and keywords of a cipher string
+func Validate(cipherString string) error {
+	_, err := Evaluate(cipherString, 0)
+	return err
+}
+
+// speed ranks the bulk ciphers, AEAD ciphers being the fastest
+func speed(s Suite) int {
+	switch {
+	case s.Cipher == "AES-GCM" || s.Cipher == "CHACHA20-POLY1305":
+		return 0
+	case s.Cipher == "AES":
+		return 1
+	case s.Cipher == "RC4":
+		return 2
+	case s.tripleDES():
+		return 4
+	default:
+		return 3
+	}
+}
+
+// Sort orders entries in place following a cipher group ordering, keeping the order of equal entries
+func Sort(entries []Entry, ordering string) {
+	switch ordering {
+	case OrderingStrength:
+		slices.SortStableFunc(entries, func(a, b Entry) int {
+			return cmp.Compare(b.Bits, a.Bits)
+		})
+	case OrderingSpeed:
+		slices.SortStableFunc(entries, func(a, b Entry) int {
+			return cmp.Or(cmp.Compare(speed(a.Suite), speed(b.Suite)), cmp.Compare(a.Bits, b.Bits))
+		})
+	}
+}
+
+// Union appends the entries of b missing from a
+func Union(a, b []Entry) []Entry {
+	for _, e := range b {
+		if !slices.ContainsFunc(a, func(o Entry) bool { return sameEntry(o, e) }) {
+			a = append(a, e)
+		}
+	}
+	return a
+}
+
+// Exclude returns the entries of a missing from b
+func Exclude(a, b []Entry) []Entry {
+	return slices.DeleteFunc(slices.Clone(a), func(e Entry) bool {
+		return slices.ContainsFunc(b, func(o Entry) bool { return sameEntry(o, e) })
+	})
+}
+
+// Intersect returns the entries of a also in b
+func Intersect(a, b []Entry) []Entry {
+	return slices.DeleteFunc(slices.Clone(a), func(e Entry) bool {
+		return !slices.ContainsFunc(b, func(o Entry) bool { return sameEntry(o, e) })
+	})
+}
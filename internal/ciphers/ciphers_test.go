@@ -0,0 +1,129 @@
+package ciphers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func names(entries []Entry) []string {
+	var result []string
+	for _, e := range entries {
+		result = append(result, e.Name+" "+e.Protocol)
+	}
+	return result
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name         string
+		cipherString string
+		version      int
+		want         []string
+		wantErr      string
+	}{
+		{
+			name:         "suite name",
+			cipherString: "ECDHE-RSA-AES128-GCM-SHA256",
+			version:      17,
+			want:         []string{"ECDHE-RSA-AES128-GCM-SHA256 TLS1.2"},
+		},
+		{
+			name:         "OpenSSL alias",
+			cipherString: "ECDHE-RSA-AES128-SHA",
+			version:      17,
+			want:         []string{"ECDHE-RSA-AES128-CBC-SHA TLS1", "ECDHE-RSA-AES128-CBC-SHA TLS1.1", "ECDHE-RSA-AES128-CBC-SHA TLS1.2"},
+		},
+		{
+			name:         "combined keywords",
+			cipherString: "ecdhe_ecdsa+AESGCM",
+			version:      17,
+			want:         []string{"ECDHE-ECDSA-AES128-GCM-SHA256 TLS1.2", "ECDHE-ECDSA-AES256-GCM-SHA384 TLS1.2"},
+		},
+		{
+			name:         "permanently removed suites are not added back",
+			cipherString: "!AES128:ECDHE_RSA+AESGCM",
+			version:      17,
+			want:         []string{"ECDHE-RSA-AES256-GCM-SHA384 TLS1.2"},
+		},
+		{
+			name:         "removed suites can be added back",
+			cipherString: "ECDHE_RSA+AESGCM:-AES128:AES128-GCM-SHA256:ECDHE-RSA-AES128-GCM-SHA256",
+			version:      17,
+			want:         []string{"ECDHE-RSA-AES256-GCM-SHA384 TLS1.2", "AES128-GCM-SHA256 TLS1.2", "ECDHE-RSA-AES128-GCM-SHA256 TLS1.2"},
+		},
+		{
+			name:         "moved to the end",
+			cipherString: "ECDHE_RSA+AESGCM:+AES128",
+			version:      17,
+			want:         []string{"ECDHE-RSA-AES256-GCM-SHA384 TLS1.2", "ECDHE-RSA-AES128-GCM-SHA256 TLS1.2"},
+		},
+		{
+			name:         "strength ordering",
+			cipherString: "RSA+AESGCM,@STRENGTH",
+			version:      17,
+			want:         []string{"AES256-GCM-SHA384 TLS1.2", "AES128-GCM-SHA256 TLS1.2"},
+		},
+		{
+			name:         "protocols",
+			cipherString: "DES-CBC3-SHA:!SSLv3:!TLSv1",
+			version:      12,
+			want:         []string{"DES-CBC3-SHA TLS1.1", "DES-CBC3-SHA TLS1.2"},
+		},
+		{
+			name:         "TLS 1.3 suites depend on the version",
+			cipherString: "TLSv1_3",
+			version:      13,
+		},
+		{
+			name:         "unknown keyword",
+			cipherString: "ECDHE:BOGUS",
+			wantErr:      "invalid cipher string 'ECDHE:BOGUS': unknown keyword 'BOGUS'",
+		},
+		{
+			name:         "empty",
+			cipherString: " : ",
+			wantErr:      "cipher string is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Evaluate(tt.cipherString, tt.version)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, names(entries))
+		})
+	}
+}
+
+func TestEvaluateDefault(t *testing.T) {
+	hasSuite := func(entries []Entry, name string) bool {
+		for _, e := range entries {
+			if e.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	v12, err := Evaluate("DEFAULT", 12)
+	require.NoError(t, err)
+	v17, err := Evaluate("DEFAULT", 17)
+	require.NoError(t, err)
+
+	require.True(t, hasSuite(v12, "RC4-SHA"))
+	require.True(t, hasSuite(v12, "DES-CBC3-SHA"))
+	require.False(t, hasSuite(v12, "TLS13-AES128-GCM-SHA256"))
+	require.False(t, hasSuite(v17, "RC4-SHA"))
+	require.False(t, hasSuite(v17, "DES-CBC3-SHA"))
+	require.True(t, hasSuite(v17, "TLS13-AES128-GCM-SHA256"))
+	require.False(t, hasSuite(v17, "NULL-SHA"))
+	require.Equal(t, "TLS13-AES128-GCM-SHA256", v17[0].Name)
+	for _, e := range v17 {
+		require.NotEqual(t, ProtocolSSL3, e.Protocol)
+	}
+}
@@ -0,0 +1,102 @@
+package ciphers
+
+// Protocols, as printed by tmm
+const (
+	ProtocolSSL3  = "SSL3"
+	ProtocolTLS1  = "TLS1"
+	ProtocolTLS11 = "TLS1.1"
+	ProtocolTLS12 = "TLS1.2"
+	ProtocolTLS13 = "TLS1.3"
+)
+
+// Strengths of the OpenSSL HIGH, MEDIUM, LOW and NULL keywords
+const (
+	StrengthHigh   = "HIGH"
+	StrengthMedium = "MEDIUM"
+	StrengthLow    = "LOW"
+	StrengthNull   = "NULL"
+)
+
+// Suite is a cipher suite supported by tmm
+type Suite struct {
+	ID   uint16
+	Name string
+	// Aliases are the OpenSSL names of suites tmm names differently
+	Aliases     []string
+	Bits        int
+	Protocols   []string
+	Cipher      string
+	Mac         string
+	KeyExchange string
+	Strength    string
+	// Since is the first TMOS major version supporting the suite
+	Since int
+	// DefaultUntil is the first TMOS major version whose DEFAULT cipher string excludes the suite,
+	// 0 if DEFAULT always includes it and -1 if it never does
+	DefaultUntil int
+}
+
+var (
+	legacyProtocols = []string{ProtocolSSL3, ProtocolTLS1, ProtocolTLS11, ProtocolTLS12}
+	tlsProtocols    = []string{ProtocolTLS1, ProtocolTLS11, ProtocolTLS12}
+	tls12Protocols  = []string{ProtocolTLS12}
+	tls13Protocols  = []string{ProtocolTLS13}
+)
+
+// suites are listed in the native order of tmm, the order of the DEFAULT cipher string
+var suites = []Suite{
+	{ID: 0x1301, Name: "TLS13-AES128-GCM-SHA256", Aliases: []string{"TLS_AES_128_GCM_SHA256"}, Bits: 128, Protocols: tls13Protocols, Cipher: "AES-GCM", Mac: "SHA256", KeyExchange: "ECDHE", Strength: StrengthHigh, Since: 14},
+	{ID: 0x1302, Name: "TLS13-AES256-GCM-SHA384", Aliases: []string{"TLS_AES_256_GCM_SHA384"}, Bits: 256, Protocols: tls13Protocols, Cipher: "AES-GCM", Mac: "SHA384", KeyExchange: "ECDHE", Strength: StrengthHigh, Since: 14},
+	{ID: 0x1303, Name: "TLS13-CHACHA20-POLY1305-SHA256", Aliases: []string{"TLS_CHACHA20_POLY1305_SHA256"}, Bits: 256, Protocols: tls13Protocols, Cipher: "CHACHA20-POLY1305", Mac: "SHA256", KeyExchange: "ECDHE", Strength: StrengthHigh, Since: 14},
+	{ID: 0xc02f, Name: "ECDHE-RSA-AES128-GCM-SHA256", Bits: 128, Protocols: tls12Protocols, Cipher: "AES-GCM", Mac: "SHA256", KeyExchange: "ECDHE_RSA", Strength: StrengthHigh},
+	{ID: 0xc013, Name: "ECDHE-RSA-AES128-CBC-SHA", Aliases: []string{"ECDHE-RSA-AES128-SHA"}, Bits: 128, Protocols: tlsProtocols, Cipher: "AES", Mac: "SHA1", KeyExchange: "ECDHE_RSA", Strength: StrengthHigh},
+	{ID: 0xc027, Name: "ECDHE-RSA-AES128-SHA256", Bits: 128, Protocols: tls12Protocols, Cipher: "AES", Mac: "SHA256", KeyExchange: "ECDHE_RSA", Strength: StrengthHigh},
+	{ID: 0xc030, Name: "ECDHE-RSA-AES256-GCM-SHA384", Bits: 256, Protocols: tls12Protocols, Cipher: "AES-GCM", Mac: "SHA384", KeyExchange: "ECDHE_RSA", Strength: StrengthHigh},
+	{ID: 0xc014, Name: "ECDHE-RSA-AES256-CBC-SHA", Aliases: []string{"ECDHE-RSA-AES256-SHA"}, Bits: 256, Protocols: tlsProtocols, Cipher: "AES", Mac: "SHA1", KeyExchange: "ECDHE_RSA", Strength: StrengthHigh},
+	{ID: 0xc028, Name: "ECDHE-RSA-AES256-SHA384", Bits: 256, Protocols: tls12Protocols, Cipher: "AES", Mac: "SHA384", KeyExchange: "ECDHE_RSA", Strength: StrengthHigh},
+	{ID: 0xcca8, Name: "ECDHE-RSA-CHACHA20-POLY1305-SHA256", Bits: 256, Protocols: tls12Protocols, Cipher: "CHACHA20-POLY1305", Mac: "SHA256", KeyExchange: "ECDHE_RSA", Strength: StrengthHigh, Since: 13},
+	{ID: 0xc02b, Name: "ECDHE-ECDSA-AES128-GCM-SHA256", Bits: 128, Protocols: tls12Protocols, Cipher: "AES-GCM", Mac: "SHA256", KeyExchange: "ECDHE_ECDSA", Strength: StrengthHigh},
+	{ID: 0xc009, Name: "ECDHE-ECDSA-AES128-SHA", Bits: 128, Protocols: tlsProtocols, Cipher: "AES", Mac: "SHA1", KeyExchange: "ECDHE_ECDSA", Strength: StrengthHigh},
+	{ID: 0xc023, Name: "ECDHE-ECDSA-AES128-SHA256", Bits: 128, Protocols: tls12Protocols, Cipher: "AES", Mac: "SHA256", KeyExchange: "ECDHE_ECDSA", Strength: StrengthHigh},
+	{ID: 0xc02c, Name: "ECDHE-ECDSA-AES256-GCM-SHA384", Bits: 256, Protocols: tls12Protocols, Cipher: "AES-GCM", Mac: "SHA384", KeyExchange: "ECDHE_ECDSA", Strength: StrengthHigh},
+	{ID: 0xc00a, Name: "ECDHE-ECDSA-AES256-SHA", Bits: 256, Protocols: tlsProtocols, Cipher: "AES", Mac: "SHA1", KeyExchange: "ECDHE_ECDSA", Strength: StrengthHigh},
+	{ID: 0xc024, Name: "ECDHE-ECDSA-AES256-SHA384", Bits: 256, Protocols: tls12Protocols, Cipher: "AES", Mac: "SHA384", KeyExchange: "ECDHE_ECDSA", Strength: StrengthHigh},
+	{ID: 0xcca9, Name: "ECDHE-ECDSA-CHACHA20-POLY1305-SHA256", Bits: 256, Protocols: tls12Protocols, Cipher: "CHACHA20-POLY1305", Mac: "SHA256", KeyExchange: "ECDHE_ECDSA", Strength: StrengthHigh, Since: 13},
+	{ID: 0x009c, Name: "AES128-GCM-SHA256", Bits: 128, Protocols: tls12Protocols, Cipher: "AES-GCM", Mac: "SHA256", KeyExchange: "RSA", Strength: StrengthHigh},
+	{ID: 0x002f, Name: "AES128-SHA", Bits: 128, Protocols: legacyProtocols, Cipher: "AES", Mac: "SHA1", KeyExchange: "RSA", Strength: StrengthHigh},
+	{ID: 0x003c, Name: "AES128-SHA256", Bits: 128, Protocols: tls12Protocols, Cipher: "AES", Mac: "SHA256", KeyExchange: "RSA", Strength: StrengthHigh},
+	{ID: 0x009d, Name: "AES256-GCM-SHA384", Bits: 256, Protocols: tls12Protocols, Cipher: "AES-GCM", Mac: "SHA384", KeyExchange: "RSA", Strength: StrengthHigh},
+	{ID: 0x0035, Name: "AES256-SHA", Bits: 256, Protocols: legacyProtocols, Cipher: "AES", Mac: "SHA1", KeyExchange: "RSA", Strength: StrengthHigh},
+	{ID: 0x003d, Name: "AES256-SHA256", Bits: 256, Protocols: tls12Protocols, Cipher: "AES", Mac: "SHA256", KeyExchange: "RSA", Strength: StrengthHigh},
+	{ID: 0x009e, Name: "DHE-RSA-AES128-GCM-SHA256", Bits: 128, Protocols: tls12Protocols, Cipher: "AES-GCM", Mac: "SHA256", KeyExchange: "EDH/RSA", Strength: StrengthHigh},
+	{ID: 0x0033, Name: "DHE-RSA-AES128-SHA", Bits: 128, Protocols: legacyProtocols, Cipher: "AES", Mac: "SHA1", KeyExchange: "EDH/RSA", Strength: StrengthHigh},
+	{ID: 0x0067, Name: "DHE-RSA-AES128-SHA256", Bits: 128, Protocols: tls12Protocols, Cipher: "AES", Mac: "SHA256", KeyExchange: "EDH/RSA", Strength: StrengthHigh},
+	{ID: 0x009f, Name: "DHE-RSA-AES256-GCM-SHA384", Bits: 256, Protocols: tls12Protocols, Cipher: "AES-GCM", Mac: "SHA384", KeyExchange: "EDH/RSA", Strength: StrengthHigh},
+	{ID: 0x0039, Name: "DHE-RSA-AES256-SHA", Bits: 256, Protocols: legacyProtocols, Cipher: "AES", Mac: "SHA1", KeyExchange: "EDH/RSA", Strength: StrengthHigh},
+	{ID: 0x006b, Name: "DHE-RSA-AES256-SHA256", Bits: 256, Protocols: tls12Protocols, Cipher: "AES", Mac: "SHA256", KeyExchange: "EDH/RSA", Strength: StrengthHigh},
+	{ID: 0xc012, Name: "ECDHE-RSA-DES-CBC3-SHA", Bits: 168, Protocols: tlsProtocols, Cipher: "DES", Mac: "SHA1", KeyExchange: "ECDHE_RSA", Strength: StrengthMedium, DefaultUntil: 14},
+	{ID: 0x000a, Name: "DES-CBC3-SHA", Bits: 168, Protocols: legacyProtocols, Cipher: "DES", Mac: "SHA1", KeyExchange: "RSA", Strength: StrengthMedium, DefaultUntil: 14},
+	{ID: 0x0016, Name: "DHE-RSA-DES-CBC3-SHA", Aliases: []string{"EDH-RSA-DES-CBC3-SHA"}, Bits: 168, Protocols: legacyProtocols, Cipher: "DES", Mac: "SHA1", KeyExchange: "EDH/RSA", Strength: StrengthMedium, DefaultUntil: 14},
+	{ID: 0x0005, Name: "RC4-SHA", Bits: 128, Protocols: legacyProtocols, Cipher: "RC4", Mac: "SHA1", KeyExchange: "RSA", Strength: StrengthMedium, DefaultUntil: 13},
+	{ID: 0x0004, Name: "RC4-MD5", Bits: 128, Protocols: legacyProtocols, Cipher: "RC4", Mac: "MD5", KeyExchange: "RSA", Strength: StrengthMedium, DefaultUntil: 13},
+	{ID: 0x0009, Name: "DES-CBC-SHA", Bits: 56, Protocols: legacyProtocols, Cipher: "DES", Mac: "SHA1", KeyExchange: "RSA", Strength: StrengthLow, DefaultUntil: -1},
+	{ID: 0x0002, Name: "NULL-SHA", Bits: 0, Protocols: legacyProtocols, Cipher: "NULL", Mac: "SHA1", KeyExchange: "RSA", Strength: StrengthNull, DefaultUntil: -1},
+	{ID: 0x0001, Name: "NULL-MD5", Bits: 0, Protocols: legacyProtocols, Cipher: "NULL", Mac: "MD5", KeyExchange: "RSA", Strength: StrengthNull, DefaultUntil: -1},
+}
+
+// inDefault reports whether the DEFAULT cipher string of a TMOS version includes the suite
+func (s Suite) inDefault(version int) bool {
+	switch {
+	case s.DefaultUntil < 0:
+		return false
+	case s.DefaultUntil == 0:
+		return true
+	default:
+		return version < s.DefaultUntil
+	}
+}
+
+// tripleDES reports whether the suite uses 3DES, which tmm reports as DES
+func (s Suite) tripleDES() bool {
+	return s.Cipher == "DES" && s.Bits == 168
+}
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/iilun/f5-mock/pkg/f5Validator"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	AuthModeBasic    = "basic"
+	AuthModeToken    = "token"
+	AuthModeBoth     = "both"
+	AuthModeDisabled = "disabled"
+)
+
+const (
+	// ReloadPolicyMerge merges seeded objects into the object store on reload
+	ReloadPolicyMerge = "merge"
+	// ReloadPolicyReplace replaces the object store with the seeded objects on reload
+	ReloadPolicyReplace = "replace"
+)
+
+// Config is the whole mock configuration, read from a YAML or JSON file then overridden by environment variables
+type Config struct {
+	Debug    bool   `json:"debug" yaml:"debug"`
+	SeedFile string `json:"seed_file" yaml:"seed_file"`
+	// ReloadPolicy is how a reloaded seed file is applied to the object store
+	ReloadPolicy string `json:"reload_policy" yaml:"reload_policy" validate:"oneof=merge replace"`
+	// ReloadInterval is the number of seconds between checks of the configuration and seed files, 0 disabling them
+	ReloadInterval int `json:"reload_interval" yaml:"reload_interval" validate:"gte=0"`
+	// Listeners are URLs such as https://:443, http://127.0.0.1:8080 or unix:///run/f5.sock
+	Listeners []string `json:"listeners" yaml:"listeners" validate:"required,min=1,dive,listener"`
+	// ShutdownTimeout is the number of seconds in-flight requests are given on shutdown
+	ShutdownTimeout  int    `json:"shutdown_timeout" yaml:"shutdown_timeout" validate:"gte=0"`
+	DefaultVersion   string `json:"default_version" yaml:"default_version" validate:"required,f5version"`
+	DefaultPartition string `json:"default_partition" yaml:"default_partition"`
+	CheckCertExpiry  bool   `json:"check_cert_expiry" yaml:"check_cert_expiry"`
+	TLS              TLS    `json:"tls" yaml:"tls"`
+	Auth             Auth   `json:"auth" yaml:"auth"`
+	SSH              SSH    `json:"ssh" yaml:"ssh"`
+}
+
+type TLS struct {
+	// CertPath is generated as a self-signed certificate when missing
+	CertPath   string `json:"cert_path" yaml:"cert_path" validate:"required"`
+	KeyPath    string `json:"key_path" yaml:"key_path" validate:"required"`
+	MinVersion string `json:"min_version" yaml:"min_version" validate:"omitempty,oneof=1.0 1.1 1.2 1.3 TLSv1.0 TLSv1.1 TLSv1.2 TLSv1.3"`
+	MaxVersion string `json:"max_version" yaml:"max_version" validate:"omitempty,oneof=1.0 1.1 1.2 1.3 TLSv1.0 TLSv1.1 TLSv1.2 TLSv1.3"`
+	// Ciphers are IANA cipher suite names, they only apply up to TLS 1.2
+	Ciphers []string `json:"ciphers" yaml:"ciphers"`
+	// ClientCAPath enables client certificate authentication when set
+	ClientCAPath string `json:"client_ca_path" yaml:"client_ca_path"`
+	ClientAuth   string `json:"client_auth" yaml:"client_auth" validate:"omitempty,oneof=none optional require"`
+}
+
+// SSH is the optional SSH listener, giving the emulated shell and file uploads
+type SSH struct {
+	// Listen is the address to listen on, e.g. :22, the listener being disabled when empty
+	Listen string `json:"listen" yaml:"listen" validate:"omitempty,hostname_port"`
+	// HostKeyPath is a PEM private key, generated when missing
+	HostKeyPath string `json:"host_key_path" yaml:"host_key_path" validate:"required"`
+}
+
+type Auth struct {
+	// Mode defaults to token auth when a login provider is set, basic auth otherwise
+	Mode          string `json:"mode" yaml:"mode" validate:"omitempty,oneof=basic token both disabled"`
+	LoginProvider string `json:"login_provider" yaml:"login_provider"`
+	AdminUsername string `json:"admin_username" yaml:"admin_username" validate:"required"`
+	// AdminPassword has no default, it is required unless authentication is disabled
+	AdminPassword string `json:"admin_password" yaml:"admin_password"`
+}
+
+func (a Auth) EffectiveMode() string {
+	if a.Mode != "" {
+		return a.Mode
+	}
+	if a.LoginProvider != "" {
+		return AuthModeToken
+	}
+	return AuthModeBasic
+}
+
+func init() {
+	_ = f5Validator.Validate.RegisterValidation("listener", func(fl validator.FieldLevel) bool {
+		_, err := ParseListener(fl.Field().String())
+		return err == nil
+	})
+
+	_ = f5Validator.Validate.RegisterValidation("f5version", func(fl validator.FieldLevel) bool {
+		major, _, _ := strings.Cut(fl.Field().String(), ".")
+		_, err := strconv.Atoi(major)
+		return err == nil
+	})
+}
+
+func Default() Config {
+	return Config{
+		ReloadPolicy:    ReloadPolicyMerge,
+		ReloadInterval:  5,
+		Listeners:       []string{"https://:443"},
+		ShutdownTimeout: 30,
+		DefaultVersion:  "17.0.0.0",
+		TLS: TLS{
+			CertPath:   "/etc/ssl/f5/cert.pem",
+			KeyPath:    "/etc/ssl/f5/key.pem",
+			MinVersion: "1.2",
+			MaxVersion: "1.3",
+		},
+		Auth: Auth{
+			AdminUsername: "admin",
+		},
+		SSH: SSH{
+			HostKeyPath: "/etc/ssh/f5/ssh_host_key",
+		},
+	}
+}
+
+// Load reads the configuration file at path, if any, applies environment overrides and validates the result
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to read config file: %w", err)
+		}
+
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			err = json.Unmarshal(data, &cfg)
+		} else {
+			err = yaml.Unmarshal(data, &cfg)
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to unmarshal config file: %w", err)
+		}
+	}
+
+	err := applyEnv(&cfg)
+	if err != nil {
+		return Config{}, err
+	}
+
+	err = f5Validator.Validate.Struct(cfg)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if cfg.Auth.AdminPassword == "" && cfg.Auth.Mode != AuthModeDisabled {
+		return Config{}, errors.New("invalid configuration: the admin password is required, set F5_ADMIN_PASSWORD or auth.admin_password")
+	}
+
+	return cfg, nil
+}
+
+func applyEnv(cfg *Config) error {
+	setString := func(name string, field *string) {
+		if value := os.Getenv(name); value != "" {
+			*field = value
+		}
+	}
+	setBool := func(name string, field *bool) {
+		if os.Getenv(name) != "" {
+			*field = true
+		}
+	}
+	setList := func(name string, field *[]string) {
+		if value := os.Getenv(name); value != "" {
+			*field = splitList(value)
+		}
+	}
+
+	setBool("F5_DEBUG", &cfg.Debug)
+	setString("F5_SEED_FILE", &cfg.SeedFile)
+	setString("F5_BASE_VERSION", &cfg.DefaultVersion)
+	setString("F5_DEFAULT_PARTITION", &cfg.DefaultPartition)
+	setBool("F5_CHECK_CERT_EXPIRY", &cfg.CheckCertExpiry)
+
+	setList("F5_LISTEN", &cfg.Listeners)
+	if os.Getenv("F5_LISTEN") == "" && (os.Getenv("F5_HOST") != "" || os.Getenv("F5_PORT") != "") {
+		port := os.Getenv("F5_PORT")
+		if port == "" {
+			port = "443"
+		}
+		cfg.Listeners = []string{"https://" + net.JoinHostPort(os.Getenv("F5_HOST"), port)}
+	}
+
+	setString("F5_RELOAD_POLICY", &cfg.ReloadPolicy)
+
+	for name, field := range map[string]*int{
+		"F5_SHUTDOWN_TIMEOUT": &cfg.ShutdownTimeout,
+		"F5_RELOAD_INTERVAL":  &cfg.ReloadInterval,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %s", name, value)
+		}
+		*field = seconds
+	}
+
+	setString("F5_CERT_PATH", &cfg.TLS.CertPath)
+	setString("F5_KEY_PATH", &cfg.TLS.KeyPath)
+	setString("F5_TLS_MIN_VERSION", &cfg.TLS.MinVersion)
+	setString("F5_TLS_MAX_VERSION", &cfg.TLS.MaxVersion)
+	setList("F5_TLS_CIPHERS", &cfg.TLS.Ciphers)
+	setString("F5_CLIENT_CA_PATH", &cfg.TLS.ClientCAPath)
+	setString("F5_CLIENT_AUTH", &cfg.TLS.ClientAuth)
+
+	setString("F5_AUTH_MODE", &cfg.Auth.Mode)
+	setString("F5_LOGIN_PROVIDER", &cfg.Auth.LoginProvider)
+	setString("F5_ADMIN_USERNAME", &cfg.Auth.AdminUsername)
+	setString("F5_ADMIN_PASSWORD", &cfg.Auth.AdminPassword)
+
+	setString("F5_SSH_LISTEN", &cfg.SSH.Listen)
+	setString("F5_SSH_HOST_KEY_PATH", &cfg.SSH.HostKeyPath)
+
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+const (
+	SchemeHTTP  = "http"
+	SchemeHTTPS = "https"
+	SchemeUnix  = "unix"
+)
+
+// Listener is an address to serve the API on. Unix sockets serve plain HTTP.
+type Listener struct {
+	Scheme  string
+	Address string
+}
+
+func (l Listener) String() string {
+	return fmt.Sprintf("%s://%s", l.Scheme, l.Address)
+}
+
+// ParseListener parses listener URLs, e.g. https://:443, http://127.0.0.1:8080 or unix:///run/f5.sock
+func ParseListener(value string) (Listener, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return Listener{}, fmt.Errorf("invalid listener %s: %w", value, err)
+	}
+
+	switch u.Scheme {
+	case SchemeHTTP, SchemeHTTPS:
+		if u.Host == "" {
+			return Listener{}, fmt.Errorf("invalid listener %s: missing address", value)
+		}
+		return Listener{Scheme: u.Scheme, Address: u.Host}, nil
+	case SchemeUnix:
+		if u.Path == "" {
+			return Listener{}, fmt.Errorf("invalid listener %s: missing socket path", value)
+		}
+		return Listener{Scheme: u.Scheme, Address: u.Path}, nil
+	default:
+		return Listener{}, fmt.Errorf("invalid listener %s: unsupported scheme %s", value, u.Scheme)
+	}
+}
+
+// ParsedListeners returns the parsed listeners, they are valid once the configuration is validated
+func (c Config) ParsedListeners() []Listener {
+	listeners := make([]Listener, 0, len(c.Listeners))
+	for _, value := range c.Listeners {
+		listener, _ := ParseListener(value)
+		listeners = append(listeners, listener)
+	}
+	return listeners
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListener(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Listener
+		wantErr string
+	}{
+		{value: "https://:443", want: Listener{Scheme: SchemeHTTPS, Address: ":443"}},
+		{value: "http://127.0.0.1:8080", want: Listener{Scheme: SchemeHTTP, Address: "127.0.0.1:8080"}},
+		{value: "unix:///run/f5.sock", want: Listener{Scheme: SchemeUnix, Address: "/run/f5.sock"}},
+		{value: "http://", wantErr: "invalid listener http://: missing address"},
+		{value: "unix://", wantErr: "invalid listener unix://: missing socket path"},
+		{value: "ftp://:21", wantErr: "invalid listener ftp://:21: unsupported scheme ftp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			listener, err := ParseListener(tt.value)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, listener)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		wantErr string
+		check   func(t *testing.T, cfg Config)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, cfg Config) {
+				want := Default()
+				want.Auth.AdminPassword = "password"
+				require.Equal(t, want, cfg)
+				require.Equal(t, AuthModeBasic, cfg.Auth.EffectiveMode())
+			},
+		},
+		{
+			name: "yaml file",
+			file: writeFile("config.yaml", `
+listeners: ["http://127.0.0.1:8080", "unix:///run/f5.sock"]
+default_version: "16.1.0"
+default_partition: Common
+tls:
+  min_version: "1.3"
+auth:
+  login_provider: tmos
+  admin_username: root
+`),
+			check: func(t *testing.T, cfg Config) {
+				require.Equal(t, []string{"http://127.0.0.1:8080", "unix:///run/f5.sock"}, cfg.Listeners)
+				require.Equal(t, []Listener{{Scheme: SchemeHTTP, Address: "127.0.0.1:8080"}, {Scheme: SchemeUnix, Address: "/run/f5.sock"}}, cfg.ParsedListeners())
+				require.Equal(t, "16.1.0", cfg.DefaultVersion)
+				require.Equal(t, "Common", cfg.DefaultPartition)
+				require.Equal(t, "1.3", cfg.TLS.MinVersion)
+				require.Equal(t, "1.3", cfg.TLS.MaxVersion)
+				require.Equal(t, "root", cfg.Auth.AdminUsername)
+				require.Equal(t, "password", cfg.Auth.AdminPassword)
+				require.Equal(t, AuthModeToken, cfg.Auth.EffectiveMode())
+			},
+		},
+		{
+			name: "json file",
+			file: writeFile("config.json", `{"shutdown_timeout": 5, "auth": {"mode": "both"}}`),
+			check: func(t *testing.T, cfg Config) {
+				require.Equal(t, 5, cfg.ShutdownTimeout)
+				require.Equal(t, AuthModeBoth, cfg.Auth.EffectiveMode())
+			},
+		},
+		{
+			name: "environment overrides the file",
+			file: writeFile("override.yaml", "default_version: \"16.1.0\"\nauth:\n  admin_password: file\n"),
+			env: map[string]string{
+				"F5_BASE_VERSION":      "15.1.0",
+				"F5_ADMIN_PASSWORD":    "env",
+				"F5_TLS_CIPHERS":       "TLS_AES_128_GCM_SHA256, TLS_AES_256_GCM_SHA384",
+				"F5_CHECK_CERT_EXPIRY": "1",
+			},
+			check: func(t *testing.T, cfg Config) {
+				require.Equal(t, "15.1.0", cfg.DefaultVersion)
+				require.Equal(t, "env", cfg.Auth.AdminPassword)
+				require.Equal(t, []string{"TLS_AES_128_GCM_SHA256", "TLS_AES_256_GCM_SHA384"}, cfg.TLS.Ciphers)
+				require.True(t, cfg.CheckCertExpiry)
+			},
+		},
+		{
+			name: "host and port make an https listener",
+			env:  map[string]string{"F5_HOST": "127.0.0.1", "F5_PORT": "8443"},
+			check: func(t *testing.T, cfg Config) {
+				require.Equal(t, []string{"https://127.0.0.1:8443"}, cfg.Listeners)
+			},
+		},
+		{
+			name: "listen takes precedence over host and port",
+			env:  map[string]string{"F5_LISTEN": "http://:8080,unix:///tmp/f5.sock", "F5_PORT": "8443"},
+			check: func(t *testing.T, cfg Config) {
+				require.Equal(t, []string{"http://:8080", "unix:///tmp/f5.sock"}, cfg.Listeners)
+			},
+		},
+		{
+			name: "ssh listener",
+			file: writeFile("ssh.yaml", "ssh:\n  listen: \":2222\"\n"),
+			env:  map[string]string{"F5_SSH_HOST_KEY_PATH": "/tmp/ssh_host_key"},
+			check: func(t *testing.T, cfg Config) {
+				require.Equal(t, SSH{Listen: ":2222", HostKeyPath: "/tmp/ssh_host_key"}, cfg.SSH)
+			},
+		},
+		{
+			name:    "invalid ssh listener",
+			env:     map[string]string{"F5_SSH_LISTEN": "ssh://:22"},
+			wantErr: "invalid configuration",
+		},
+		{
+			name:    "missing file",
+			file:    filepath.Join(dir, "missing.yaml"),
+			wantErr: "failed to read config file",
+		},
+		{
+			name:    "malformed file",
+			file:    writeFile("malformed.json", `{"listeners": `),
+			wantErr: "failed to unmarshal config file",
+		},
+		{
+			name:    "invalid listener",
+			file:    writeFile("listener.yaml", "listeners: [\"ftp://:21\"]\n"),
+			wantErr: "invalid configuration",
+		},
+		{
+			name:    "invalid version",
+			env:     map[string]string{"F5_BASE_VERSION": "abc"},
+			wantErr: "invalid configuration",
+		},
+		{
+			name:    "invalid auth mode",
+			env:     map[string]string{"F5_AUTH_MODE": "kerberos"},
+			wantErr: "invalid configuration",
+		},
+		{
+			name:    "missing admin password",
+			env:     map[string]string{"F5_ADMIN_PASSWORD": ""},
+			wantErr: "invalid configuration: the admin password is required",
+		},
+		{
+			name: "no admin password without authentication",
+			env:  map[string]string{"F5_ADMIN_PASSWORD": "", "F5_AUTH_MODE": "disabled"},
+			check: func(t *testing.T, cfg Config) {
+				require.Empty(t, cfg.Auth.AdminPassword)
+			},
+		},
+		{
+			name:    "invalid shutdown timeout",
+			env:     map[string]string{"F5_SHUTDOWN_TIMEOUT": "soon"},
+			wantErr: "invalid F5_SHUTDOWN_TIMEOUT soon",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The admin password has no default
+			t.Setenv("F5_ADMIN_PASSWORD", "password")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := Load(tt.file)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, cfg)
+		})
+	}
+}
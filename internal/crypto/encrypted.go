@@ -0,0 +1,256 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	ErrEncryptedKey        = errors.New("key is encrypted, a passphrase is required")
+	ErrIncorrectPassphrase = errors.New("incorrect passphrase")
+)
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+const pbkdf2Iterations = 2048
+
+// maxPBKDF2Iterations bounds the work an uploaded key can ask for, far above what OpenSSL uses
+const maxPBKDF2Iterations = 1 << 20
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// IsEncryptedPemKey reports whether content is a passphrase protected key, either PKCS#8 or legacy OpenSSL PEM
+func IsEncryptedPemKey(content []byte) bool {
+	pemBlock, err := decodeKeyBlock(content)
+	if err != nil {
+		return false
+	}
+	return isEncryptedBlock(pemBlock)
+}
+
+func isEncryptedBlock(block *pem.Block) bool {
+	return block.Type == "ENCRYPTED PRIVATE KEY" || block.Headers["Proc-Type"] == "4,ENCRYPTED"
+}
+
+// DecryptPemPrivateKey decrypts a passphrase protected PEM key and returns it unencrypted.
+// Unencrypted keys are returned as is.
+func DecryptPemPrivateKey(content []byte, passphrase string) ([]byte, error) {
+	pemBlock, err := decodeKeyBlock(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isEncryptedBlock(pemBlock) {
+		return content, nil
+	}
+
+	if passphrase == "" {
+		return nil, ErrEncryptedKey
+	}
+
+	if pemBlock.Type != "ENCRYPTED PRIVATE KEY" {
+		// Legacy OpenSSL encryption, only kept for compatibility with old keys
+		//nolint:staticcheck
+		der, err := x509.DecryptPEMBlock(pemBlock, []byte(passphrase))
+		if err != nil {
+			return nil, ErrIncorrectPassphrase
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: pemBlock.Type, Bytes: der}), nil
+	}
+
+	der, err := decryptPKCS8(pemBlock.Bytes, []byte(passphrase))
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// EncryptPemPrivateKey encrypts a PEM key as PKCS#8 using PBES2 with AES-256-CBC
+func EncryptPemPrivateKey(content []byte, passphrase string) ([]byte, error) {
+	key, err := ParsePemPrivateKey(content)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	iv := make([]byte, aes.BlockSize)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err = rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	derivedKey := pbkdf2.Key([]byte(passphrase), salt, pbkdf2Iterations, 32, sha256.New)
+
+	block, err := aes.NewCipher(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	padding := aes.BlockSize - len(der)%aes.BlockSize
+	plaintext := append(der, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	encrypted := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, plaintext)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbkdf2Iterations,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ivParams, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParams}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: encrypted,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: info}), nil
+}
+
+func decryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, errors.New("invalid encrypted private key")
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, errors.New("unsupported key encryption algorithm")
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, errors.New("invalid PBES2 parameters")
+	}
+
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, errors.New("unsupported key derivation function")
+	}
+
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, errors.New("invalid PBKDF2 parameters")
+	}
+
+	if kdfParams.IterationCount < 1 || kdfParams.IterationCount > maxPBKDF2Iterations {
+		return nil, errors.New("unsupported PBKDF2 iteration count")
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case len(kdfParams.PRF.Algorithm) == 0, kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	default:
+		return nil, errors.New("unsupported PBKDF2 pseudo random function")
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, errors.New("invalid encryption scheme parameters")
+	}
+
+	var keyLength int
+	var newCipher func([]byte) (cipher.Block, error)
+	switch {
+	case params.EncryptionScheme.Algorithm.Equal(oidAES128CBC):
+		keyLength, newCipher = 16, aes.NewCipher
+	case params.EncryptionScheme.Algorithm.Equal(oidAES192CBC):
+		keyLength, newCipher = 24, aes.NewCipher
+	case params.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		keyLength, newCipher = 32, aes.NewCipher
+	case params.EncryptionScheme.Algorithm.Equal(oidDESEDE3CBC):
+		keyLength, newCipher = 24, des.NewTripleDESCipher
+	default:
+		return nil, errors.New("unsupported key encryption scheme")
+	}
+
+	derivedKey := pbkdf2.Key(passphrase, kdfParams.Salt, kdfParams.IterationCount, keyLength, prf)
+
+	block, err := newCipher(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(iv) != block.BlockSize() || len(info.EncryptedData) == 0 || len(info.EncryptedData)%block.BlockSize() != 0 {
+		return nil, errors.New("invalid encrypted private key")
+	}
+
+	decrypted := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, info.EncryptedData)
+
+	// A wrong passphrase shows up as invalid padding or an unparsable key
+	padding := int(decrypted[len(decrypted)-1])
+	if padding == 0 || padding > block.BlockSize() || !bytes.Equal(decrypted[len(decrypted)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, ErrIncorrectPassphrase
+	}
+	decrypted = decrypted[:len(decrypted)-padding]
+
+	if _, err = x509.ParsePKCS8PrivateKey(decrypted); err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	return decrypted, nil
+}
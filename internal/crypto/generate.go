@@ -0,0 +1,229 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	KeyTypeRSA = "rsa-private"
+	KeyTypeEC  = "ec-private"
+
+	CurveP256 = "prime256v1"
+	CurveP384 = "secp384r1"
+)
+
+// RSAKeySizes are the RSA key sizes accepted by tmsh
+var RSAKeySizes = []int{1024, 2048, 3072, 4096}
+
+// Subject holds the distinguished name fields tmsh accepts for certificates and CSRs
+type Subject struct {
+	CommonName         string
+	Country            string
+	State              string
+	City               string
+	Organization       string
+	OrganizationalUnit string
+	EmailAddress       string
+}
+
+func (s Subject) toPkix() pkix.Name {
+	name := pkix.Name{CommonName: s.CommonName}
+	if s.Country != "" {
+		name.Country = []string{s.Country}
+	}
+	if s.State != "" {
+		name.Province = []string{s.State}
+	}
+	if s.City != "" {
+		name.Locality = []string{s.City}
+	}
+	if s.Organization != "" {
+		name.Organization = []string{s.Organization}
+	}
+	if s.OrganizationalUnit != "" {
+		name.OrganizationalUnit = []string{s.OrganizationalUnit}
+	}
+	if s.EmailAddress != "" {
+		// emailAddress is not a first class pkix field
+		name.ExtraNames = append(name.ExtraNames, pkix.AttributeTypeAndValue{
+			Type:  []int{1, 2, 840, 113549, 1, 9, 1},
+			Value: s.EmailAddress,
+		})
+	}
+	return name
+}
+
+// SubjectAlternativeNames is the parsed form of a tmsh subject-alternative-name string
+type SubjectAlternativeNames struct {
+	DNSNames       []string
+	IPAddresses    []net.IP
+	EmailAddresses []string
+	URIs           []*url.URL
+}
+
+// ParseSubjectAlternativeNames parses the tmsh format, e.g. "DNS:www.example.com, IP:10.0.0.1"
+func ParseSubjectAlternativeNames(value string) (SubjectAlternativeNames, error) {
+	var sans SubjectAlternativeNames
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kind, content, found := strings.Cut(entry, ":")
+		if !found || content == "" {
+			return SubjectAlternativeNames{}, fmt.Errorf("invalid subject alternative name %q", entry)
+		}
+
+		switch strings.ToUpper(kind) {
+		case "DNS":
+			sans.DNSNames = append(sans.DNSNames, content)
+		case "IP":
+			ip := net.ParseIP(content)
+			if ip == nil {
+				return SubjectAlternativeNames{}, fmt.Errorf("invalid IP address %q", content)
+			}
+			sans.IPAddresses = append(sans.IPAddresses, ip)
+		case "EMAIL":
+			sans.EmailAddresses = append(sans.EmailAddresses, content)
+		case "URI":
+			uri, err := url.Parse(content)
+			if err != nil {
+				return SubjectAlternativeNames{}, fmt.Errorf("invalid URI %q", content)
+			}
+			sans.URIs = append(sans.URIs, uri)
+		default:
+			return SubjectAlternativeNames{}, fmt.Errorf("unsupported subject alternative name type %q", kind)
+		}
+	}
+
+	return sans, nil
+}
+
+// GenerateKey generates a new private key and returns it PEM encoded
+func GenerateKey(keyType string, keySize int, curveName string) ([]byte, error) {
+	switch keyType {
+	case "", KeyTypeRSA:
+		if keySize == 0 {
+			keySize = 2048
+		}
+		if !slices.Contains(RSAKeySizes, keySize) {
+			return nil, fmt.Errorf("invalid key size %d", keySize)
+		}
+
+		key, err := rsa.GenerateKey(rand.Reader, keySize)
+		if err != nil {
+			return nil, err
+		}
+
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), nil
+	case KeyTypeEC:
+		var curve elliptic.Curve
+		switch curveName {
+		case "", CurveP256:
+			curve = elliptic.P256()
+		case CurveP384:
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("invalid curve name %s", curveName)
+		}
+
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+	default:
+		return nil, fmt.Errorf("invalid key type %s", keyType)
+	}
+}
+
+// CreateSelfSignedCertificate creates a PEM encoded certificate signed by the given PEM encoded key
+func CreateSelfSignedCertificate(keyContent []byte, subject Subject, sans SubjectAlternativeNames, lifetime time.Duration) ([]byte, error) {
+	key, err := ParsePemPrivateKey(keyContent)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject.toPkix(),
+		NotBefore:             now,
+		NotAfter:              now.Add(lifetime),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		// Like openssl req -x509, self-signed certificates can act as their own chain
+		IsCA:           true,
+		DNSNames:       sans.DNSNames,
+		IPAddresses:    sans.IPAddresses,
+		EmailAddresses: sans.EmailAddresses,
+		URIs:           sans.URIs,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// CreateCertificateRequest creates a PEM encoded CSR signed by the given PEM encoded key
+func CreateCertificateRequest(keyContent []byte, subject Subject, sans SubjectAlternativeNames) ([]byte, error) {
+	key, err := ParsePemPrivateKey(keyContent)
+	if err != nil {
+		return nil, err
+	}
+
+	template := x509.CertificateRequest{
+		Subject:        subject.toPkix(),
+		DNSNames:       sans.DNSNames,
+		IPAddresses:    sans.IPAddresses,
+		EmailAddresses: sans.EmailAddresses,
+		URIs:           sans.URIs,
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &template, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+// ParsePemCertificateRequest parses a PEM encoded CSR
+func ParsePemCertificateRequest(content []byte) (*x509.CertificateRequest, error) {
+	pemBlock, _ := pem.Decode(content)
+	if pemBlock == nil {
+		return nil, errors.New("invalid pem file")
+	}
+	return x509.ParseCertificateRequest(pemBlock.Bytes)
+}
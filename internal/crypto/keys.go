@@ -0,0 +1,118 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+const (
+	KeyTypeRSAPublic = "rsa-public"
+	KeyTypeECPublic  = "ec-public"
+)
+
+// KeyInfo describes the algorithm of a private key, as shown by tmsh
+type KeyInfo struct {
+	Type  string
+	Size  int
+	Curve string
+}
+
+var curveNames = map[string]string{
+	"P-256": CurveP256,
+	"P-384": CurveP384,
+	"P-521": "secp521r1",
+}
+
+// decodeKeyBlock returns the first private key block of content, skipping EC parameters
+func decodeKeyBlock(content []byte) (*pem.Block, error) {
+	for {
+		pemBlock, rest := pem.Decode(content)
+		if pemBlock == nil {
+			return nil, errors.New("invalid pem file")
+		}
+
+		switch pemBlock.Type {
+		case "EC PARAMETERS":
+			content = rest
+		case "RSA PRIVATE KEY", "EC PRIVATE KEY", "PRIVATE KEY", "ENCRYPTED PRIVATE KEY":
+			return pemBlock, nil
+		default:
+			return nil, fmt.Errorf("unexpected pem type %s, expected a private key", pemBlock.Type)
+		}
+	}
+}
+
+// ParsePemPrivateKey parses a PKCS#1, PKCS#8 or SEC1 PEM encoded private key
+func ParsePemPrivateKey(content []byte) (crypto.Signer, error) {
+	pemBlock, err := decodeKeyBlock(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if isEncryptedBlock(pemBlock) {
+		return nil, ErrEncryptedKey
+	}
+
+	switch pemBlock.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(pemBlock.Bytes)
+		if err != nil {
+			return nil, errors.New("invalid PKCS#1 private key")
+		}
+		return key, nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(pemBlock.Bytes)
+		if err != nil {
+			return nil, errors.New("invalid EC private key")
+		}
+		return key, nil
+	default:
+		key, err := x509.ParsePKCS8PrivateKey(pemBlock.Bytes)
+		if err != nil {
+			return nil, errors.New("invalid PKCS#8 private key")
+		}
+
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+		return signer, nil
+	}
+}
+
+// GetKeyInfo returns the algorithm and size of key, failing for algorithms BIG-IP does not support
+func GetKeyInfo(key crypto.Signer) (KeyInfo, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return KeyInfo{Type: KeyTypeRSA, Size: k.N.BitLen()}, nil
+	case *ecdsa.PrivateKey:
+		curve, found := curveNames[k.Curve.Params().Name]
+		if !found {
+			return KeyInfo{}, fmt.Errorf("unsupported curve %s", k.Curve.Params().Name)
+		}
+		return KeyInfo{Type: KeyTypeEC, Size: k.Curve.Params().BitSize, Curve: curve}, nil
+	default:
+		return KeyInfo{}, fmt.Errorf("unsupported key algorithm %T", key)
+	}
+}
+
+// GetCertificateKeyInfo returns the algorithm and size of the certificate public key
+func GetCertificateKeyInfo(cert *x509.Certificate) (KeyInfo, error) {
+	switch k := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return KeyInfo{Type: KeyTypeRSAPublic, Size: k.N.BitLen()}, nil
+	case *ecdsa.PublicKey:
+		curve, found := curveNames[k.Curve.Params().Name]
+		if !found {
+			return KeyInfo{}, fmt.Errorf("unsupported curve %s", k.Curve.Params().Name)
+		}
+		return KeyInfo{Type: KeyTypeECPublic, Size: k.Curve.Params().BitSize, Curve: curve}, nil
+	default:
+		return KeyInfo{}, fmt.Errorf("unsupported key algorithm %T", cert.PublicKey)
+	}
+}
@@ -0,0 +1,67 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// PKCS12Bundle is the content of a PKCS#12 archive, split into PEM files
+type PKCS12Bundle struct {
+	Key          []byte
+	Certificates []byte
+}
+
+// IsPKCS12 reports whether content looks like a DER PKCS#12 archive rather than a PEM file
+func IsPKCS12(content []byte) bool {
+	pemBlock, _ := pem.Decode(content)
+	// PFX is a DER SEQUENCE
+	return pemBlock == nil && len(content) > 0 && content[0] == 0x30
+}
+
+// ParsePKCS12 decodes a PKCS#12 archive protected by passphrase into a PEM key and certificates, the certificate
+// of the key coming first. Both the PBES2 algorithms OpenSSL 3 uses by default and the legacy ones are supported.
+func ParsePKCS12(content []byte, passphrase string) (PKCS12Bundle, error) {
+	privateKey, cert, caCerts, err := pkcs12.DecodeChain(content, passphrase)
+	if err != nil {
+		if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+			return PKCS12Bundle{}, ErrIncorrectPassphrase
+		}
+		var notImplemented pkcs12.NotImplementedError
+		if errors.As(err, &notImplemented) {
+			return PKCS12Bundle{}, fmt.Errorf("unsupported PKCS#12 file: %s", strings.TrimPrefix(err.Error(), "pkcs12: "))
+		}
+		return PKCS12Bundle{}, fmt.Errorf("invalid PKCS#12 file: %s", strings.TrimPrefix(err.Error(), "pkcs12: "))
+	}
+
+	var keyBlock *pem.Block
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		keyBlock = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return PKCS12Bundle{}, err
+		}
+		keyBlock = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	default:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return PKCS12Bundle{}, err
+		}
+		keyBlock = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+
+	bundle := PKCS12Bundle{Key: pem.EncodeToMemory(keyBlock)}
+	for _, c := range append([]*x509.Certificate{cert}, caCerts...) {
+		bundle.Certificates = append(bundle.Certificates, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+
+	return bundle, nil
+}
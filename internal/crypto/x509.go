@@ -1,11 +1,19 @@
 package crypto
 
 import (
+	"crypto"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 )
 
+// ExpirationFormat is the openssl date format used by tmsh for expirationString
+const ExpirationFormat = "Jan _2 15:04:05 2006 GMT"
+
 func IsValidPemCertificate(content []byte) bool {
 	_, err := ParsePemCertificate(content)
 	return err == nil
@@ -19,10 +27,59 @@ func ParsePemCertificate(content []byte) (*x509.Certificate, error) {
 	return x509.ParseCertificate(pemBlock.Bytes)
 }
 
-func IsValidPem(content []byte) bool {
-	pemBlock, _ := pem.Decode(content)
-	if pemBlock == nil {
-		return false
+// ParsePemCertificates parses all the certificates of a PEM bundle
+func ParsePemCertificates(content []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var pemBlock *pem.Block
+		pemBlock, content = pem.Decode(content)
+		if pemBlock == nil {
+			break
+		}
+		if pemBlock.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(pemBlock.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
 	}
-	return true
+
+	if len(certs) == 0 {
+		return nil, errors.New("invalid pem file")
+	}
+	return certs, nil
+}
+
+// KeyMatchesCertificate reports whether key is the private key of the certificate public key
+func KeyMatchesCertificate(cert *x509.Certificate, key crypto.Signer) bool {
+	pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && pub.Equal(cert.PublicKey)
+}
+
+// IsIssuedBy reports whether cert is signed by one of issuers
+func IsIssuedBy(cert *x509.Certificate, issuers []*x509.Certificate) bool {
+	for _, issuer := range issuers {
+		if cert.CheckSignatureFrom(issuer) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Fingerprint returns the SHA256 fingerprint of cert, formatted as tmsh does
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+
+	hexBytes := make([]string, len(sum))
+	for i, b := range sum {
+		hexBytes[i] = fmt.Sprintf("%02X", b)
+	}
+	return "SHA256/" + strings.Join(hexBytes, ":")
+}
+
+// ExpirationString formats the certificate expiration date as tmsh does
+func ExpirationString(cert *x509.Certificate) string {
+	return cert.NotAfter.In(time.UTC).Format(ExpirationFormat)
 }
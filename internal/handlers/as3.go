@@ -21,7 +21,11 @@ func (h AS3Handler) Handler() http.HandlerFunc {
 		switch r.Method {
 		case http.MethodGet:
 			byPartition := make(map[string][]*models.ClientSSLProfile)
+			user := userFromRequest(r)
 			for _, p := range cache.GlobalCache.ClientSSLProfiles {
+				if !canReadPartition(user, p.Partition) {
+					continue
+				}
 				base := byPartition[p.Partition]
 				base = append(base, p)
 				byPartition[p.Partition] = base
@@ -86,7 +90,11 @@ func (h AS3Handler) Handler() http.HandlerFunc {
 					return
 				}
 
-				profile := findProfile(partition, profileName)
+				if !checkPartitionAccess(w, r, partition) {
+					return
+				}
+
+				profile := findProfile(objectPath{partition: partition, name: profileName})
 				if profile == nil {
 					f5Error(w, r, http.StatusBadRequest, "profile %s not found", profileName)
 					return
@@ -8,7 +8,6 @@ import (
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 )
 
@@ -80,6 +79,8 @@ func TestAS3Handler(t *testing.T) {
 
 	_, _ = cache.New("")
 
+	cfg := testConfig()
+
 	logger := log.New(true)
 	defer logger.Close()
 
@@ -88,11 +89,11 @@ func TestAS3Handler(t *testing.T) {
 
 			cache.GlobalCache.ClientSSLProfiles = tt.profiles
 
-			h := F5HandlerWrapper{AS3Handler{}, logger}
+			h := F5HandlerWrapper{AS3Handler{}, logger, &cfg}
 			w := httptest.NewRecorder()
 			req := httptest.NewRequest(tt.method, h.Route(), nil)
 			if !tt.disableAuth {
-				req.SetBasicAuth(os.Getenv("F5_ADMIN_USERNAME"), os.Getenv("F5_ADMIN_PASSWORD"))
+				req.SetBasicAuth(cfg.Auth.AdminUsername, cfg.Auth.AdminPassword)
 			}
 
 			h.Handler()(w, req)
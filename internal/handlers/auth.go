@@ -3,12 +3,14 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
-	"github.com/google/uuid"
+	"fmt"
+	"github.com/iilun/f5-mock/internal/config"
 	"github.com/iilun/f5-mock/pkg/cache"
 	"github.com/iilun/f5-mock/pkg/f5Validator"
+	"github.com/iilun/f5-mock/pkg/models"
 	"io"
+	"net"
 	"net/http"
-	"os"
 )
 
 type LoginHandler struct{}
@@ -19,14 +21,6 @@ func (h LoginHandler) Route() string {
 
 func (h LoginHandler) Handler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		expectedLoginProvider := os.Getenv("F5_LOGIN_PROVIDER")
-		// FIXME: check if htis is how it works
-		if expectedLoginProvider == "" {
-			// This endpoint is disabled if external auth not enabled
-			f5Error(w, r, http.StatusForbidden, "login not available")
-			return
-		}
-
 		// Read body
 		bytes, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -47,26 +41,26 @@ func (h LoginHandler) Handler() http.HandlerFunc {
 			return
 		}
 
-		err = checkAuth(request.Username, request.Password)
+		_, err = authenticate(configFromRequest(r).Auth, request.LoginProvider, request.Username, request.Password)
 		if err != nil {
 			f5Error(w, r, http.StatusBadRequest, "%v", err)
 			return
 		}
 
-		if request.LoginProvider != expectedLoginProvider {
-			f5Error(w, r, http.StatusBadRequest, "unknown login provider")
-			return
-		}
-
 		// All validations are successful, generate token
-		token := uuid.New()
-		err = cache.GlobalCache.AuthTokens.Set(token.String(), nil)
+		address, _, _ := net.SplitHostPort(r.RemoteAddr)
+		token := cache.NewAuthToken(request.Username, request.LoginProvider, address)
+		err = cache.GlobalCache.SetToken(token)
 		if err != nil {
 			f5Error(w, r, http.StatusInternalServerError, "could not set cache entry")
 			return
 		}
 
-		response := LoginResponse{Token: Token{token.String()}}
+		response := LoginResponse{
+			Username:          request.Username,
+			LoginProviderName: request.LoginProvider,
+			Token:             token,
+		}
 
 		jsonBytes, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
@@ -83,19 +77,35 @@ func (h LoginHandler) Handler() http.HandlerFunc {
 	}
 }
 
-func checkAuth(username, password string) error {
-	expectedUsername := os.Getenv("F5_ADMIN_USERNAME")
-	expectedPassword := os.Getenv("F5_ADMIN_PASSWORD")
+var (
+	errUnknownUsername   = errors.New("unknown username")
+	errBadAuthentication = errors.New("bad authentication")
+)
+
+// checkAuth checks credentials against the local user store
+func checkAuth(auth config.Auth, username, password string) (*models.User, error) {
+	user := findUser(auth, username)
+	if user == nil {
+		return nil, errUnknownUsername
+	}
 
-	if username != expectedUsername {
-		return errors.New("unknown username")
+	if isLocked(user) {
+		return nil, fmt.Errorf("account %s is locked", username)
 	}
 
-	if password != expectedPassword {
-		return errors.New("bad authentication")
+	// Accounts without a password, like the configured admin when none is set, cannot log in
+	if user.Password == "" || password != user.Password {
+		recordLoginFailure(user)
+		return nil, errBadAuthentication
 	}
+	user.FailedLogins = 0
 
-	return nil
+	if isPasswordExpired(user) {
+		// The user is still returned so that it can change its password
+		return user, errPasswordExpired
+	}
+
+	return user, nil
 }
 
 type LoginRequest struct {
@@ -105,9 +115,7 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token Token `json:"token"`
-}
-
-type Token struct {
-	Token string `json:"token"`
+	Username          string           `json:"username"`
+	LoginProviderName string           `json:"loginProviderName"`
+	Token             models.AuthToken `json:"token"`
 }
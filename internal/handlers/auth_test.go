@@ -3,76 +3,73 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/iilun/f5-mock/internal/config"
 	"github.com/iilun/f5-mock/internal/log"
 	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 )
 
 func TestAuthHandler(t *testing.T) {
 
-	defaultEnv := map[string]string{
-		"F5_LOGIN_PROVIDER": "tmos",
-		"F5_ADMIN_USERNAME": "admin",
-		"F5_ADMIN_PASSWORD": "secret",
-	}
+	defaultAuth := config.Auth{LoginProvider: "tmos", AdminUsername: "admin", AdminPassword: "secret"}
 
 	tests := []struct {
 		name       string
-		env        map[string]string // optional overrides
+		auth       config.Auth
 		body       any
 		wantStatus int
 		wantBody   string
 	}{
 		{
-			name: "login disabled",
-			env: map[string]string{
-				"F5_LOGIN_PROVIDER": "",
-				"F5_ADMIN_USERNAME": "admin",
-				"F5_ADMIN_PASSWORD": "secret",
+			name: "login without login provider",
+			auth: config.Auth{LoginProvider: "", AdminUsername: "admin", AdminPassword: "secret"},
+			body: map[string]string{
+				"username":      "admin",
+				"password":      "secret",
+				"loginProvider": "tmos",
 			},
-			body:       map[string]string{},
-			wantStatus: http.StatusForbidden,
-			wantBody:   "login not available",
+			wantStatus: http.StatusOK,
+			wantBody:   `"token"`,
 		},
 		{
 			name:       "invalid JSON body",
 			body:       "not-json",
 			wantStatus: http.StatusBadRequest,
 			wantBody:   "invalid JSON body",
-			env:        defaultEnv,
+			auth:       defaultAuth,
 		},
 		{
 			name:       "missing fields",
 			body:       map[string]string{"username": "admin"},
 			wantStatus: http.StatusBadRequest,
 			wantBody:   "invalid request",
-			env:        defaultEnv,
+			auth:       defaultAuth,
 		},
 		{
 			name: "bad username",
 			body: map[string]string{
 				"username":      "wrong",
 				"password":      "secret",
-				"loginProvider": "f5",
+				"loginProvider": "tmos",
 			},
 			wantStatus: http.StatusBadRequest,
 			wantBody:   "unknown username",
-			env:        defaultEnv,
+			auth:       defaultAuth,
 		},
 		{
 			name: "bad password",
 			body: map[string]string{
 				"username":      "admin",
 				"password":      "wrong",
-				"loginProvider": "f5",
+				"loginProvider": "tmos",
 			},
 			wantStatus: http.StatusBadRequest,
 			wantBody:   "bad authentication",
-			env:        defaultEnv,
+			auth:       defaultAuth,
 		},
 		{
 			name: "wrong login provider",
@@ -83,7 +80,7 @@ func TestAuthHandler(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantBody:   "unknown login provider",
-			env:        defaultEnv,
+			auth:       defaultAuth,
 		},
 		{
 			name: "successful login",
@@ -94,7 +91,92 @@ func TestAuthHandler(t *testing.T) {
 			},
 			wantStatus: http.StatusOK,
 			wantBody:   `"token"`,
-			env:        defaultEnv,
+			auth:       defaultAuth,
+		},
+		{
+			name: "remote provider login",
+			body: map[string]string{
+				"username":      "alice",
+				"password":      "alicepass",
+				"loginProvider": "corp-ldap",
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `"loginProviderName": "corp-ldap"`,
+			auth:       defaultAuth,
+		},
+		{
+			name: "remote user with the local provider",
+			body: map[string]string{
+				"username":      "alice",
+				"password":      "alicepass",
+				"loginProvider": "local",
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "unknown username",
+			auth:       defaultAuth,
+		},
+		{
+			name: "tmos uses the system provider",
+			body: map[string]string{
+				"username":      "alice",
+				"password":      "alicepass",
+				"loginProvider": "tmos",
+			},
+			wantStatus: http.StatusOK,
+			auth:       config.Auth{LoginProvider: "corp-ldap", AdminUsername: "admin", AdminPassword: "secret"},
+		},
+		{
+			name: "tmos falls back to local users",
+			body: map[string]string{
+				"username":      "admin",
+				"password":      "secret",
+				"loginProvider": "tmos",
+			},
+			wantStatus: http.StatusOK,
+			auth:       config.Auth{LoginProvider: "corp-ldap", AdminUsername: "admin", AdminPassword: "secret"},
+		},
+		{
+			name: "locked account",
+			body: map[string]string{
+				"username":      "bob",
+				"password":      "bobpass",
+				"loginProvider": "corp-ldap",
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "account bob is locked",
+			auth:       defaultAuth,
+		},
+		{
+			name: "no role mapped",
+			body: map[string]string{
+				"username":      "carol",
+				"password":      "carolpass",
+				"loginProvider": "corp-ldap",
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "no role mapped for user carol",
+			auth:       defaultAuth,
+		},
+		{
+			name: "role mapped to a deleted partition",
+			body: map[string]string{
+				"username":      "dave",
+				"password":      "davepass",
+				"loginProvider": "corp-ldap",
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid role mapped for user dave: The requested partition (Gone) was not found.",
+			auth:       defaultAuth,
+		},
+		{
+			name: "default role",
+			body: map[string]string{
+				"username":      "carol",
+				"password":      "carolpass",
+				"loginProvider": "radius",
+			},
+			wantStatus: http.StatusOK,
+			auth:       defaultAuth,
 		},
 	}
 
@@ -103,17 +185,35 @@ func TestAuthHandler(t *testing.T) {
 
 	_, _ = cache.New("")
 
+	cache.GlobalCache.LoginProviders = []*models.LoginProvider{
+		{
+			Name: "corp-ldap",
+			Type: "ldap",
+			Users: []*models.RemoteUser{
+				{Name: "alice", Password: "alicepass", Groups: []string{"certadmins"}},
+				{Name: "bob", Password: "bobpass", Groups: []string{"certadmins"}, Locked: true},
+				{Name: "carol", Password: "carolpass"},
+				{Name: "dave", Password: "davepass", Groups: []string{"gone"}},
+			},
+			RoleMapping: []models.RoleMapping{
+				{Group: "certadmins", Role: models.RoleCertificateManager, Partition: "Common"},
+				{Group: "gone", Role: models.RoleManager, Partition: "Gone"},
+			},
+		},
+		{
+			Name:        "radius",
+			Type:        "radius",
+			Users:       []*models.RemoteUser{{Name: "carol", Password: "carolpass"}},
+			DefaultRole: models.RoleGuest,
+		},
+	}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			for k, v := range tt.env {
-				if v == "" {
-					_ = os.Unsetenv(k)
-				} else {
-					_ = os.Setenv(k, v)
-				}
-			}
+			cfg := testConfig()
+			cfg.Auth = tt.auth
 
-			handler := F5HandlerWrapper{LoginHandler{}, logger}
+			handler := F5HandlerWrapper{LoginHandler{}, logger, &cfg}
 			reqBody := &bytes.Buffer{}
 
 			switch v := tt.body.(type) {
@@ -137,8 +237,14 @@ func TestAuthHandler(t *testing.T) {
 				err := json.Unmarshal(rr.Body.Bytes(), &resp)
 				require.NoError(t, err)
 				// Verify token set in cache
-				_, err = cache.GlobalCache.AuthTokens.Get(resp.Token.Token)
+				token, err := cache.GlobalCache.GetToken(resp.Token.Token)
 				require.NoError(t, err)
+				require.Equal(t, cache.DefaultTokenTimeout, token.Timeout)
+				require.Equal(t, tt.body.(map[string]string)["username"], resp.Username)
+
+				// The token resolves to the mapped user
+				user := lookupUser(cfg.Auth, token.AuthProviderName, token.UserName)
+				require.NotNil(t, user)
 			}
 		})
 	}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/iilun/f5-mock/internal/ciphers"
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+)
+
+// CipherEvaluationHandler expands a cipher string, a cipher group or the ciphers of a client-ssl profile into
+// the ordered suites offered by the requested version, like tmm --clientciphers. It is not part of the iControl
+// REST API.
+type CipherEvaluationHandler struct{}
+
+func (h CipherEvaluationHandler) Route() string {
+	return "/mgmt/shared/mock/ciphers"
+}
+
+func (h CipherEvaluationHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				f5Error(w, r, http.StatusMethodNotAllowed, "only GET allowed")
+				return
+			}
+
+			query := r.URL.Query()
+			resp := CipherEvaluationResponse{
+				CipherString: query.Get("cipherString"),
+				CipherGroup:  query.Get("cipherGroup"),
+				Profile:      query.Get("profile"),
+				Items:        []CipherSuiteResponse{},
+			}
+			resp.Version, _ = r.Context().Value(log.ContextVersion).(string)
+			majorVersion, _ := r.Context().Value(log.ContextMajorVersion).(int)
+
+			given := 0
+			for _, value := range []string{resp.CipherString, resp.CipherGroup, resp.Profile} {
+				if value != "" {
+					given++
+				}
+			}
+			if given != 1 {
+				f5Error(w, r, http.StatusBadRequest, "exactly one of cipherString, cipherGroup and profile must be given")
+				return
+			}
+
+			var entries []ciphers.Entry
+			var err error
+			switch {
+			case resp.CipherString != "":
+				entries, err = ciphers.Evaluate(resp.CipherString, majorVersion)
+			case resp.CipherGroup != "":
+				groupPath, pathErr := parsePath(resp.CipherGroup, configFromRequest(r).DefaultPartition)
+				if pathErr != nil {
+					f5Error(w, r, http.StatusBadRequest, "%v", pathErr.Error())
+					return
+				}
+				if !checkPartitionAccess(w, r, groupPath.partition) {
+					return
+				}
+				if cache.GlobalCache.FindCipherGroup(groupPath.fullPath()) == nil {
+					f5Error(w, r, http.StatusNotFound, "The requested cipher group (%s) was not found.", groupPath.fullPath())
+					return
+				}
+				resp.CipherGroup = groupPath.fullPath()
+				entries, err = cache.GlobalCache.EvaluateCipherGroup(groupPath.fullPath(), majorVersion)
+			default:
+				profilePath, pathErr := parsePath(resp.Profile, configFromRequest(r).DefaultPartition)
+				if pathErr != nil {
+					f5Error(w, r, http.StatusBadRequest, "%v", pathErr.Error())
+					return
+				}
+				if !checkPartitionAccess(w, r, profilePath.partition) {
+					return
+				}
+				profile := findProfile(profilePath)
+				if profile == nil {
+					f5Error(w, r, http.StatusNotFound, "The requested client-ssl profile (%s) was not found.", profilePath.fullPath())
+					return
+				}
+				resp.Profile = profilePath.fullPath()
+				entries, err = cache.GlobalCache.EvaluateProfileCiphers(profile, majorVersion)
+			}
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "%v", err)
+				return
+			}
+
+			for _, e := range entries {
+				resp.Items = append(resp.Items, CipherSuiteResponse{
+					ID:          e.ID,
+					Name:        e.Name,
+					Bits:        e.Bits,
+					Protocol:    e.Protocol,
+					Cipher:      e.Cipher,
+					Mac:         e.Mac,
+					KeyExchange: e.KeyExchange,
+					Strength:    e.Strength,
+				})
+			}
+
+			writeJSON(w, r, resp)
+		})
+}
+
+type CipherSuiteResponse struct {
+	ID          uint16 `json:"id"`
+	Name        string `json:"name"`
+	Bits        int    `json:"bits"`
+	Protocol    string `json:"protocol"`
+	Cipher      string `json:"cipher"`
+	Mac         string `json:"mac"`
+	KeyExchange string `json:"keyExchange"`
+	Strength    string `json:"strength"`
+}
+
+type CipherEvaluationResponse struct {
+	CipherString string                `json:"cipherString,omitempty"`
+	CipherGroup  string                `json:"cipherGroup,omitempty"`
+	Profile      string                `json:"profile,omitempty"`
+	Version      string                `json:"version"`
+	Items        []CipherSuiteResponse `json:"items"`
+}
@@ -2,12 +2,92 @@ package handlers
 
 import (
 	"encoding/json"
-	"github.com/iilun/f5-mock/pkg/cache"
-	"github.com/iilun/f5-mock/pkg/models"
+	"fmt"
+	"io"
 	"net/http"
 	"slices"
+	"strings"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/f5Validator"
+	"github.com/iilun/f5-mock/pkg/models"
 )
 
+type CipherGroupListHandler struct{}
+
+func (h CipherGroupListHandler) Route() string {
+	return "/mgmt/tm/ltm/cipher/group"
+}
+
+func (h CipherGroupListHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				user := userFromRequest(r)
+				items := []CipherGroupResponse{}
+				for _, group := range cache.GlobalCache.ListCipherGroups() {
+					if canReadPartition(user, group.Partition) {
+						items = append(items, newCipherGroupResponse(r, group))
+					}
+				}
+
+				writeJSON(w, r, CipherGroupListResponse{
+					Kind:  "tm:ltm:cipher:group:groupcollectionstate",
+					Items: items,
+				})
+			case http.MethodPost:
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
+				}
+
+				var group models.CipherGroup
+				err = json.Unmarshal(bodyBytes, &group)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid post request")
+					return
+				}
+
+				groupPath, ok := resolveCipherPath(w, r, group.Name, group.Partition)
+				if !ok {
+					return
+				}
+				group.Name, group.Partition = groupPath.name, groupPath.partition
+
+				err = f5Validator.Validate.Struct(group)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid cipher group: %v", err)
+					return
+				}
+
+				if !checkPartitionAccess(w, r, group.Partition) || !checkFolderExists(w, r, groupPath.folder()) {
+					return
+				}
+
+				if cache.GlobalCache.FindCipherGroup(groupPath.fullPath()) != nil {
+					f5Error(w, r, http.StatusConflict, "The requested cipher group (%s) already exists.", groupPath.fullPath())
+					return
+				}
+
+				if !checkCipherReferences(w, r, &group) {
+					return
+				}
+
+				logger := loggerFromRequest(r)
+				logger.Debug("Added %s cipher group", groupPath.fullPath())
+
+				cache.GlobalCache.CipherGroups = append(cache.GlobalCache.CipherGroups, &group)
+
+				writeJSON(w, r, newCipherGroupResponse(r, &group))
+			default:
+				f5Error(w, r, http.StatusMethodNotAllowed, "invalid method")
+			}
+		})
+}
+
 type CipherGroupHandler struct{}
 
 func (h CipherGroupHandler) Route() string {
@@ -17,41 +97,152 @@ func (h CipherGroupHandler) Route() string {
 func (h CipherGroupHandler) Handler() http.HandlerFunc {
 	return authenticatedRequestMiddleware(
 		func(w http.ResponseWriter, r *http.Request) {
-			// Partition is ignored for groups
-			_, group, err := parsePath(r.PathValue("group"))
+			groupPath, err := parsePath(r.PathValue("group"), configFromRequest(r).DefaultPartition)
 			if err != nil {
 				f5Error(w, r, http.StatusBadRequest, "%v", err.Error())
 				return
 			}
 
-			if !slices.Contains(cache.GlobalCache.CipherGroups, group) {
-				f5Error(w, r, http.StatusNotFound, "group not found")
+			if !checkPartitionAccess(w, r, groupPath.partition) {
+				return
+			}
+
+			group := cache.GlobalCache.FindCipherGroup(groupPath.fullPath())
+			if group == nil || groupPath.subPath != "" {
+				f5Error(w, r, http.StatusNotFound, "The requested cipher group (%s) was not found.", groupPath.fullPath())
+				return
+			}
+
+			if isWriteRequest(r) && cache.IsBuiltinCipherGroup(groupPath.fullPath()) {
+				f5Error(w, r, http.StatusBadRequest, "The built-in cipher group (%s) cannot be modified.", groupPath.fullPath())
 				return
 			}
 
 			switch r.Method {
 			case http.MethodGet:
-
-				cg := models.CipherGroup{
-					Kind: "tm:ltm:cipher:group:groupstate",
-					Name: group,
+				writeJSON(w, r, newCipherGroupResponse(r, group))
+			case http.MethodPatch:
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
 				}
 
-				respBytes, err := json.Marshal(cg)
+				// Apply the patch on a copy so that invalid values leave the group untouched, json reusing
+				// the backing arrays of slices
+				updated := *group
+				updated.Allow = slices.Clone(group.Allow)
+				updated.Exclude = slices.Clone(group.Exclude)
+				updated.Require = slices.Clone(group.Require)
+				err = json.Unmarshal(bodyBytes, &updated)
 				if err != nil {
-					f5Error(w, r, http.StatusInternalServerError, "could not marshal")
+					f5Error(w, r, http.StatusBadRequest, "invalid patch request")
+					return
+				}
+
+				if updated.Name != group.Name || updated.Partition != group.Partition {
+					f5Error(w, r, http.StatusBadRequest, "cipher group name and partition cannot be modified")
 					return
 				}
 
-				_, err = w.Write(respBytes)
+				err = f5Validator.Validate.Struct(updated)
 				if err != nil {
-					f5Error(w, r, http.StatusInternalServerError, "could not write response")
+					f5Error(w, r, http.StatusBadRequest, "invalid cipher group: %v", err)
 					return
 				}
-				break
+
+				if !checkCipherReferences(w, r, &updated) {
+					return
+				}
+
+				*group = updated
+
+				writeJSON(w, r, newCipherGroupResponse(r, group))
+			case http.MethodDelete:
+				if profiles := cache.GlobalCache.CipherGroupUsers(groupPath.fullPath()); len(profiles) > 0 {
+					f5Error(w, r, http.StatusBadRequest, "The cipher group (%s) cannot be deleted, it is used by client-ssl profile %s.", groupPath.fullPath(), strings.Join(profiles, ", "))
+					return
+				}
+
+				cache.GlobalCache.CipherGroups = slices.DeleteFunc(cache.GlobalCache.CipherGroups, func(cg *models.CipherGroup) bool {
+					return cg == group
+				})
+
+				writeJSON(w, r, newCipherGroupResponse(r, group))
 			default:
 				f5Error(w, r, http.StatusMethodNotAllowed, "invalid method")
-				return
 			}
 		})
 }
+
+// checkCipherReferences writes a 400 error and returns false if a rule referenced by the group does not
+// exist. References are stored by full path.
+func checkCipherReferences(w http.ResponseWriter, r *http.Request, group *models.CipherGroup) bool {
+	for _, refs := range [][]models.CipherReference{group.Allow, group.Exclude, group.Require} {
+		for i, ref := range refs {
+			if cache.GlobalCache.FindCipherRule(ref.Name) == nil {
+				f5Error(w, r, http.StatusBadRequest, "The requested cipher rule (%s) was not found.", cache.CipherPath(ref.Name))
+				return false
+			}
+			refs[i].Name = cache.CipherPath(ref.Name)
+		}
+	}
+	return true
+}
+
+func newCipherGroupResponse(r *http.Request, group *models.CipherGroup) CipherGroupResponse {
+	version, _ := r.Context().Value(log.ContextVersion).(string)
+	fullPath := cache.CipherPath(models.FullPath(group.Partition, "", group.Name))
+
+	references := func(refs []models.CipherReference) []CipherReferenceResponse {
+		var items []CipherReferenceResponse
+		for _, ref := range refs {
+			refPath := cache.CipherPath(ref.Name)
+			partition, name, _ := strings.Cut(strings.TrimPrefix(refPath, "/"), "/")
+			items = append(items, CipherReferenceResponse{Name: name, Partition: partition, FullPath: refPath})
+		}
+		return items
+	}
+
+	ordering := group.Ordering
+	if ordering == "" {
+		ordering = "default"
+	}
+
+	return CipherGroupResponse{
+		Kind:        "tm:ltm:cipher:group:groupstate",
+		Name:        group.Name,
+		Partition:   group.Partition,
+		FullPath:    fullPath,
+		Description: group.Description,
+		Ordering:    ordering,
+		Allow:       references(group.Allow),
+		Exclude:     references(group.Exclude),
+		Require:     references(group.Require),
+		SelfLink:    fmt.Sprintf("https://localhost/mgmt/tm/ltm/cipher/group/%s?ver=%s", strings.ReplaceAll(fullPath, "/", "~"), version),
+	}
+}
+
+type CipherReferenceResponse struct {
+	Name      string `json:"name"`
+	Partition string `json:"partition"`
+	FullPath  string `json:"fullPath"`
+}
+
+type CipherGroupResponse struct {
+	Kind        string                    `json:"kind"`
+	Name        string                    `json:"name"`
+	Partition   string                    `json:"partition"`
+	FullPath    string                    `json:"fullPath"`
+	Description string                    `json:"description,omitempty"`
+	Ordering    string                    `json:"ordering"`
+	Allow       []CipherReferenceResponse `json:"allow,omitempty"`
+	Exclude     []CipherReferenceResponse `json:"exclude,omitempty"`
+	Require     []CipherReferenceResponse `json:"require,omitempty"`
+	SelfLink    string                    `json:"selfLink"`
+}
+
+type CipherGroupListResponse struct {
+	Kind  string                `json:"kind"`
+	Items []CipherGroupResponse `json:"items"`
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/iilun/f5-mock/internal/ciphers"
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/f5Validator"
+	"github.com/iilun/f5-mock/pkg/models"
+)
+
+type CipherRuleListHandler struct{}
+
+func (h CipherRuleListHandler) Route() string {
+	return "/mgmt/tm/ltm/cipher/rule"
+}
+
+func (h CipherRuleListHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				user := userFromRequest(r)
+				items := []CipherRuleResponse{}
+				for _, rule := range cache.GlobalCache.ListCipherRules() {
+					if canReadPartition(user, rule.Partition) {
+						items = append(items, newCipherRuleResponse(r, rule))
+					}
+				}
+
+				writeJSON(w, r, CipherRuleListResponse{
+					Kind:  "tm:ltm:cipher:rule:rulecollectionstate",
+					Items: items,
+				})
+			case http.MethodPost:
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
+				}
+
+				var rule models.CipherRule
+				err = json.Unmarshal(bodyBytes, &rule)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid post request")
+					return
+				}
+
+				rulePath, ok := resolveCipherPath(w, r, rule.Name, rule.Partition)
+				if !ok {
+					return
+				}
+				rule.Name, rule.Partition = rulePath.name, rulePath.partition
+
+				err = f5Validator.Validate.Struct(rule)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid cipher rule: %v", err)
+					return
+				}
+
+				if !checkPartitionAccess(w, r, rule.Partition) || !checkFolderExists(w, r, rulePath.folder()) {
+					return
+				}
+
+				if cache.GlobalCache.FindCipherRule(rulePath.fullPath()) != nil {
+					f5Error(w, r, http.StatusConflict, "The requested cipher rule (%s) already exists.", rulePath.fullPath())
+					return
+				}
+
+				err = ciphers.Validate(rule.Cipher)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "Cipher rule %s: %v", rulePath.fullPath(), err)
+					return
+				}
+
+				logger := loggerFromRequest(r)
+				logger.Debug("Added %s cipher rule", rulePath.fullPath())
+
+				cache.GlobalCache.CipherRules = append(cache.GlobalCache.CipherRules, &rule)
+
+				writeJSON(w, r, newCipherRuleResponse(r, &rule))
+			default:
+				f5Error(w, r, http.StatusMethodNotAllowed, "invalid method")
+			}
+		})
+}
+
+type CipherRuleHandler struct{}
+
+func (h CipherRuleHandler) Route() string {
+	return "/mgmt/tm/ltm/cipher/rule/{rule}"
+}
+
+func (h CipherRuleHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			rulePath, err := parsePath(r.PathValue("rule"), configFromRequest(r).DefaultPartition)
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "%v", err.Error())
+				return
+			}
+
+			if !checkPartitionAccess(w, r, rulePath.partition) {
+				return
+			}
+
+			rule := cache.GlobalCache.FindCipherRule(rulePath.fullPath())
+			if rule == nil || rulePath.subPath != "" {
+				f5Error(w, r, http.StatusNotFound, "The requested cipher rule (%s) was not found.", rulePath.fullPath())
+				return
+			}
+
+			if isWriteRequest(r) && cache.IsBuiltinCipherRule(rulePath.fullPath()) {
+				f5Error(w, r, http.StatusBadRequest, "The built-in cipher rule (%s) cannot be modified.", rulePath.fullPath())
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				writeJSON(w, r, newCipherRuleResponse(r, rule))
+			case http.MethodPatch:
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
+				}
+
+				// Apply the patch on a copy so that invalid values leave the rule untouched
+				updated := *rule
+				err = json.Unmarshal(bodyBytes, &updated)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid patch request")
+					return
+				}
+
+				if updated.Name != rule.Name || updated.Partition != rule.Partition {
+					f5Error(w, r, http.StatusBadRequest, "cipher rule name and partition cannot be modified")
+					return
+				}
+
+				err = f5Validator.Validate.Struct(updated)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid cipher rule: %v", err)
+					return
+				}
+
+				err = ciphers.Validate(updated.Cipher)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "Cipher rule %s: %v", rulePath.fullPath(), err)
+					return
+				}
+
+				*rule = updated
+
+				writeJSON(w, r, newCipherRuleResponse(r, rule))
+			case http.MethodDelete:
+				if groups := cache.GlobalCache.CipherRuleUsers(rulePath.fullPath()); len(groups) > 0 {
+					f5Error(w, r, http.StatusBadRequest, "The cipher rule (%s) cannot be deleted, it is used by cipher group %s.", rulePath.fullPath(), strings.Join(groups, ", "))
+					return
+				}
+
+				cache.GlobalCache.CipherRules = slices.DeleteFunc(cache.GlobalCache.CipherRules, func(cr *models.CipherRule) bool {
+					return cr == rule
+				})
+
+				writeJSON(w, r, newCipherRuleResponse(r, rule))
+			default:
+				f5Error(w, r, http.StatusMethodNotAllowed, "invalid method")
+			}
+		})
+}
+
+// resolveCipherPath returns the path of a cipher rule or group to create, writing a 400 error and returning
+// false if it is invalid. Cipher rules and groups live at the root of partitions.
+func resolveCipherPath(w http.ResponseWriter, r *http.Request, name, partition string) (objectPath, bool) {
+	cipherPath, err := resolvePath(name, partition, "", configFromRequest(r).DefaultPartition)
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "%v", err.Error())
+		return objectPath{}, false
+	}
+	if cipherPath.subPath != "" {
+		f5Error(w, r, http.StatusBadRequest, "Cipher rules and groups cannot be created in folders (%s).", cipherPath.fullPath())
+		return objectPath{}, false
+	}
+	return cipherPath, true
+}
+
+func newCipherRuleResponse(r *http.Request, rule *models.CipherRule) CipherRuleResponse {
+	version, _ := r.Context().Value(log.ContextVersion).(string)
+	fullPath := cache.CipherPath(models.FullPath(rule.Partition, "", rule.Name))
+
+	return CipherRuleResponse{
+		Kind:                "tm:ltm:cipher:rule:rulestate",
+		Name:                rule.Name,
+		Partition:           rule.Partition,
+		FullPath:            fullPath,
+		Description:         rule.Description,
+		Cipher:              rule.Cipher,
+		DHGroups:            rule.DHGroups,
+		SignatureAlgorithms: rule.SignatureAlgorithms,
+		SelfLink:            fmt.Sprintf("https://localhost/mgmt/tm/ltm/cipher/rule/%s?ver=%s", strings.ReplaceAll(fullPath, "/", "~"), version),
+	}
+}
+
+type CipherRuleResponse struct {
+	Kind                string `json:"kind"`
+	Name                string `json:"name"`
+	Partition           string `json:"partition"`
+	FullPath            string `json:"fullPath"`
+	Description         string `json:"description,omitempty"`
+	Cipher              string `json:"cipher"`
+	DHGroups            string `json:"dhGroups,omitempty"`
+	SignatureAlgorithms string `json:"signatureAlgorithms,omitempty"`
+	SelfLink            string `json:"selfLink"`
+}
+
+type CipherRuleListResponse struct {
+	Kind  string               `json:"kind"`
+	Items []CipherRuleResponse `json:"items"`
+}
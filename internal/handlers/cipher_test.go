@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCipherRulesAndGroups(t *testing.T) {
+	cfg := testConfig()
+
+	_, _ = cache.New("")
+
+	logger := log.New(true)
+	defer logger.Close()
+
+	cache.GlobalCache.Partitions = []*models.Partition{{Name: "Prod"}}
+	cache.GlobalCache.CipherRules = nil
+	cache.GlobalCache.CipherGroups = nil
+	cache.GlobalCache.ClientSSLProfiles = []*models.ClientSSLProfile{{Name: "web", Partition: "Prod", CipherGroup: "/Prod/gcm"}}
+
+	tests := []struct {
+		name       string
+		handler    F5Handler
+		method     string
+		pathValue  string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "built-in rules",
+			handler:    CipherRuleListHandler{},
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"kind":"tm:ltm:cipher:rule:rulestate","name":"f5-default","partition":"Common","fullPath":"/Common/f5-default","description":"Default cipher rule","cipher":"DEFAULT"`,
+		},
+		{
+			name:       "create rule",
+			handler:    CipherRuleListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"gcm","partition":"Prod","cipher":"ECDHE+AESGCM:!SHA1"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"name":"gcm","partition":"Prod","fullPath":"/Prod/gcm","cipher":"ECDHE+AESGCM:!SHA1","selfLink":"https://localhost/mgmt/tm/ltm/cipher/rule/~Prod~gcm?ver=17.0.0.0"`,
+		},
+		{
+			name:       "duplicate rule",
+			handler:    CipherRuleListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"/Prod/gcm","cipher":"DEFAULT"}`,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "invalid cipher string",
+			handler:    CipherRuleListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"bad","cipher":"ECDHE:+:RSA"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "Cipher rule /Common/bad: invalid cipher string 'ECDHE:+:RSA': unknown keyword ''",
+		},
+		{
+			name:       "rule in a missing partition",
+			handler:    CipherRuleListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"gcm","partition":"Missing","cipher":"DEFAULT"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "The requested folder (/Missing) was not found.",
+		},
+		{
+			name:       "built-in rules are read only",
+			handler:    CipherRuleHandler{},
+			method:     http.MethodPatch,
+			pathValue:  "f5-default",
+			body:       `{"cipher":"ALL"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "The built-in cipher rule (/Common/f5-default) cannot be modified.",
+		},
+		{
+			name:       "update rule",
+			handler:    CipherRuleHandler{},
+			method:     http.MethodPatch,
+			pathValue:  "~Prod~gcm",
+			body:       `{"cipher":"ECDHE+AESGCM","dhGroups":"P256:P384"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"cipher":"ECDHE+AESGCM","dhGroups":"P256:P384"`,
+		},
+		{
+			name:       "invalid rule update",
+			handler:    CipherRuleHandler{},
+			method:     http.MethodPatch,
+			pathValue:  "~Prod~gcm",
+			body:       `{"cipher":"BOGUS"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "create group",
+			handler:    CipherGroupListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"gcm","partition":"Prod","ordering":"strength","allow":[{"name":"f5-secure"}],"require":[{"name":"/Prod/gcm"}]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"fullPath":"/Prod/gcm","ordering":"strength","allow":[{"name":"f5-secure","partition":"Common","fullPath":"/Common/f5-secure"}],"require":[{"name":"gcm","partition":"Prod","fullPath":"/Prod/gcm"}]`,
+		},
+		{
+			name:       "group with a missing rule",
+			handler:    CipherGroupListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"other","allow":[{"name":"missing"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "The requested cipher rule (/Common/missing) was not found.",
+		},
+		{
+			name:       "invalid ordering",
+			handler:    CipherGroupListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"other","ordering":"random"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid group update",
+			handler:    CipherGroupHandler{},
+			method:     http.MethodPatch,
+			pathValue:  "~Prod~gcm",
+			body:       `{"exclude":[{"name":"missing"}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "update group",
+			handler:    CipherGroupHandler{},
+			method:     http.MethodPatch,
+			pathValue:  "~Prod~gcm",
+			body:       `{"exclude":[{"name":"f5-aes"}]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"exclude":[{"name":"f5-aes","partition":"Common","fullPath":"/Common/f5-aes"}],"require":[{"name":"gcm"`,
+		},
+		{
+			name:       "built-in groups",
+			handler:    CipherGroupHandler{},
+			method:     http.MethodGet,
+			pathValue:  "f5-default",
+			wantStatus: http.StatusOK,
+			wantBody:   `"ordering":"default","allow":[{"name":"f5-default","partition":"Common","fullPath":"/Common/f5-default"}]`,
+		},
+		{
+			name:       "rule used by a group",
+			handler:    CipherRuleHandler{},
+			method:     http.MethodDelete,
+			pathValue:  "~Prod~gcm",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "it is used by cipher group /Prod/gcm",
+		},
+		{
+			name:       "group used by a profile",
+			handler:    CipherGroupHandler{},
+			method:     http.MethodDelete,
+			pathValue:  "~Prod~gcm",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "it is used by client-ssl profile /Prod/web",
+		},
+		{
+			name:       "partition holding cipher objects",
+			handler:    PartitionHandler{},
+			method:     http.MethodDelete,
+			pathValue:  "Prod",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "ltm cipher rule /Prod/gcm, ltm cipher group /Prod/gcm",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := F5HandlerWrapper{tt.handler, logger, &cfg}
+
+			req := httptest.NewRequest(tt.method, h.Route(), bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetPathValue("rule", tt.pathValue)
+			req.SetPathValue("group", tt.pathValue)
+			req.SetPathValue("name", tt.pathValue)
+			req.SetBasicAuth(cfg.Auth.AdminUsername, cfg.Auth.AdminPassword)
+
+			rr := httptest.NewRecorder()
+			h.Handler()(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			require.Contains(t, rr.Body.String(), tt.wantBody)
+		})
+	}
+
+	cache.GlobalCache.Partitions = nil
+	cache.GlobalCache.ClientSSLProfiles = nil
+	cache.GlobalCache.CipherRules = nil
+	cache.GlobalCache.CipherGroups = nil
+}
+
+func TestCipherEvaluation(t *testing.T) {
+	cfg := testConfig()
+
+	_, _ = cache.New("")
+
+	logger := log.New(true)
+	defer logger.Close()
+
+	cache.GlobalCache.CipherRules = []*models.CipherRule{{Name: "rsa-gcm", Partition: "Common", Cipher: "RSA+AESGCM"}}
+	cache.GlobalCache.CipherGroups = []*models.CipherGroup{{
+		Name:      "gcm",
+		Partition: "Common",
+		Ordering:  "strength",
+		Allow:     []models.CipherReference{{Name: "/Common/f5-default"}},
+		Require:   []models.CipherReference{{Name: "/Common/rsa-gcm"}},
+	}}
+	cache.GlobalCache.ClientSSLProfiles = []*models.ClientSSLProfile{
+		{Name: "grouped", Partition: "Common", CipherGroup: "/Common/gcm", Ciphers: "none"},
+		{Name: "default", Partition: "Common"},
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "cipher string",
+			query:      "cipherString=ECDHE-RSA-AES256-GCM-SHA384",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"cipherString":"ECDHE-RSA-AES256-GCM-SHA384","version":"17.0.0.0","items":[{"id":49200,"name":"ECDHE-RSA-AES256-GCM-SHA384","bits":256,"protocol":"TLS1.2","cipher":"AES-GCM","mac":"SHA384","keyExchange":"ECDHE_RSA","strength":"HIGH"}]}`,
+		},
+		{
+			name:       "cipher group",
+			query:      "cipherGroup=gcm",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"cipherGroup":"/Common/gcm","version":"17.0.0.0","items":[{"id":157,"name":"AES256-GCM-SHA384"`,
+		},
+		{
+			name:       "profile with a cipher group",
+			query:      "profile=~Common~grouped",
+			wantStatus: http.StatusOK,
+			wantBody:   `"items":[{"id":157,"name":"AES256-GCM-SHA384","bits":256,"protocol":"TLS1.2","cipher":"AES-GCM","mac":"SHA384","keyExchange":"RSA","strength":"HIGH"},{"id":156,"name":"AES128-GCM-SHA256"`,
+		},
+		{
+			name:       "default ciphers depend on the version",
+			query:      "profile=default&ver=12.1.0",
+			wantStatus: http.StatusOK,
+			wantBody:   `"name":"RC4-SHA"`,
+		},
+		{
+			name:       "invalid cipher string",
+			query:      "cipherString=HIGH:WEAK",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "unknown keyword 'WEAK'",
+		},
+		{
+			name:       "missing group",
+			query:      "cipherGroup=missing",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "several sources",
+			query:      "cipherString=DEFAULT&cipherGroup=f5-default",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := F5HandlerWrapper{CipherEvaluationHandler{}, logger, &cfg}
+
+			req := httptest.NewRequest(http.MethodGet, h.Route()+"?"+tt.query, nil)
+			req.SetBasicAuth(cfg.Auth.AdminUsername, cfg.Auth.AdminPassword)
+
+			rr := httptest.NewRecorder()
+			h.Handler()(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			require.Contains(t, rr.Body.String(), tt.wantBody)
+		})
+	}
+
+	cache.GlobalCache.ClientSSLProfiles = nil
+	cache.GlobalCache.CipherRules = nil
+	cache.GlobalCache.CipherGroups = nil
+}
@@ -7,10 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
+	"github.com/iilun/f5-mock/internal/ciphers"
 	"github.com/iilun/f5-mock/internal/crypto"
 	"github.com/iilun/f5-mock/internal/log"
 	"github.com/iilun/f5-mock/pkg/cache"
@@ -45,8 +46,12 @@ func (h ClientSSLListHandler) Handler() http.HandlerFunc {
 			}
 
 			filteredItems := []map[string]any{}
+			user := userFromRequest(r)
 
 			for _, profile := range cache.GlobalCache.ClientSSLProfiles {
+				if !canReadPartition(user, profile.Partition) {
+					continue
+				}
 				if partition == "" || profile.Partition == partition {
 					filteredProfile, err := filterFields(*profile, fieldSelect)
 					if err != nil {
@@ -89,19 +94,30 @@ func (h ClientSSLListHandler) Handler() http.HandlerFunc {
 				return
 			}
 
+			profilePath, err := resolvePath(newProfile.Name, newProfile.Partition, newProfile.SubPath, configFromRequest(r).DefaultPartition)
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "%v", err)
+				return
+			}
+			newProfile.Partition, newProfile.SubPath, newProfile.Name = profilePath.partition, profilePath.subPath, profilePath.name
+
+			if !checkPartitionAccess(w, r, profilePath.partition) || !checkFolderExists(w, r, profilePath.folder()) {
+				return
+			}
+
 			version, ok := r.Context().Value(log.ContextMajorVersion).(int)
 			if !ok {
 				f5Error(w, r, http.StatusInternalServerError, "invalid version")
 				return
 			}
 
-			err = validateProfileConfig(newProfile, version)
+			err = validateProfileConfig(newProfile, version, configFromRequest(r).CheckCertExpiry)
 			if err != nil {
 				f5Error(w, r, http.StatusBadRequest, "%v", err.Error())
 				return
 			}
 
-			foundProfile := findProfile(newProfile.Partition, newProfile.Name)
+			foundProfile := findProfile(profilePath)
 			if foundProfile != nil {
 				f5Error(w, r, http.StatusBadRequest, "profile already exists")
 				return
@@ -113,7 +129,7 @@ func (h ClientSSLListHandler) Handler() http.HandlerFunc {
 
 			cache.GlobalCache.ClientSSLProfiles = append(cache.GlobalCache.ClientSSLProfiles, &newProfile)
 
-			respBytes, err := json.Marshal(newProfile)
+			respBytes, err := json.Marshal(newProfileResponse(newProfile))
 			if err != nil {
 				f5Error(w, r, http.StatusInternalServerError, "could not marshal response")
 				return
@@ -134,7 +150,7 @@ func (h ClientSSLListHandler) Handler() http.HandlerFunc {
 }
 
 func filterFields(profile models.ClientSSLProfile, selectField string) (map[string]any, error) {
-	bytes, err := json.Marshal(profile)
+	bytes, err := json.Marshal(newProfileResponse(profile))
 	if err != nil {
 		return nil, err
 	}
@@ -156,6 +172,22 @@ func filterFields(profile models.ClientSSLProfile, selectField string) (map[stri
 	return asMap, nil
 }
 
+// ClientSSLProfileResponse adds the full path of the profile to its fields
+type ClientSSLProfileResponse struct {
+	models.ClientSSLProfile
+	FullPath string `json:"fullPath"`
+}
+
+// newProfileResponse leaves the key passphrases out, they can be set but never read back
+func newProfileResponse(profile models.ClientSSLProfile) ClientSSLProfileResponse {
+	profile.Passphrase = ""
+	profile.CertKeyChain = slices.Clone(profile.CertKeyChain)
+	for i := range profile.CertKeyChain {
+		profile.CertKeyChain[i].Passphrase = ""
+	}
+	return ClientSSLProfileResponse{ClientSSLProfile: profile, FullPath: models.FullPath(profile.Partition, profile.SubPath, profile.Name)}
+}
+
 type ClientSSLListResponse struct {
 	// Items is a map because results can be filtered to omit fields
 	Items []map[string]any `json:"items"`
@@ -169,17 +201,21 @@ func (h ClientSSLHandler) Route() string {
 
 func (h ClientSSLHandler) Handler() http.HandlerFunc {
 	return authenticatedRequestMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		partition, profileName, err := parsePath(r.PathValue("profile"))
+		profilePath, err := parsePath(r.PathValue("profile"), configFromRequest(r).DefaultPartition)
 
 		if err != nil {
 			f5Error(w, r, http.StatusBadRequest, "%v", err)
 			return
 		}
 
-		foundProfile := findProfile(partition, profileName)
+		if !checkPartitionAccess(w, r, profilePath.partition) {
+			return
+		}
+
+		foundProfile := findProfile(profilePath)
 
 		if foundProfile == nil {
-			f5Error(w, r, http.StatusNotFound, "could not find profile %s for partition %s", profileName, partition)
+			f5Error(w, r, http.StatusNotFound, "could not find profile %s", profilePath.fullPath())
 			return
 		}
 
@@ -203,7 +239,7 @@ func (h ClientSSLHandler) Handler() http.HandlerFunc {
 			}
 
 			if v, found := asMap["selfLink"]; found && v == "" {
-				asMap["selfLink"] = filepath.Join("https://localhost/mgmt/tm/ltm/profile/client-ssl", fmt.Sprintf("~%s~%s?ver=%s", foundProfile.Partition, foundProfile.Name, version))
+				asMap["selfLink"] = fmt.Sprintf("https://localhost/mgmt/tm/ltm/profile/client-ssl/%s?ver=%s", profilePath.tildePath(), version)
 			}
 
 			respBytes, err := json.Marshal(asMap)
@@ -261,13 +297,19 @@ func (h ClientSSLHandler) Handler() http.HandlerFunc {
 				return
 			}
 
+			// Moving the profile would bypass the partition checks made on its path
+			if patchedProfile.Name != foundProfile.Name || patchedProfile.Partition != foundProfile.Partition || patchedProfile.SubPath != foundProfile.SubPath {
+				f5Error(w, r, http.StatusBadRequest, "client-ssl profile name, partition and subPath cannot be modified")
+				return
+			}
+
 			version, ok := r.Context().Value(log.ContextMajorVersion).(int)
 			if !ok {
 				f5Error(w, r, http.StatusInternalServerError, "invalid version")
 				return
 			}
 
-			err = validateProfileConfig(*patchedProfile, version)
+			err = validateProfileConfig(*patchedProfile, version, configFromRequest(r).CheckCertExpiry)
 			if err != nil {
 				f5Error(w, r, http.StatusBadRequest, "%v", err.Error())
 				return
@@ -276,7 +318,7 @@ func (h ClientSSLHandler) Handler() http.HandlerFunc {
 			var patchedProfiles []*models.ClientSSLProfile
 
 			for _, pf := range cache.GlobalCache.ClientSSLProfiles {
-				if pf.Name == patchedProfile.Name {
+				if pf == foundProfile {
 					patchedProfiles = append(patchedProfiles, patchedProfile)
 				} else {
 					patchedProfiles = append(patchedProfiles, pf)
@@ -285,7 +327,7 @@ func (h ClientSSLHandler) Handler() http.HandlerFunc {
 
 			cache.GlobalCache.ClientSSLProfiles = patchedProfiles
 
-			respBytes, err := json.Marshal(*patchedProfile)
+			respBytes, err := json.Marshal(newProfileResponse(*patchedProfile))
 			if err != nil {
 				f5Error(w, r, http.StatusInternalServerError, "could not marshal response")
 				return
@@ -304,53 +346,113 @@ func (h ClientSSLHandler) Handler() http.HandlerFunc {
 	})
 }
 
-func validateCert(profile models.ClientSSLProfile, version int) error {
-	certPath := profile.Cert
-	if certPath == "" {
-		for _, elem := range profile.CertKeyChain {
-			certPath = elem.Cert
-		}
+func validateCert(profile models.ClientSSLProfile, version int, checkExpiry bool) error {
+	var elements []models.ChainElement
+	if profile.Cert != "" {
+		elements = append(elements, models.ChainElement{Cert: profile.Cert, Key: profile.Key, Passphrase: profile.Passphrase})
 	}
+	elements = append(elements, profile.CertKeyChain...)
 
-	if certPath == "" {
+	if len(elements) == 0 {
 		return errors.New("no path defined")
 	}
 
-	certBytes, err := cache.GlobalCache.Fs.ReadFile(filepath.Join("/certs", certPath))
+	for _, elem := range elements {
+		err := validateCertKeyChain(elem, version, checkExpiry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateCertKeyChain(elem models.ChainElement, version int, checkExpiry bool) error {
+	certBytes, err := cache.GlobalCache.Fs.ReadFile(storePath("/certs", elem.Cert))
 	if err != nil {
 		return err
 	}
 
-	if version >= 17 {
-		return nil
+	cert, err := crypto.ParsePemCertificate(certBytes)
+	if err != nil {
+		return fmt.Errorf("unable to parse certificate %s", elem.Cert)
 	}
+
 	// Check ECDSA certs not supported
-	cert, err := crypto.ParsePemCertificate(certBytes)
+	if version < 17 && cert.PublicKeyAlgorithm != x509.RSA {
+		return errors.New("must have RSA certificate/key pair.")
+	}
+
+	if checkExpiry && time.Now().After(cert.NotAfter) {
+		return fmt.Errorf("certificate %s expired on %s", elem.Cert, cert.NotAfter.UTC().Format(time.RFC1123))
+	}
+
+	if elem.Key == "" {
+		return fmt.Errorf("certificate %s must have a key", elem.Cert)
+	}
+
+	keyBytes, err := readKey(elem.Key, elem.Passphrase)
 	if err != nil {
 		return err
 	}
 
-	if cert.PublicKeyAlgorithm != x509.RSA {
-		return errors.New("must have RSA certificate/key pair.")
+	key, err := crypto.ParsePemPrivateKey(keyBytes)
+	if err != nil {
+		return fmt.Errorf("unable to parse key %s", elem.Key)
+	}
+
+	if !crypto.KeyMatchesCertificate(cert, key) {
+		return fmt.Errorf("key values mismatch: key %s does not match certificate %s", elem.Key, elem.Cert)
+	}
+
+	if elem.Chain == "" {
+		return nil
+	}
+
+	chainBytes, err := cache.GlobalCache.Fs.ReadFile(storePath("/certs", elem.Chain))
+	if err != nil {
+		return fmt.Errorf("chain %s: %w", elem.Chain, err)
+	}
+
+	chain, err := crypto.ParsePemCertificates(chainBytes)
+	if err != nil {
+		return fmt.Errorf("unable to parse chain %s", elem.Chain)
+	}
+
+	if !crypto.IsIssuedBy(cert, chain) {
+		return fmt.Errorf("chain %s does not issue certificate %s", elem.Chain, elem.Cert)
 	}
 
 	return nil
 }
 
 func validateCipherConfig(profile models.ClientSSLProfile) error {
+	group, cipherString := profile.CipherGroup, profile.Ciphers
+	if group == "none" {
+		group = ""
+	}
+	if cipherString == "none" {
+		cipherString = ""
+	}
 
-	if profile.CipherGroup != "" && !slices.Contains(cache.GlobalCache.CipherGroups, profile.CipherGroup) {
+	if group != "" && cache.GlobalCache.FindCipherGroup(group) == nil {
 		return fmt.Errorf("CypherGroup: '%s' is not available", profile.CipherGroup)
 	}
 
-	if profile.CipherGroup != "" && profile.Ciphers != "" {
+	if cipherString != "" {
+		if err := ciphers.Validate(cipherString); err != nil {
+			return fmt.Errorf("Ciphers: %w", err)
+		}
+	}
+
+	if group != "" && cipherString != "" {
 		return fmt.Errorf("Profile %s/%s cannot contain both ciphers and a cipher-group.", profile.Partition, profile.Name)
 	}
 
 	return nil
 }
 
-func validateProfileConfig(profile models.ClientSSLProfile, version int) error {
+func validateProfileConfig(profile models.ClientSSLProfile, version int, checkExpiry bool) error {
 	err := f5Validator.Validate.Struct(profile)
 	if err != nil {
 		return errors.New("invalid request")
@@ -361,7 +463,7 @@ func validateProfileConfig(profile models.ClientSSLProfile, version int) error {
 		return err
 	}
 
-	err = validateCert(profile, version)
+	err = validateCert(profile, version, checkExpiry)
 	if err != nil {
 		return fmt.Errorf("invalid cert: %v", err)
 	}
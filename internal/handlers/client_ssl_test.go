@@ -3,24 +3,41 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/iilun/f5-mock/internal/crypto"
 	"github.com/iilun/f5-mock/internal/log"
 	"github.com/iilun/f5-mock/pkg/cache"
 	"github.com/iilun/f5-mock/pkg/models"
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
+func generateTestCertKey(t *testing.T, keyType string) ([]byte, []byte) {
+	key, err := crypto.GenerateKey(keyType, 2048, "")
+	require.NoError(t, err)
+
+	cert, err := crypto.CreateSelfSignedCertificate(key, crypto.Subject{CommonName: "test"}, crypto.SubjectAlternativeNames{}, time.Hour)
+	require.NoError(t, err)
+
+	return cert, key
+}
+
 func TestClientSSLHandler(t *testing.T) {
+	testCert, testKey := generateTestCertKey(t, crypto.KeyTypeRSA)
+	otherCert, otherKey := generateTestCertKey(t, crypto.KeyTypeRSA)
+	ecCert, ecKey := generateTestCertKey(t, crypto.KeyTypeEC)
+
 	tests := []struct {
 		name          string
 		method        string
 		path          string
 		profiles      []*models.ClientSSLProfile
-		cipherGroups  []string
+		cipherGroups  []*models.CipherGroup
 		existingFiles []string
+		files         map[string][]byte
 		body          any
 		headers       map[string]string
 		wantStatus    int
@@ -30,7 +47,7 @@ func TestClientSSLHandler(t *testing.T) {
 		{
 			name:       "invalid path param",
 			method:     http.MethodGet,
-			path:       "invalid-path",
+			path:       "~Common~~prof1",
 			profiles:   nil,
 			wantStatus: http.StatusBadRequest,
 			wantBody:   "invalid",
@@ -52,7 +69,38 @@ func TestClientSSLHandler(t *testing.T) {
 				{Name: "prof1", Partition: "Common", Cert: "c1.crt", Key: "k1.key"},
 			},
 			wantStatus: http.StatusOK,
-			wantBody:   `{"cert":"c1.crt","certKeyChain":null,"cipherGroup":"","ciphers":"none","defaultsFrom":"","key":"k1.key","kind":"tm:ltm:profile:client-ssl:client-sslstate","name":"prof1","partition":"Common","selfLink":"https:/localhost/mgmt/tm/ltm/profile/client-ssl/~Common~prof1?ver=17.0.0.0"}`,
+			wantBody:   `{"cert":"c1.crt","certKeyChain":null,"cipherGroup":"","ciphers":"none","defaultsFrom":"","fullPath":"/Common/prof1","key":"k1.key","kind":"tm:ltm:profile:client-ssl:client-sslstate","name":"prof1","partition":"Common","selfLink":"https://localhost/mgmt/tm/ltm/profile/client-ssl/~Common~prof1?ver=17.0.0.0"}`,
+		},
+		{
+			name:   "GET leaves passphrases out",
+			method: http.MethodGet,
+			path:   "~Common~prof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common", Cert: "c1.crt", Key: "k1.key", Passphrase: "secret", CertKeyChain: []models.ChainElement{{Name: "c1", Cert: "c1.crt", Key: "k1.key", Passphrase: "secret"}}},
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"cert":"c1.crt","certKeyChain":[{"cert":"c1.crt","key":"k1.key","name":"c1"}],"cipherGroup":"","ciphers":"none","defaultsFrom":"","fullPath":"/Common/prof1","key":"k1.key",`,
+		},
+		{
+			name:   "GET name without partition",
+			method: http.MethodGet,
+			path:   "prof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common", Cert: "c1.crt", Key: "k1.key"},
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `"fullPath":"/Common/prof1"`,
+		},
+		{
+			name:   "GET in a folder, URL encoded",
+			method: http.MethodGet,
+			path:   "%7ESample_02%7Eapp.app%7Eprof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Sample_02", Cert: "c1.crt", Key: "k1.key"},
+				{Name: "prof1", Partition: "Sample_02", SubPath: "app.app", Cert: "c2.crt", Key: "k2.key"},
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `"cert":"c2.crt","certKeyChain":null,"cipherGroup":"","ciphers":"none","defaultsFrom":"","fullPath":"/Sample_02/app.app/prof1","key":"k2.key","kind":"tm:ltm:profile:client-ssl:client-sslstate","name":"prof1","partition":"Sample_02","selfLink":"https://localhost/mgmt/tm/ltm/profile/client-ssl/~Sample_02~app.app~prof1?ver=17.0.0.0","subPath":"app.app"}`,
 		},
 		{
 			name:   "PATCH wrong content type",
@@ -88,48 +136,126 @@ func TestClientSSLHandler(t *testing.T) {
 			body:          map[string]string{"cert": "bad-cert", "key": "k1.key"},
 			wantStatus:    http.StatusBadRequest,
 			wantBody:      "invalid cert: file does not exist",
-			existingFiles: []string{"/keys/k1.key"},
+			existingFiles: []string{"/keys/Common/k1.key"},
 		},
 		{
 			name:   "PATCH change cipher group",
 			method: http.MethodPatch,
 			path:   "~Common~prof1",
 			profiles: []*models.ClientSSLProfile{
-				{Name: "prof1", Partition: "Common", Ciphers: "", CipherGroup: "a", Cert: "cert.pem"},
+				{Name: "prof1", Partition: "Common", Ciphers: "", CipherGroup: "a", Cert: "cert.pem", Key: "k1.key"},
 			},
 			headers:       map[string]string{"Content-Type": "application/json"},
 			body:          map[string]string{"cipherGroup": "new-cipher"},
 			wantStatus:    http.StatusOK,
 			wantBody:      "\"cipherGroup\":\"new-cipher\"",
-			cipherGroups:  []string{"new-cipher"},
-			existingFiles: []string{"/certs/cert.pem", "/keys/k1.key"},
+			cipherGroups:  []*models.CipherGroup{{Name: "new-cipher", Partition: "Common"}},
+			existingFiles: []string{"/certs/Common/cert.pem", "/keys/Common/k1.key"},
 		},
 		{
 			name:   "PATCH change ciphers",
 			method: http.MethodPatch,
 			path:   "~Common~prof1",
 			profiles: []*models.ClientSSLProfile{
-				{Name: "prof1", Partition: "Common", Ciphers: "", CipherGroup: "a", Cert: "cert.pem"},
+				{Name: "prof1", Partition: "Common", Ciphers: "", CipherGroup: "a", Cert: "cert.pem", Key: "k1.key"},
+			},
+			headers:       map[string]string{"Content-Type": "application/json"},
+			body:          map[string]any{"ciphers": "ECDHE+AESGCM:!SHA1", "cipherGroup": nil},
+			wantStatus:    http.StatusOK,
+			wantBody:      "\"ciphers\":\"ECDHE+AESGCM:!SHA1\"",
+			existingFiles: []string{"/certs/Common/cert.pem", "/keys/Common/k1.key"},
+		},
+		{
+			name:   "PATCH invalid ciphers",
+			method: http.MethodPatch,
+			path:   "~Common~prof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common", Cert: "cert.pem", Key: "k1.key"},
+			},
+			headers:       map[string]string{"Content-Type": "application/json"},
+			body:          map[string]any{"ciphers": "ECDHE:some-cipher"},
+			wantStatus:    http.StatusBadRequest,
+			wantBody:      "Ciphers: invalid cipher string 'ECDHE:some-cipher': unknown keyword 'some-cipher'",
+			existingFiles: []string{"/certs/Common/cert.pem", "/keys/Common/k1.key"},
+		},
+		{
+			name:   "PATCH partition",
+			method: http.MethodPatch,
+			path:   "~Common~prof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common", Cert: "cert.pem", Key: "k1.key"},
+			},
+			headers:       map[string]string{"Content-Type": "application/json"},
+			body:          map[string]string{"partition": "Prod"},
+			wantStatus:    http.StatusBadRequest,
+			wantBody:      "client-ssl profile name, partition and subPath cannot be modified",
+			existingFiles: []string{"/certs/Common/cert.pem", "/keys/Common/k1.key"},
+		},
+		{
+			name:   "PATCH missing partition",
+			method: http.MethodPatch,
+			path:   "~Common~prof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common", Cert: "cert.pem", Key: "k1.key"},
+			},
+			headers:       map[string]string{"Content-Type": "application/json"},
+			body:          map[string]string{"partition": "Nope"},
+			wantStatus:    http.StatusBadRequest,
+			wantBody:      "client-ssl profile name, partition and subPath cannot be modified",
+			existingFiles: []string{"/certs/Common/cert.pem", "/keys/Common/k1.key"},
+		},
+		{
+			name:   "PATCH name and subPath",
+			method: http.MethodPatch,
+			path:   "~Common~prof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common", Cert: "cert.pem", Key: "k1.key"},
+			},
+			headers:       map[string]string{"Content-Type": "application/json"},
+			body:          map[string]string{"name": "prof2", "subPath": "app.app"},
+			wantStatus:    http.StatusBadRequest,
+			wantBody:      "client-ssl profile name, partition and subPath cannot be modified",
+			existingFiles: []string{"/certs/Common/cert.pem", "/keys/Common/k1.key"},
+		},
+		{
+			name:   "PATCH same path",
+			method: http.MethodPatch,
+			path:   "~Common~prof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common", Cert: "cert.pem", Key: "k1.key"},
+			},
+			headers:       map[string]string{"Content-Type": "application/json"},
+			body:          map[string]string{"name": "prof1", "partition": "Common", "ciphers": "DEFAULT"},
+			wantStatus:    http.StatusOK,
+			wantBody:      `"ciphers":"DEFAULT"`,
+			existingFiles: []string{"/certs/Common/cert.pem", "/keys/Common/k1.key"},
+		},
+		{
+			name:   "PATCH built-in cipher group",
+			method: http.MethodPatch,
+			path:   "~Common~prof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common", Ciphers: "DEFAULT", Cert: "cert.pem", Key: "k1.key"},
 			},
 			headers:       map[string]string{"Content-Type": "application/json"},
-			body:          map[string]any{"ciphers": "some-cipher", "cipherGroup": nil},
+			body:          map[string]string{"cipherGroup": "/Common/f5-secure", "ciphers": "none"},
 			wantStatus:    http.StatusOK,
-			wantBody:      "\"ciphers\":\"some-cipher\"",
-			existingFiles: []string{"/certs/cert.pem", "/keys/k1.key"},
+			wantBody:      "\"cipherGroup\":\"/Common/f5-secure\"",
+			existingFiles: []string{"/certs/Common/cert.pem", "/keys/Common/k1.key"},
 		},
 		{
 			name:   "PATCH change ciphers and cipher group",
 			method: http.MethodPatch,
 			path:   "~Common~prof1",
 			profiles: []*models.ClientSSLProfile{
-				{Name: "prof1", Partition: "Common", Ciphers: "", CipherGroup: "a", Cert: "cert.pem"},
+				{Name: "prof1", Partition: "Common", Ciphers: "", CipherGroup: "a", Cert: "cert.pem", Key: "k1.key"},
 			},
 			headers:       map[string]string{"Content-Type": "application/json"},
-			body:          map[string]string{"cipherGroup": "new-cipher", "ciphers": "some-cipher"},
+			body:          map[string]string{"cipherGroup": "new-cipher", "ciphers": "DEFAULT"},
 			wantStatus:    http.StatusBadRequest,
 			wantBody:      "Profile Common/prof1 cannot contain both ciphers and a cipher-group.",
-			existingFiles: []string{"/certs/cert.pem", "/keys/k1.key"},
-			cipherGroups:  []string{"new-cipher"},
+			existingFiles: []string{"/certs/Common/cert.pem", "/keys/Common/k1.key"},
+			cipherGroups:  []*models.CipherGroup{{Name: "new-cipher", Partition: "Common"}},
 		},
 		{
 			name:   "PATCH success",
@@ -142,7 +268,94 @@ func TestClientSSLHandler(t *testing.T) {
 			body:          map[string]string{"cert": "c2.crt", "key": "k2.key"},
 			wantStatus:    http.StatusOK,
 			wantBody:      `"cert":"c2.crt"`,
-			existingFiles: []string{"/keys/k2.key", "/certs/c2.crt"},
+			existingFiles: []string{"/keys/Common/k2.key", "/certs/Common/c2.crt"},
+		},
+		{
+			name:   "PATCH missing key",
+			method: http.MethodPatch,
+			path:   "~Common~prof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common"},
+			},
+			headers:       map[string]string{"Content-Type": "application/json"},
+			body:          map[string]string{"cert": "c2.crt", "key": "missing.key"},
+			wantStatus:    http.StatusBadRequest,
+			wantBody:      "invalid cert: key missing.key not found",
+			existingFiles: []string{"/certs/Common/c2.crt"},
+		},
+		{
+			name:   "PATCH key does not match cert",
+			method: http.MethodPatch,
+			path:   "~Common~prof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common"},
+			},
+			headers:    map[string]string{"Content-Type": "application/json"},
+			body:       map[string]string{"cert": "c2.crt", "key": "other.key"},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid cert: key values mismatch: key other.key does not match certificate c2.crt",
+			files:      map[string][]byte{"/keys/Common/other.key": otherKey},
+		},
+		{
+			name:   "PATCH cert key chain entry does not match",
+			method: http.MethodPatch,
+			path:   "~Common~prof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common"},
+			},
+			headers: map[string]string{"Content-Type": "application/json"},
+			body: map[string]any{"certKeyChain": []map[string]string{
+				{"name": "first", "cert": "c2.crt", "key": "k2.key"},
+				{"name": "second", "cert": "other.crt", "key": "k2.key"},
+			}},
+			wantStatus:    http.StatusBadRequest,
+			wantBody:      "key values mismatch: key k2.key does not match certificate other.crt",
+			existingFiles: []string{"/keys/Common/k2.key", "/certs/Common/c2.crt"},
+			files:         map[string][]byte{"/certs/Common/other.crt": otherCert},
+		},
+		{
+			name:   "PATCH chain does not issue cert",
+			method: http.MethodPatch,
+			path:   "~Common~prof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common"},
+			},
+			headers: map[string]string{"Content-Type": "application/json"},
+			body: map[string]any{"certKeyChain": []map[string]string{
+				{"name": "first", "cert": "c2.crt", "key": "k2.key", "chain": "other.crt"},
+			}},
+			wantStatus:    http.StatusBadRequest,
+			wantBody:      "chain other.crt does not issue certificate c2.crt",
+			existingFiles: []string{"/keys/Common/k2.key", "/certs/Common/c2.crt"},
+			files:         map[string][]byte{"/certs/Common/other.crt": otherCert},
+		},
+		{
+			name:   "PATCH valid chain",
+			method: http.MethodPatch,
+			path:   "~Common~prof1",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common"},
+			},
+			headers: map[string]string{"Content-Type": "application/json"},
+			body: map[string]any{"certKeyChain": []map[string]string{
+				{"name": "first", "cert": "c2.crt", "key": "k2.key", "chain": "c2.crt"},
+			}},
+			wantStatus:    http.StatusOK,
+			wantBody:      `"chain":"c2.crt"`,
+			existingFiles: []string{"/keys/Common/k2.key", "/certs/Common/c2.crt"},
+		},
+		{
+			name:   "PATCH EC cert before v17",
+			method: http.MethodPatch,
+			path:   "~Common~prof1?ver=16.1.0",
+			profiles: []*models.ClientSSLProfile{
+				{Name: "prof1", Partition: "Common"},
+			},
+			headers:    map[string]string{"Content-Type": "application/json"},
+			body:       map[string]string{"cert": "ec.crt", "key": "ec.key"},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "must have RSA certificate/key pair.",
+			files:      map[string][]byte{"/certs/Common/ec.crt": ecCert, "/keys/Common/ec.key": ecKey},
 		},
 		{
 			name:   "invalid method",
@@ -165,7 +378,7 @@ func TestClientSSLHandler(t *testing.T) {
 		},
 	}
 
-	_ = os.Unsetenv("F5_LOGIN_PROVIDER")
+	cfg := testConfig()
 
 	_, _ = cache.New("")
 
@@ -178,10 +391,18 @@ func TestClientSSLHandler(t *testing.T) {
 			cache.GlobalCache.CipherGroups = tt.cipherGroups
 
 			for _, f := range tt.existingFiles {
-				_, _ = cache.GlobalCache.Fs.WriteFile(f, []byte("some content"))
+				if strings.HasPrefix(f, "/keys") {
+					_, _ = cache.GlobalCache.Fs.WriteFile(f, testKey)
+				} else {
+					_, _ = cache.GlobalCache.Fs.WriteFile(f, testCert)
+				}
 			}
 
-			h := F5HandlerWrapper{ClientSSLHandler{}, logger}
+			for f, content := range tt.files {
+				_, _ = cache.GlobalCache.Fs.WriteFile(f, content)
+			}
+
+			h := F5HandlerWrapper{ClientSSLHandler{}, logger, &cfg}
 
 			reqBody := &bytes.Buffer{}
 			switch v := tt.body.(type) {
@@ -194,10 +415,10 @@ func TestClientSSLHandler(t *testing.T) {
 			}
 
 			req := httptest.NewRequest(tt.method, "/clientssl/"+tt.path, reqBody)
-			req.SetPathValue("profile", tt.path)
+			req.SetPathValue("profile", strings.Split(tt.path, "?")[0])
 
 			if !tt.disableAuth {
-				req.SetBasicAuth(os.Getenv("F5_ADMIN_USERNAME"), os.Getenv("F5_ADMIN_PASSWORD"))
+				req.SetBasicAuth(cfg.Auth.AdminUsername, cfg.Auth.AdminPassword)
 			}
 
 			if tt.headers != nil {
@@ -214,6 +435,11 @@ func TestClientSSLHandler(t *testing.T) {
 			if tt.wantBody != "" {
 				require.Contains(t, rr.Body.String(), tt.wantBody)
 			}
+
+			// Refused patches leave the profiles as they were
+			if tt.method == http.MethodPatch && rr.Code != http.StatusOK {
+				require.Equal(t, tt.profiles, cache.GlobalCache.ClientSSLProfiles)
+			}
 		})
 	}
 }
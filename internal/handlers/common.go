@@ -2,53 +2,124 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/iilun/f5-mock/internal/config"
 	"github.com/iilun/f5-mock/internal/log"
 	"github.com/iilun/f5-mock/pkg/cache"
 	"github.com/iilun/f5-mock/pkg/models"
 
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 )
 
-func globalAuthCheck(r *http.Request) error {
-	externalAuthEnabled := os.Getenv("F5_LOGIN_PROVIDER") != ""
-	if externalAuthEnabled {
-		// Check the token
-		authToken := r.Header.Get("X-F5-Auth-Token")
-		if authToken == "" {
-			return fmt.Errorf("missing authentication")
-		}
-		_, err := cache.GlobalCache.AuthTokens.Get(authToken)
-		if err != nil {
-			// Token not found
-			return fmt.Errorf("invalid authentication")
-		}
-	} else {
-		// Check the authorization header
-		username, password, found := r.BasicAuth()
-		if !found {
-			return fmt.Errorf("missing authentication")
+func globalAuthCheck(r *http.Request) (*models.User, error) {
+	auth := configFromRequest(r).Auth
+
+	// Internal requests, like those of tmsh commands, are made as the user running them
+	if user, ok := r.Context().Value(userCtxKey{}).(*models.User); ok {
+		return user, nil
+	}
+
+	if user := clientCertificateUser(auth, r); user != nil {
+		return user, nil
+	}
+
+	authToken := r.Header.Get("X-F5-Auth-Token")
+
+	switch auth.EffectiveMode() {
+	case config.AuthModeBasic:
+		return checkBasicAuth(auth, r)
+	case config.AuthModeToken:
+		return checkTokenAuth(auth, authToken)
+	case config.AuthModeBoth:
+		// Like on a real device, a token is checked whenever it is sent, even with basic credentials
+		if authToken != "" {
+			return checkTokenAuth(auth, authToken)
 		}
-		err := checkAuth(username, password)
-		if err != nil {
-			return err
+		return checkBasicAuth(auth, r)
+	case config.AuthModeDisabled:
+		return findUser(auth, auth.AdminUsername), nil
+	default:
+		return nil, fmt.Errorf("invalid auth mode %s", auth.EffectiveMode())
+	}
+}
+
+// clientCertificateUser returns the user named after the common name of a verified client certificate
+func clientCertificateUser(auth config.Auth, r *http.Request) *models.User {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+
+	commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if commonName == "" {
+		return nil
+	}
+
+	user := findUser(auth, commonName)
+	if user == nil || isLocked(user) {
+		// Other credentials can still be used
+		return nil
+	}
+	return user
+}
+
+func checkTokenAuth(auth config.Auth, authToken string) (*models.User, error) {
+	if authToken == "" {
+		return nil, fmt.Errorf("missing authentication")
+	}
+	token, err := cache.GlobalCache.GetToken(authToken)
+	if err != nil {
+		if errors.Is(err, cache.ErrTokenExpired) {
+			return nil, fmt.Errorf("token expired")
 		}
+		// Token not found
+		return nil, fmt.Errorf("invalid authentication")
 	}
-	return nil
+	user := lookupUser(auth, token.AuthProviderName, token.UserName)
+	if user == nil {
+		// The user was deleted after the token was issued
+		return nil, fmt.Errorf("invalid authentication")
+	}
+	return user, nil
+}
+
+func checkBasicAuth(auth config.Auth, r *http.Request) (*models.User, error) {
+	username, password, found := r.BasicAuth()
+	if !found {
+		return nil, fmt.Errorf("missing authentication")
+	}
+	return checkAuth(auth, username, password)
 }
 
 func authenticatedRequestMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		err := globalAuthCheck(r)
+		user, err := globalAuthCheck(r)
+		if errors.Is(err, errPasswordExpired) && isOwnUserUpdate(r, user) {
+			err = nil
+		}
 		if err != nil {
 			f5Error(w, r, http.StatusUnauthorized, "%v", err)
 			return
 		}
 
+		if !isAuthorized(user, r) {
+			f5Error(w, r, http.StatusUnauthorized, "Authorization failed: user=%s resource=%s verb=%s", user.Name, r.URL.Path, r.Method)
+			return
+		}
+
+		next(w, withUser(r, user))
+	}
+}
+
+// lockStoreMiddleware serializes requests with reloads of the object store and configuration
+func lockStoreMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cache.GlobalCache.Lock()
+		defer cache.GlobalCache.Unlock()
+
 		next(w, r)
 	}
 }
@@ -58,11 +129,7 @@ func applyVersionMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		version := r.URL.Query().Get("ver")
 
 		if version == "" {
-			version = os.Getenv("F5_BASE_VERSION")
-		}
-
-		if version == "" {
-			version = "17.0.0.0"
+			version = configFromRequest(r).DefaultVersion
 		}
 
 		major, _, _ := strings.Cut(version, ".")
@@ -80,12 +147,37 @@ func applyVersionMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func findProfile(partition, name string) *models.ClientSSLProfile {
+func findProfile(profilePath objectPath) *models.ClientSSLProfile {
 	for i := range cache.GlobalCache.ClientSSLProfiles {
 		profile := cache.GlobalCache.ClientSSLProfiles[i]
-		if profile.Partition == partition && profile.Name == name {
+		if profile.Partition == profilePath.partition && profile.SubPath == profilePath.subPath && profile.Name == profilePath.name {
 			return profile
 		}
 	}
 	return nil
 }
+
+// envAdmin keeps the lockout state of the configured admin
+var envAdmin *models.User
+
+// findUser returns the user with the given name, the configured admin taking precedence
+func findUser(auth config.Auth, name string) *models.User {
+	if name == auth.AdminUsername {
+		password := auth.AdminPassword
+		if envAdmin == nil || envAdmin.Name != name || envAdmin.Password != password {
+			envAdmin = &models.User{
+				Name:            name,
+				Password:        password,
+				PartitionAccess: []models.PartitionAccess{{Name: models.AllPartitions, Role: models.RoleAdmin}},
+			}
+		}
+		return envAdmin
+	}
+
+	for _, user := range cache.GlobalCache.Users {
+		if user.Name == name {
+			return user
+		}
+	}
+	return nil
+}
@@ -1,25 +1,37 @@
 package handlers
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/iilun/f5-mock/internal/config"
+	"github.com/iilun/f5-mock/internal/crypto"
 	"github.com/iilun/f5-mock/internal/log"
 	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func TestAuthenticatedRequestMiddleware_ExternalAuth(t *testing.T) {
 	// Enable external auth
-	_ = os.Setenv("F5_LOGIN_PROVIDER", "tmos")
-	defer func() { _ = os.Unsetenv("F5_LOGIN_PROVIDER") }()
+	cfg := testConfig()
+	cfg.Auth.LoginProvider = "tmos"
 
 	baseCache, _ := cache.New("")
 	cache.GlobalCache = baseCache
 
-	_ = cache.GlobalCache.AuthTokens.Set("valid-token", nil)
+	validToken := cache.NewAuthToken("admin", "tmos", "")
+	validToken.Token = "valid-token"
+	_ = cache.GlobalCache.SetToken(validToken)
+
+	expiredToken := cache.NewAuthToken("admin", "tmos", "")
+	expiredToken.Token = "expired-token"
+	expiredToken.ExpirationMicros = time.Now().Add(-time.Second).UnixMicro()
+	_ = cache.GlobalCache.SetToken(expiredToken)
 
 	logger := log.New(true)
 	defer logger.Close()
@@ -32,6 +44,7 @@ func TestAuthenticatedRequestMiddleware_ExternalAuth(t *testing.T) {
 	}{
 		{"missing token", "", http.StatusUnauthorized, "missing authentication"},
 		{"invalid token", "bad-token", http.StatusUnauthorized, "invalid authentication"},
+		{"expired token", "expired-token", http.StatusUnauthorized, "token expired"},
 		{"valid token", "valid-token", http.StatusOK, "ok"},
 	}
 
@@ -43,9 +56,9 @@ func TestAuthenticatedRequestMiddleware_ExternalAuth(t *testing.T) {
 				req.Header.Set("X-F5-Auth-Token", tt.token)
 			}
 
-			middleware := authenticatedRequestMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			middleware := configMiddleware(&cfg, authenticatedRequestMiddleware(func(w http.ResponseWriter, r *http.Request) {
 				_, _ = w.Write([]byte("ok"))
-			})
+			}))
 
 			middleware(rr, req)
 
@@ -57,9 +70,8 @@ func TestAuthenticatedRequestMiddleware_ExternalAuth(t *testing.T) {
 
 func TestAuthenticatedRequestMiddleware_BasicAuth(t *testing.T) {
 	// Disable external auth
-	_ = os.Unsetenv("F5_LOGIN_PROVIDER")
-	_ = os.Setenv("F5_ADMIN_USERNAME", "admin")
-	_ = os.Setenv("F5_ADMIN_PASSWORD", "secret")
+	cfg := config.Default()
+	cfg.Auth.AdminPassword = "secret"
 
 	logger := log.New(true)
 	defer logger.Close()
@@ -84,9 +96,9 @@ func TestAuthenticatedRequestMiddleware_BasicAuth(t *testing.T) {
 				req.SetBasicAuth(tt.username, tt.password)
 			}
 
-			middleware := authenticatedRequestMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			middleware := configMiddleware(&cfg, authenticatedRequestMiddleware(func(w http.ResponseWriter, r *http.Request) {
 				_, _ = w.Write([]byte("ok"))
-			})
+			}))
 
 			middleware(rr, req)
 
@@ -94,6 +106,22 @@ func TestAuthenticatedRequestMiddleware_BasicAuth(t *testing.T) {
 			require.Contains(t, rr.Body.String(), tt.wantBody)
 		})
 	}
+
+	t.Run("admin without a password", func(t *testing.T) {
+		cfg := config.Default()
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(cfg.Auth.AdminUsername, "")
+
+		middleware := configMiddleware(&cfg, authenticatedRequestMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		}))
+		middleware(rr, req)
+
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+		require.Contains(t, rr.Body.String(), "bad authentication")
+	})
 }
 
 func TestApplyVersionMiddleware(t *testing.T) {
@@ -103,49 +131,49 @@ func TestApplyVersionMiddleware(t *testing.T) {
 	tests := []struct {
 		name            string
 		urlVersion      string
-		envVersion      string
+		defaultVersion  string
 		error           string
 		expectedVersion string
 	}{
 		{
-			name:            "invalid env version and no version in url",
+			name:            "invalid default version and no version in url",
 			urlVersion:      "",
-			envVersion:      "abc",
+			defaultVersion:  "abc",
 			error:           "{\"message\":\"invalid version\"}",
 			expectedVersion: "",
 		},
 		{
-			name:            "invalid env version and invalid version in url",
+			name:            "invalid default version and invalid version in url",
 			urlVersion:      "abc",
-			envVersion:      "abc",
+			defaultVersion:  "abc",
 			error:           "{\"message\":\"invalid version\"}",
 			expectedVersion: "",
 		},
 		{
-			name:            "invalid env version and valid version in url",
+			name:            "invalid default version and valid version in url",
 			urlVersion:      "16.2",
-			envVersion:      "abc",
+			defaultVersion:  "abc",
 			error:           "",
 			expectedVersion: "16",
 		},
 		{
-			name:            "valid env version and no version in url",
+			name:            "valid default version and no version in url",
 			urlVersion:      "",
-			envVersion:      "16.3",
+			defaultVersion:  "16.3",
 			error:           "",
 			expectedVersion: "16",
 		},
 		{
-			name:            "valid env version and valid version in url",
+			name:            "valid default version and valid version in url",
 			urlVersion:      "18.0.0.0",
-			envVersion:      "16.3",
+			defaultVersion:  "16.3",
 			error:           "",
 			expectedVersion: "18",
 		},
 		{
-			name:            "no env nor url version",
+			name:            "no url version",
 			urlVersion:      "",
-			envVersion:      "",
+			defaultVersion:  "",
 			error:           "",
 			expectedVersion: "17",
 		},
@@ -160,13 +188,12 @@ func TestApplyVersionMiddleware(t *testing.T) {
 			}
 			req := httptest.NewRequest(http.MethodGet, url, nil)
 
-			if tt.envVersion != "" {
-				_ = os.Setenv("F5_BASE_VERSION", tt.envVersion)
-			} else {
-				_ = os.Unsetenv("F5_BASE_VERSION")
+			cfg := testConfig()
+			if tt.defaultVersion != "" {
+				cfg.DefaultVersion = tt.defaultVersion
 			}
 
-			middleware := applyVersionMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			middleware := configMiddleware(&cfg, applyVersionMiddleware(func(w http.ResponseWriter, r *http.Request) {
 				version, ok := r.Context().Value(log.ContextMajorVersion).(int)
 				if !ok {
 					f5Error(w, r, http.StatusInternalServerError, "invalid version in context")
@@ -174,7 +201,7 @@ func TestApplyVersionMiddleware(t *testing.T) {
 				}
 
 				_, _ = w.Write([]byte(strconv.Itoa(version)))
-			})
+			}))
 
 			middleware(rr, req)
 
@@ -187,3 +214,125 @@ func TestApplyVersionMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthenticatedRequestMiddleware_AuthModes(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.AdminPassword = "secret"
+
+	_, _ = cache.New("")
+
+	validToken := cache.NewAuthToken("admin", "tmos", "")
+	validToken.Token = "mode-token"
+	_ = cache.GlobalCache.SetToken(validToken)
+
+	logger := log.New(true)
+	defer logger.Close()
+
+	tests := []struct {
+		name       string
+		mode       string
+		token      string
+		basic      bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"basic mode with basic", "basic", "", true, http.StatusOK, "ok"},
+		{"basic mode ignores tokens", "basic", "mode-token", false, http.StatusUnauthorized, "missing authentication"},
+		{"token mode with token", "token", "mode-token", false, http.StatusOK, "ok"},
+		{"token mode ignores basic", "token", "", true, http.StatusUnauthorized, "missing authentication"},
+		{"both mode with token", "both", "mode-token", false, http.StatusOK, "ok"},
+		{"both mode with basic", "both", "", true, http.StatusOK, "ok"},
+		{"both mode with both", "both", "mode-token", true, http.StatusOK, "ok"},
+		{"both mode checks sent tokens", "both", "bad-token", true, http.StatusUnauthorized, "invalid authentication"},
+		{"both mode without credentials", "both", "", false, http.StatusUnauthorized, "missing authentication"},
+		{"disabled mode", "disabled", "", false, http.StatusOK, "ok"},
+		{"invalid mode", "kerberos", "", true, http.StatusUnauthorized, "invalid auth mode kerberos"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Auth.Mode = tt.mode
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.token != "" {
+				req.Header.Set("X-F5-Auth-Token", tt.token)
+			}
+			if tt.basic {
+				req.SetBasicAuth("admin", "secret")
+			}
+
+			middleware := configMiddleware(&cfg, authenticatedRequestMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("ok"))
+			}))
+
+			middleware(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+			require.Contains(t, rr.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+func TestAuthenticatedRequestMiddleware_ClientCertificate(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.AdminPassword = "secret"
+
+	_, _ = cache.New("")
+	cache.GlobalCache.Users = []*models.User{
+		{Name: "certuser", Password: "unused", PartitionAccess: []models.PartitionAccess{{Name: models.AllPartitions, Role: models.RoleGuest}}},
+	}
+
+	newCertificate := func(commonName string) *x509.Certificate {
+		key, err := crypto.GenerateKey(crypto.KeyTypeEC, 0, "")
+		require.NoError(t, err)
+		certPEM, err := crypto.CreateSelfSignedCertificate(key, crypto.Subject{CommonName: commonName}, crypto.SubjectAlternativeNames{}, time.Hour)
+		require.NoError(t, err)
+		cert, err := crypto.ParsePemCertificate(certPEM)
+		require.NoError(t, err)
+		return cert
+	}
+
+	known := newCertificate("certuser")
+	unknown := newCertificate("stranger")
+
+	tests := []struct {
+		name       string
+		state      *tls.ConnectionState
+		basic      bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"verified certificate of a user", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{known}, VerifiedChains: [][]*x509.Certificate{{known}}}, false, http.StatusOK, "certuser"},
+		{"unverified certificate", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{known}}, false, http.StatusUnauthorized, "missing authentication"},
+		{"certificate of an unknown user", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{unknown}, VerifiedChains: [][]*x509.Certificate{{unknown}}}, false, http.StatusUnauthorized, "missing authentication"},
+		{"unknown user falls back to basic auth", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{unknown}, VerifiedChains: [][]*x509.Certificate{{unknown}}}, true, http.StatusOK, "admin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.TLS = tt.state
+			if tt.basic {
+				req.SetBasicAuth("admin", "secret")
+			}
+
+			middleware := configMiddleware(&cfg, authenticatedRequestMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(userFromRequest(r).Name))
+			}))
+
+			middleware(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+			require.Contains(t, rr.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+// testConfig returns the default configuration with the admin password tests log in with, which has no default
+func testConfig() config.Config {
+	cfg := config.Default()
+	cfg.Auth.AdminPassword = "password"
+	return cfg
+}
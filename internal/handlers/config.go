@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/iilun/f5-mock/internal/config"
+)
+
+type configCtxKey struct{}
+
+func configMiddleware(cfg *config.Config, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), configCtxKey{}, cfg)
+
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// configFromRequest returns the configuration injected by the handler wrapper, the defaults otherwise
+func configFromRequest(r *http.Request) *config.Config {
+	if cfg, ok := r.Context().Value(configCtxKey{}).(*config.Config); ok && cfg != nil {
+		return cfg
+	}
+	cfg := config.Default()
+	return &cfg
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+)
+
+// ConfigExportHandler renders the mock state as a bigip.conf, to be diffed against a golden file or
+// loaded onto a device. It is not part of the iControl REST API.
+type ConfigExportHandler struct{}
+
+func (h ConfigExportHandler) Route() string {
+	return "/mgmt/shared/mock/bigip.conf"
+}
+
+func (h ConfigExportHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				f5Error(w, r, http.StatusMethodNotAllowed, "only GET allowed")
+				return
+			}
+
+			// The export spans every partition, like tmsh save sys config
+			user := userFromRequest(r)
+			if !isAdmin(user) {
+				f5Error(w, r, http.StatusForbidden, "Access Denied: User (%s) may not export the configuration", user.Name)
+				return
+			}
+
+			version, _ := r.Context().Value(log.ContextVersion).(string)
+
+			var conf bytes.Buffer
+			err := cache.GlobalCache.ExportSCF(&conf, version)
+			if err != nil {
+				f5Error(w, r, http.StatusInternalServerError, "could not export configuration")
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/plain")
+			_, err = w.Write(conf.Bytes())
+			if err != nil {
+				f5Error(w, r, http.StatusInternalServerError, "could not write response")
+				return
+			}
+		})
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/iilun/f5-mock/pkg/f5Validator"
 	"io"
 	"net/http"
-	"path"
+	"time"
 )
 
 type CryptoCertHandler struct{}
@@ -40,43 +40,164 @@ func (h CryptoCertHandler) Handler() http.HandlerFunc {
 				return
 			}
 
-			if request.Command != "install" {
-				f5Error(w, r, http.StatusBadRequest, "unsupported command")
+			filePath := splitFilePath(request.Name)
+			if !checkPartitionAccess(w, r, filePath.partition) || !checkFolderExists(w, r, filePath.folder()) {
 				return
 			}
 
-			destPath := path.Join("/certs", request.Name)
-
-			if cache.GlobalCache.Fs.Exists(destPath) {
-				f5Error(w, r, http.StatusBadRequest, "dest path already exists")
-				return
+			switch request.Command {
+			case "install":
+				installCert(w, r, request)
+			case "create":
+				createCert(w, r, request)
+			default:
+				f5Error(w, r, http.StatusBadRequest, "unsupported command")
 			}
+		})
+}
 
-			// Get previous file
-			contents, err := cache.GlobalCache.Fs.ReadFile(request.FromLocalFile)
-			if err != nil {
-				f5Error(w, r, http.StatusBadRequest, "could not read local file")
-				return
-			}
+func installCert(w http.ResponseWriter, r *http.Request, request CryptoCommandRequest) {
+	destPath := storePath("/certs", request.Name)
 
-			// Check that content is a valid certificate
-			if !crypto.IsValidPemCertificate(contents) {
-				f5Error(w, r, http.StatusBadRequest, "invalid certificate file")
-				return
-			}
+	if cache.GlobalCache.Fs.Exists(destPath) {
+		f5Error(w, r, http.StatusBadRequest, "dest path already exists")
+		return
+	}
 
-			_, err = cache.GlobalCache.Fs.WriteFile(destPath, contents)
-			if err != nil {
-				f5Error(w, r, http.StatusInternalServerError, "could not write cert file")
-				return
-			}
-			return
-		})
+	// Get previous file
+	contents, ok := readUpload(w, r, request.FromLocalFile)
+	if !ok {
+		return
+	}
+
+	// Check that content is a valid certificate
+	if !crypto.IsValidPemCertificate(contents) {
+		f5Error(w, r, http.StatusBadRequest, "invalid certificate file")
+		return
+	}
+
+	_, err := cache.GlobalCache.Fs.WriteFile(destPath, contents)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not write cert file")
+		return
+	}
+}
+
+func createCert(w http.ResponseWriter, r *http.Request, request CryptoCommandRequest) {
+	destPath := storePath("/certs", request.Name)
+
+	if cache.GlobalCache.Fs.Exists(destPath) {
+		f5Error(w, r, http.StatusBadRequest, "dest path already exists")
+		return
+	}
+
+	if request.Key == "" {
+		f5Error(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	keyContents, err := readKey(request.Key, request.Passphrase)
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	sans, err := crypto.ParseSubjectAlternativeNames(request.SubjectAlternativeName)
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	lifetime := request.Lifetime
+	if lifetime == 0 {
+		lifetime = 365
+	}
+
+	contents, err := crypto.CreateSelfSignedCertificate(keyContents, request.subject(), sans, time.Duration(lifetime)*24*time.Hour)
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "could not create certificate: %v", err)
+		return
+	}
+
+	cert, err := crypto.ParsePemCertificate(contents)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not parse created certificate")
+		return
+	}
+
+	logger := loggerFromRequest(r)
+	logger.Debug("Writing self-signed certificate to %s", destPath)
+
+	_, err = cache.GlobalCache.Fs.WriteFile(destPath, contents)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not write cert file")
+		return
+	}
+
+	resp := CryptoCertResponse{
+		Kind:                   "tm:sys:crypto:cert:certstate",
+		Name:                   request.Name,
+		CommonName:             request.CommonName,
+		Subject:                cert.Subject.String(),
+		SubjectAlternativeName: request.SubjectAlternativeName,
+		Expiration:             cert.NotAfter.UTC().Format(time.RFC3339),
+		Key:                    request.Key,
+	}
+
+	respBytes, err := json.Marshal(resp)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not marshal response")
+		return
+	}
+
+	_, err = w.Write(respBytes)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not write response")
+		return
+	}
 }
 
 type CryptoCommandRequest struct {
 	Command       string `json:"command" validate:"required"`
 	Name          string `json:"name" validate:"required"`
-	FromLocalFile string `json:"from-local-file" validate:"required,existingfile"`
+	FromLocalFile string `json:"from-local-file" validate:"required_if=Command install,existingfile"`
 	SecurityType  string `json:"securityType"`
+	Passphrase    string `json:"passphrase"`
+
+	// Fields used by the create command
+	KeyType                string `json:"keyType"`
+	KeySize                int    `json:"keySize"`
+	CurveName              string `json:"curveName"`
+	Key                    string `json:"key"`
+	CommonName             string `json:"commonName"`
+	Country                string `json:"country"`
+	State                  string `json:"state"`
+	City                   string `json:"city"`
+	Organization           string `json:"organization"`
+	OU                     string `json:"ou"`
+	EmailAddress           string `json:"emailAddress"`
+	SubjectAlternativeName string `json:"subjectAlternativeName"`
+	Lifetime               int    `json:"lifetime"`
+}
+
+func (r CryptoCommandRequest) subject() crypto.Subject {
+	return crypto.Subject{
+		CommonName:         r.CommonName,
+		Country:            r.Country,
+		State:              r.State,
+		City:               r.City,
+		Organization:       r.Organization,
+		OrganizationalUnit: r.OU,
+		EmailAddress:       r.EmailAddress,
+	}
+}
+
+type CryptoCertResponse struct {
+	Kind                   string `json:"kind"`
+	Name                   string `json:"name"`
+	CommonName             string `json:"commonName"`
+	Subject                string `json:"subject"`
+	SubjectAlternativeName string `json:"subjectAlternativeName,omitempty"`
+	Expiration             string `json:"expiration"`
+	Key                    string `json:"key"`
 }
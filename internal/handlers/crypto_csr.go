@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/iilun/f5-mock/internal/crypto"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/f5Validator"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+)
+
+type CryptoCSRHandler struct{}
+
+func (h CryptoCSRHandler) Route() string {
+	return "/mgmt/tm/sys/crypto/csr"
+}
+
+func (h CryptoCSRHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				f5Error(w, r, http.StatusMethodNotAllowed, "only POST allowed")
+				return
+			}
+
+			// Read body
+			bytes, err := io.ReadAll(r.Body)
+			if err != nil {
+				f5Error(w, r, http.StatusInternalServerError, "could not read body")
+				return
+			}
+
+			var request CryptoCommandRequest
+			err = json.Unmarshal(bytes, &request)
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "invalid JSON body")
+				return
+			}
+
+			err = f5Validator.Validate.Struct(request)
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "invalid request")
+				return
+			}
+
+			filePath := splitFilePath(request.Name)
+			if !checkPartitionAccess(w, r, filePath.partition) || !checkFolderExists(w, r, filePath.folder()) {
+				return
+			}
+
+			if request.Command != "create" {
+				f5Error(w, r, http.StatusBadRequest, "unsupported command")
+				return
+			}
+
+			destPath := storePath("/csrs", request.Name)
+
+			if cache.GlobalCache.Fs.Exists(destPath) {
+				f5Error(w, r, http.StatusBadRequest, "dest path already exists")
+				return
+			}
+
+			if request.Key == "" {
+				f5Error(w, r, http.StatusBadRequest, "key is required")
+				return
+			}
+
+			keyContents, err := readKey(request.Key, request.Passphrase)
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "%v", err)
+				return
+			}
+
+			sans, err := crypto.ParseSubjectAlternativeNames(request.SubjectAlternativeName)
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "%v", err)
+				return
+			}
+
+			contents, err := crypto.CreateCertificateRequest(keyContents, request.subject(), sans)
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "could not create csr: %v", err)
+				return
+			}
+
+			logger := loggerFromRequest(r)
+			logger.Debug("Writing csr to %s", destPath)
+
+			_, err = cache.GlobalCache.Fs.WriteFile(destPath, contents)
+			if err != nil {
+				f5Error(w, r, http.StatusInternalServerError, "could not write csr file")
+				return
+			}
+
+			writeCSRResponse(w, r, request.Name, request.Key, contents)
+		})
+}
+
+type CryptoCSRItemHandler struct{}
+
+func (h CryptoCSRItemHandler) Route() string {
+	return "/mgmt/tm/sys/crypto/csr/{path}"
+}
+
+func (h CryptoCSRItemHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				f5Error(w, r, http.StatusMethodNotAllowed, "only GET allowed")
+				return
+			}
+
+			csrPath, err := parsePath(r.PathValue("path"), configFromRequest(r).DefaultPartition)
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "%v", err)
+				return
+			}
+
+			if !checkPartitionAccess(w, r, csrPath.partition) {
+				return
+			}
+
+			contents, err := cache.GlobalCache.Fs.ReadFile(path.Join("/csrs", csrPath.fullPath()))
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					f5Error(w, r, http.StatusNotFound, "%v", err)
+				} else {
+					f5Error(w, r, http.StatusBadRequest, "%v", err)
+				}
+				return
+			}
+
+			writeCSRResponse(w, r, csrPath.fullPath(), "", contents)
+		})
+}
+
+func writeCSRResponse(w http.ResponseWriter, r *http.Request, name, key string, contents []byte) {
+	csr, err := crypto.ParsePemCertificateRequest(contents)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not parse csr")
+		return
+	}
+
+	resp := CryptoCSRResponse{
+		Kind:       "tm:sys:crypto:csr:csrstate",
+		Name:       name,
+		CommonName: csr.Subject.CommonName,
+		Subject:    csr.Subject.String(),
+		Key:        key,
+		Csr:        string(contents),
+	}
+
+	respBytes, err := json.Marshal(resp)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not marshal response")
+		return
+	}
+
+	_, err = w.Write(respBytes)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not write response")
+		return
+	}
+}
+
+type CryptoCSRResponse struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	CommonName string `json:"commonName"`
+	Subject    string `json:"subject"`
+	Key        string `json:"key,omitempty"`
+	Csr        string `json:"csr"`
+}
@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/iilun/f5-mock/internal/crypto"
+	"github.com/iilun/f5-mock/internal/log"
 	"github.com/iilun/f5-mock/pkg/cache"
 	"github.com/iilun/f5-mock/pkg/f5Validator"
 	"io"
 	"net/http"
-	"path"
 )
 
 type CryptoKeyHandler struct{}
@@ -40,39 +42,204 @@ func (h CryptoKeyHandler) Handler() http.HandlerFunc {
 				return
 			}
 
-			if request.Command != "install" {
-				f5Error(w, r, http.StatusBadRequest, "unsupported command")
+			filePath := splitFilePath(request.Name)
+			if !checkPartitionAccess(w, r, filePath.partition) || !checkFolderExists(w, r, filePath.folder()) {
 				return
 			}
 
-			destPath := path.Join("/keys", request.Name)
-
-			if cache.GlobalCache.Fs.Exists(destPath) {
-				f5Error(w, r, http.StatusBadRequest, "dest path already exists")
-				return
+			switch request.Command {
+			case "install":
+				installKey(w, r, request)
+			case "create":
+				createKey(w, r, request)
+			default:
+				f5Error(w, r, http.StatusBadRequest, "unsupported command")
 			}
+		})
+}
 
-			// Get previous file
-			contents, err := cache.GlobalCache.Fs.ReadFile(request.FromLocalFile)
-			if err != nil {
-				f5Error(w, r, http.StatusBadRequest, "could not read local file")
-				return
-			}
+func installKey(w http.ResponseWriter, r *http.Request, request CryptoCommandRequest) {
+	destPath := storePath("/keys", request.Name)
 
-			// Check that content is a valid certificate
-			if !crypto.IsValidPem(contents) {
-				f5Error(w, r, http.StatusBadRequest, "invalid pem file")
-				return
-			}
+	if cache.GlobalCache.Fs.Exists(destPath) {
+		f5Error(w, r, http.StatusBadRequest, "dest path already exists")
+		return
+	}
 
-			logger := loggerFromRequest(r)
-			logger.Debug("Writing key to %s", destPath)
+	// Get previous file
+	contents, ok := readUpload(w, r, request.FromLocalFile)
+	if !ok {
+		return
+	}
 
-			_, err = cache.GlobalCache.Fs.WriteFile(destPath, contents)
-			if err != nil {
-				f5Error(w, r, http.StatusInternalServerError, "could not write key file")
-				return
-			}
-			return
-		})
+	// PKCS#12 bundles hold both the key and the certificate
+	if crypto.IsPKCS12(contents) {
+		installPKCS12(w, r, request, contents)
+		return
+	}
+
+	info, err := validateKey(r, contents, request)
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	contents, err = applySecurityType(contents, request)
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	logger := loggerFromRequest(r)
+	logger.Debug("Writing %s key of size %d to %s", info.Type, info.Size, destPath)
+
+	_, err = cache.GlobalCache.Fs.WriteFile(destPath, contents)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not write key file")
+		return
+	}
+
+	writeKeyResponse(w, r, request, info)
+}
+
+func createKey(w http.ResponseWriter, r *http.Request, request CryptoCommandRequest) {
+	destPath := storePath("/keys", request.Name)
+
+	if cache.GlobalCache.Fs.Exists(destPath) {
+		f5Error(w, r, http.StatusBadRequest, "dest path already exists")
+		return
+	}
+
+	contents, err := crypto.GenerateKey(request.KeyType, request.KeySize, request.CurveName)
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	info, err := validateKey(r, contents, request)
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	contents, err = applySecurityType(contents, request)
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	logger := loggerFromRequest(r)
+	logger.Debug("Writing generated key to %s", destPath)
+
+	_, err = cache.GlobalCache.Fs.WriteFile(destPath, contents)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not write key file")
+		return
+	}
+
+	writeKeyResponse(w, r, request, info)
+}
+
+// validateKey checks that contents is a private key usable on the requested version
+func validateKey(r *http.Request, contents []byte, request CryptoCommandRequest) (crypto.KeyInfo, error) {
+	version, ok := r.Context().Value(log.ContextMajorVersion).(int)
+	if !ok {
+		return crypto.KeyInfo{}, errors.New("invalid version")
+	}
+
+	decrypted, err := crypto.DecryptPemPrivateKey(contents, request.Passphrase)
+	if err != nil {
+		return crypto.KeyInfo{}, fmt.Errorf("invalid key %s: %w", request.Name, err)
+	}
+
+	key, err := crypto.ParsePemPrivateKey(decrypted)
+	if err != nil {
+		return crypto.KeyInfo{}, fmt.Errorf("invalid key %s: %w", request.Name, err)
+	}
+
+	info, err := crypto.GetKeyInfo(key)
+	if err != nil {
+		return crypto.KeyInfo{}, fmt.Errorf("invalid key %s: %w", request.Name, err)
+	}
+
+	if version < 17 && info.Type != crypto.KeyTypeRSA {
+		return crypto.KeyInfo{}, fmt.Errorf("invalid key %s: only RSA keys are supported before version 17", request.Name)
+	}
+
+	return info, nil
+}
+
+func writeKeyResponse(w http.ResponseWriter, r *http.Request, request CryptoCommandRequest, info crypto.KeyInfo) {
+	resp := CryptoKeyResponse{
+		Kind:         "tm:sys:crypto:key:keystate",
+		Name:         request.Name,
+		KeyType:      info.Type,
+		KeySize:      info.Size,
+		CurveName:    info.Curve,
+		SecurityType: request.SecurityType,
+	}
+
+	if resp.SecurityType == "" {
+		resp.SecurityType = "normal"
+	}
+
+	respBytes, err := json.Marshal(resp)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not marshal response")
+		return
+	}
+
+	_, err = w.Write(respBytes)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not write response")
+		return
+	}
+}
+
+// applySecurityType stores keys decrypted for the "normal" security type,
+// and passphrase protected for the "password" one
+func applySecurityType(contents []byte, request CryptoCommandRequest) ([]byte, error) {
+	decrypted, err := crypto.DecryptPemPrivateKey(contents, request.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt key %s: %w", request.Name, err)
+	}
+
+	switch request.SecurityType {
+	case "", "normal":
+		return decrypted, nil
+	case "password":
+		if request.Passphrase == "" {
+			return nil, errors.New("passphrase is required when securityType is password")
+		}
+		if crypto.IsEncryptedPemKey(contents) {
+			return contents, nil
+		}
+		return crypto.EncryptPemPrivateKey(decrypted, request.Passphrase)
+	default:
+		return nil, fmt.Errorf("invalid securityType %s", request.SecurityType)
+	}
+}
+
+// readKey reads an installed key, decrypting it when it is passphrase protected
+func readKey(name, passphrase string) ([]byte, error) {
+	contents, err := cache.GlobalCache.Fs.ReadFile(storePath("/keys", name))
+	if err != nil {
+		return nil, fmt.Errorf("key %s not found", name)
+	}
+
+	contents, err = crypto.DecryptPemPrivateKey(contents, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt key %s: %w", name, err)
+	}
+
+	return contents, nil
+}
+
+type CryptoKeyResponse struct {
+	Kind         string `json:"kind"`
+	Name         string `json:"name"`
+	KeyType      string `json:"keyType"`
+	KeySize      int    `json:"keySize,omitempty"`
+	CurveName    string `json:"curveName,omitempty"`
+	SecurityType string `json:"securityType"`
 }
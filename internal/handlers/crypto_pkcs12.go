@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/iilun/f5-mock/internal/crypto"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/f5Validator"
+	"io"
+	"net/http"
+)
+
+type CryptoPKCS12Handler struct{}
+
+func (h CryptoPKCS12Handler) Route() string {
+	return "/mgmt/tm/sys/crypto/pkcs12"
+}
+
+func (h CryptoPKCS12Handler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				f5Error(w, r, http.StatusMethodNotAllowed, "only POST allowed")
+				return
+			}
+
+			// Read body
+			bytes, err := io.ReadAll(r.Body)
+			if err != nil {
+				f5Error(w, r, http.StatusInternalServerError, "could not read body")
+				return
+			}
+
+			var request CryptoCommandRequest
+			err = json.Unmarshal(bytes, &request)
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "invalid JSON body")
+				return
+			}
+
+			err = f5Validator.Validate.Struct(request)
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "invalid request")
+				return
+			}
+
+			filePath := splitFilePath(request.Name)
+			if !checkPartitionAccess(w, r, filePath.partition) || !checkFolderExists(w, r, filePath.folder()) {
+				return
+			}
+
+			if request.Command != "install" {
+				f5Error(w, r, http.StatusBadRequest, "unsupported command")
+				return
+			}
+
+			contents, ok := readUpload(w, r, request.FromLocalFile)
+			if !ok {
+				return
+			}
+
+			installPKCS12(w, r, request, contents)
+		})
+}
+
+// installPKCS12 splits a PKCS#12 bundle into a key and a certificate sharing the request name
+func installPKCS12(w http.ResponseWriter, r *http.Request, request CryptoCommandRequest, contents []byte) {
+	keyPath := storePath("/keys", request.Name)
+	certPath := storePath("/certs", request.Name)
+
+	if cache.GlobalCache.Fs.Exists(keyPath) || cache.GlobalCache.Fs.Exists(certPath) {
+		f5Error(w, r, http.StatusBadRequest, "dest path already exists")
+		return
+	}
+
+	bundle, err := crypto.ParsePKCS12(contents, request.Passphrase)
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "could not read PKCS#12 file %s: %v", request.FromLocalFile, err)
+		return
+	}
+
+	info, err := validateKey(r, bundle.Key, CryptoCommandRequest{Name: request.Name})
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	key, err := applySecurityType(bundle.Key, request)
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	logger := loggerFromRequest(r)
+	logger.Debug("Writing PKCS#12 key to %s and certificate to %s", keyPath, certPath)
+
+	_, err = cache.GlobalCache.Fs.WriteFile(keyPath, key)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not write key file")
+		return
+	}
+
+	_, err = cache.GlobalCache.Fs.WriteFile(certPath, bundle.Certificates)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not write cert file")
+		return
+	}
+
+	writeKeyResponse(w, r, request, info)
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/f5Validator"
+	"github.com/iilun/f5-mock/pkg/models"
+)
+
+type FolderListHandler struct{}
+
+func (h FolderListHandler) Route() string {
+	return "/mgmt/tm/sys/folder"
+}
+
+func (h FolderListHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				// Partitions are the top-level folders
+				var folders []*models.Folder
+				for _, partition := range cache.GlobalCache.ListPartitions() {
+					folders = append(folders, &models.Folder{FullPath: "/" + partition.Name, Description: partition.Description})
+				}
+				folders = append(folders, cache.GlobalCache.Folders...)
+				slices.SortStableFunc(folders, func(a, b *models.Folder) int {
+					return strings.Compare(a.FullPath, b.FullPath)
+				})
+
+				user := userFromRequest(r)
+				items := []FolderResponse{}
+				for _, folder := range folders {
+					if canReadPartition(user, folderPartition(folder.FullPath)) {
+						items = append(items, newFolderResponse(r, folder))
+					}
+				}
+
+				writeJSON(w, r, FolderListResponse{
+					Kind:  "tm:sys:folder:foldercollectionstate",
+					Items: items,
+				})
+			case http.MethodPost:
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
+				}
+
+				var request FolderRequest
+				err = json.Unmarshal(bodyBytes, &request)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid post request")
+					return
+				}
+
+				folder := models.Folder{FullPath: request.fullPath(), Description: request.Description}
+				err = f5Validator.Validate.Struct(folder)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid folder: %v", err)
+					return
+				}
+
+				parent := path.Dir(folder.FullPath)
+				if parent == "/" {
+					f5Error(w, r, http.StatusBadRequest, "partitions are created through /mgmt/tm/auth/partition")
+					return
+				}
+
+				if !checkPartitionAccess(w, r, folderPartition(folder.FullPath)) || !checkFolderExists(w, r, parent) {
+					return
+				}
+
+				if cache.GlobalCache.FindFolder(folder.FullPath) != nil {
+					f5Error(w, r, http.StatusConflict, "The requested folder (%s) already exists.", folder.FullPath)
+					return
+				}
+
+				logger := loggerFromRequest(r)
+				logger.Debug("Added %s folder", folder.FullPath)
+
+				cache.GlobalCache.Folders = append(cache.GlobalCache.Folders, &folder)
+
+				writeJSON(w, r, newFolderResponse(r, &folder))
+			default:
+				f5Error(w, r, http.StatusMethodNotAllowed, "invalid method")
+			}
+		})
+}
+
+type FolderHandler struct{}
+
+func (h FolderHandler) Route() string {
+	return "/mgmt/tm/sys/folder/{path}"
+}
+
+func (h FolderHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			fullPath := path.Clean("/" + strings.ReplaceAll(r.PathValue("path"), "~", "/"))
+			if !cache.GlobalCache.FolderExists(fullPath) || !canReadPartition(userFromRequest(r), folderPartition(fullPath)) {
+				f5Error(w, r, http.StatusNotFound, "The requested folder (%s) was not found.", fullPath)
+				return
+			}
+
+			folder := cache.GlobalCache.FindFolder(fullPath)
+			if folder == nil {
+				// Partitions are read only through sys folder
+				if isWriteRequest(r) {
+					f5Error(w, r, http.StatusBadRequest, "partitions are managed through /mgmt/tm/auth/partition")
+					return
+				}
+				partition := cache.GlobalCache.FindPartition(folderPartition(fullPath))
+				folder = &models.Folder{FullPath: fullPath, Description: partition.Description}
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				writeJSON(w, r, newFolderResponse(r, folder))
+			case http.MethodPatch:
+				if !checkPartitionAccess(w, r, folderPartition(fullPath)) {
+					return
+				}
+
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
+				}
+
+				var request FolderRequest
+				err = json.Unmarshal(bodyBytes, &request)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid patch request")
+					return
+				}
+
+				if request.Name != "" && path.Base(request.Name) != path.Base(folder.FullPath) {
+					f5Error(w, r, http.StatusBadRequest, "folder name cannot be modified")
+					return
+				}
+
+				folder.Description = request.Description
+
+				writeJSON(w, r, newFolderResponse(r, folder))
+			case http.MethodDelete:
+				if !checkPartitionAccess(w, r, folderPartition(fullPath)) || !checkFolderEmpty(w, r, fullPath) {
+					return
+				}
+
+				cache.GlobalCache.Folders = slices.DeleteFunc(cache.GlobalCache.Folders, func(f *models.Folder) bool {
+					return f == folder
+				})
+
+				writeJSON(w, r, newFolderResponse(r, folder))
+			default:
+				f5Error(w, r, http.StatusMethodNotAllowed, "invalid method")
+			}
+		})
+}
+
+// folderPartition returns the partition of a folder full path
+func folderPartition(fullPath string) string {
+	partition, _, _ := strings.Cut(strings.TrimPrefix(fullPath, "/"), "/")
+	return partition
+}
+
+type FolderRequest struct {
+	Name        string `json:"name"`
+	Partition   string `json:"partition"`
+	SubPath     string `json:"subPath"`
+	Description string `json:"description"`
+}
+
+// fullPath returns the folder path, given as a full path name or as a name in a partition and sub path
+func (f FolderRequest) fullPath() string {
+	if f.Name == "" || strings.HasPrefix(f.Name, "/") {
+		return f.Name
+	}
+
+	partition := f.Partition
+	if partition == "" {
+		partition = cache.CommonPartition
+	}
+	return path.Join("/", partition, f.SubPath, f.Name)
+}
+
+func newFolderResponse(r *http.Request, folder *models.Folder) FolderResponse {
+	version, _ := r.Context().Value(log.ContextVersion).(string)
+
+	resp := FolderResponse{
+		Kind:        "tm:sys:folder:folderstate",
+		Name:        path.Base(folder.FullPath),
+		FullPath:    folder.FullPath,
+		Description: folder.Description,
+		SelfLink:    fmt.Sprintf("https://localhost/mgmt/tm/sys/folder/%s?ver=%s", strings.ReplaceAll(folder.FullPath, "/", "~"), version),
+	}
+
+	// Partitions live in the root folder, other folders in a partition and an optional sub path
+	if path.Dir(folder.FullPath) == "/" {
+		resp.SubPath = "/"
+	} else {
+		resp.Partition = folderPartition(folder.FullPath)
+		resp.SubPath = strings.TrimPrefix(path.Dir(folder.FullPath), "/"+resp.Partition)
+		resp.SubPath = strings.TrimPrefix(resp.SubPath, "/")
+	}
+
+	return resp
+}
+
+type FolderResponse struct {
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	Partition   string `json:"partition,omitempty"`
+	SubPath     string `json:"subPath,omitempty"`
+	FullPath    string `json:"fullPath"`
+	Description string `json:"description,omitempty"`
+	SelfLink    string `json:"selfLink"`
+}
+
+type FolderListResponse struct {
+	Kind  string           `json:"kind"`
+	Items []FolderResponse `json:"items"`
+}
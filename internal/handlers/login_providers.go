@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/iilun/f5-mock/internal/config"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
+)
+
+const (
+	// loginProviderTMOS authenticates against the system authentication source, then local accounts
+	loginProviderTMOS = "tmos"
+	// loginProviderLocal only authenticates local accounts
+	loginProviderLocal = "local"
+)
+
+var errUnknownLoginProvider = errors.New("unknown login provider")
+
+func findLoginProvider(name string) *models.LoginProvider {
+	for _, provider := range cache.GlobalCache.LoginProviders {
+		if provider.Name == name {
+			return provider
+		}
+	}
+	return nil
+}
+
+// loginProviderChain returns the providers to check in order for a loginProvider name,
+// a nil provider standing for the local user store
+func loginProviderChain(auth config.Auth, name string) ([]*models.LoginProvider, error) {
+	if name == loginProviderLocal {
+		return []*models.LoginProvider{nil}, nil
+	}
+
+	if provider := findLoginProvider(name); provider != nil {
+		return []*models.LoginProvider{provider}, nil
+	}
+
+	systemProvider := auth.LoginProvider
+	if name != loginProviderTMOS && name != systemProvider {
+		return nil, errUnknownLoginProvider
+	}
+
+	if provider := findLoginProvider(systemProvider); provider != nil {
+		return []*models.LoginProvider{provider, nil}, nil
+	}
+	return []*models.LoginProvider{nil}, nil
+}
+
+// authenticate checks credentials against a login provider and returns the matching user
+func authenticate(auth config.Auth, providerName, username, password string) (*models.User, error) {
+	chain, err := loginProviderChain(auth, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, provider := range chain {
+		var user *models.User
+		if provider == nil {
+			user, err = checkAuth(auth, username, password)
+		} else {
+			user, err = checkRemoteAuth(provider, username, password)
+		}
+
+		if errors.Is(err, errUnknownUsername) {
+			continue
+		}
+		return user, err
+	}
+
+	return nil, errUnknownUsername
+}
+
+// lookupUser returns the user a token was issued to, nil if it does not exist anymore or is locked
+func lookupUser(auth config.Auth, providerName, username string) *models.User {
+	chain, err := loginProviderChain(auth, providerName)
+	if err != nil {
+		return nil
+	}
+
+	for _, provider := range chain {
+		if provider == nil {
+			user := findUser(auth, username)
+			if user == nil {
+				continue
+			}
+			if isLocked(user) {
+				return nil
+			}
+			return user
+		}
+
+		remote := findRemoteUser(provider, username)
+		if remote == nil {
+			continue
+		}
+		if remote.Locked {
+			return nil
+		}
+		user, err := mapRemoteUser(provider, remote)
+		if err != nil {
+			return nil
+		}
+		return user
+	}
+
+	return nil
+}
+
+func findRemoteUser(provider *models.LoginProvider, username string) *models.RemoteUser {
+	for _, user := range provider.Users {
+		if user.Name == username {
+			return user
+		}
+	}
+	return nil
+}
+
+func checkRemoteAuth(provider *models.LoginProvider, username, password string) (*models.User, error) {
+	remote := findRemoteUser(provider, username)
+	if remote == nil {
+		return nil, errUnknownUsername
+	}
+
+	if remote.Locked {
+		return nil, fmt.Errorf("account %s is locked", username)
+	}
+
+	if remote.Password == "" || password != remote.Password {
+		return nil, errBadAuthentication
+	}
+
+	return mapRemoteUser(provider, remote)
+}
+
+// mapRemoteUser grants roles to a remote user from its groups, the first mapping of a partition winning
+func mapRemoteUser(provider *models.LoginProvider, remote *models.RemoteUser) (*models.User, error) {
+	user := &models.User{Name: remote.Name}
+
+	for _, mapping := range provider.RoleMapping {
+		if !slices.Contains(remote.Groups, mapping.Group) {
+			continue
+		}
+
+		partition := mapping.Partition
+		if partition == "" {
+			partition = models.AllPartitions
+		}
+
+		mapped := slices.ContainsFunc(user.PartitionAccess, func(access models.PartitionAccess) bool {
+			return access.Name == partition
+		})
+		if !mapped {
+			user.PartitionAccess = append(user.PartitionAccess, models.PartitionAccess{Name: partition, Role: mapping.Role})
+		}
+	}
+
+	if len(user.PartitionAccess) == 0 && provider.DefaultRole != "" {
+		partition := provider.DefaultPartition
+		if partition == "" {
+			partition = models.AllPartitions
+		}
+		user.PartitionAccess = append(user.PartitionAccess, models.PartitionAccess{Name: partition, Role: provider.DefaultRole})
+	}
+
+	if len(user.PartitionAccess) == 0 {
+		return nil, fmt.Errorf("no role mapped for user %s", remote.Name)
+	}
+
+	// Partitions may have been deleted since the provider was seeded
+	if err := accessPartitionsError(user.PartitionAccess); err != nil {
+		return nil, fmt.Errorf("invalid role mapped for user %s: %w", remote.Name, err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/f5Validator"
+	"github.com/iilun/f5-mock/pkg/models"
+)
+
+type PartitionListHandler struct{}
+
+func (h PartitionListHandler) Route() string {
+	return "/mgmt/tm/auth/partition"
+}
+
+func (h PartitionListHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				user := userFromRequest(r)
+				items := []PartitionResponse{}
+				for _, partition := range cache.GlobalCache.ListPartitions() {
+					if canReadPartition(user, partition.Name) {
+						items = append(items, newPartitionResponse(r, partition))
+					}
+				}
+
+				writeJSON(w, r, PartitionListResponse{
+					Kind:  "tm:auth:partition:partitioncollectionstate",
+					Items: items,
+				})
+			case http.MethodPost:
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
+				}
+
+				var partition models.Partition
+				err = json.Unmarshal(bodyBytes, &partition)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid post request")
+					return
+				}
+
+				err = f5Validator.Validate.Struct(partition)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid partition: %v", err)
+					return
+				}
+
+				if cache.GlobalCache.FindPartition(partition.Name) != nil {
+					f5Error(w, r, http.StatusConflict, "The requested partition (%s) already exists.", partition.Name)
+					return
+				}
+
+				logger := loggerFromRequest(r)
+				logger.Debug("Added %s partition", partition.Name)
+
+				cache.GlobalCache.Partitions = append(cache.GlobalCache.Partitions, &partition)
+
+				writeJSON(w, r, newPartitionResponse(r, &partition))
+			default:
+				f5Error(w, r, http.StatusMethodNotAllowed, "invalid method")
+			}
+		})
+}
+
+type PartitionHandler struct{}
+
+func (h PartitionHandler) Route() string {
+	return "/mgmt/tm/auth/partition/{name}"
+}
+
+func (h PartitionHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			name := strings.Trim(strings.ReplaceAll(r.PathValue("name"), "~", "/"), "/")
+			partition := cache.GlobalCache.FindPartition(name)
+			if partition == nil || !canReadPartition(userFromRequest(r), name) {
+				f5Error(w, r, http.StatusNotFound, "The requested partition (%s) was not found.", name)
+				return
+			}
+
+			if isWriteRequest(r) && name == cache.CommonPartition {
+				f5Error(w, r, http.StatusBadRequest, "The %s partition cannot be modified.", cache.CommonPartition)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				writeJSON(w, r, newPartitionResponse(r, partition))
+			case http.MethodPatch:
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
+				}
+
+				// Apply the patch on a copy so that invalid values leave the partition untouched
+				updated := *partition
+				err = json.Unmarshal(bodyBytes, &updated)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid patch request")
+					return
+				}
+
+				if updated.Name != partition.Name {
+					f5Error(w, r, http.StatusBadRequest, "partition name cannot be modified")
+					return
+				}
+
+				err = f5Validator.Validate.Struct(updated)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid partition: %v", err)
+					return
+				}
+
+				*partition = updated
+
+				writeJSON(w, r, newPartitionResponse(r, partition))
+			case http.MethodDelete:
+				if !checkFolderEmpty(w, r, "/"+name) {
+					return
+				}
+
+				for _, user := range cache.GlobalCache.Users {
+					if slices.ContainsFunc(user.PartitionAccess, func(access models.PartitionAccess) bool { return access.Name == name }) {
+						f5Error(w, r, http.StatusBadRequest, "The requested partition (%s) cannot be deleted, user %s has access to it.", name, user.Name)
+						return
+					}
+				}
+
+				cache.GlobalCache.Partitions = slices.DeleteFunc(cache.GlobalCache.Partitions, func(p *models.Partition) bool {
+					return p == partition
+				})
+
+				writeJSON(w, r, newPartitionResponse(r, partition))
+			default:
+				f5Error(w, r, http.StatusMethodNotAllowed, "invalid method")
+			}
+		})
+}
+
+// checkFolderExists writes a 400 error and returns false if the partition or folder, like Sample_02 or
+// Sample_02/app.app, does not exist
+func checkFolderExists(w http.ResponseWriter, r *http.Request, folder string) bool {
+	fullPath := path.Clean("/" + folder)
+	if cache.GlobalCache.FolderExists(fullPath) {
+		return true
+	}
+	f5Error(w, r, http.StatusBadRequest, "The requested folder (%s) was not found.", fullPath)
+	return false
+}
+
+// checkFolderEmpty writes a 400 error and returns false if a partition or folder still holds objects
+func checkFolderEmpty(w http.ResponseWriter, r *http.Request, fullPath string) bool {
+	contents := cache.GlobalCache.FolderContents(fullPath)
+	if len(contents) == 0 {
+		return true
+	}
+	f5Error(w, r, http.StatusBadRequest, "The requested folder (%s) cannot be deleted because it is not empty: %s", fullPath, strings.Join(contents, ", "))
+	return false
+}
+
+func newPartitionResponse(r *http.Request, partition *models.Partition) PartitionResponse {
+	version, _ := r.Context().Value(log.ContextVersion).(string)
+
+	return PartitionResponse{
+		Kind:               "tm:auth:partition:partitionstate",
+		Name:               partition.Name,
+		FullPath:           partition.Name,
+		DefaultRouteDomain: partition.DefaultRouteDomain,
+		Description:        partition.Description,
+		SelfLink:           fmt.Sprintf("https://localhost/mgmt/tm/auth/partition/%s?ver=%s", partition.Name, version),
+	}
+}
+
+type PartitionResponse struct {
+	Kind               string `json:"kind"`
+	Name               string `json:"name"`
+	FullPath           string `json:"fullPath"`
+	DefaultRouteDomain int    `json:"defaultRouteDomain"`
+	Description        string `json:"description,omitempty"`
+	SelfLink           string `json:"selfLink"`
+}
+
+type PartitionListResponse struct {
+	Kind  string              `json:"kind"`
+	Items []PartitionResponse `json:"items"`
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionsAndFolders(t *testing.T) {
+	cfg := testConfig()
+
+	_, _ = cache.New("")
+
+	logger := log.New(true)
+	defer logger.Close()
+
+	cache.GlobalCache.Partitions = nil
+	cache.GlobalCache.Folders = nil
+	// An admin of another partition, as seed files can declare, who can read Prod
+	cache.GlobalCache.Users = []*models.User{
+		{Name: "devadmin", Password: "devpass", PartitionAccess: []models.PartitionAccess{{Name: "Dev", Role: models.RoleAdmin}, {Name: "Prod", Role: models.RoleOperator}}},
+	}
+	devAdmin := [2]string{"devadmin", "devpass"}
+
+	tests := []struct {
+		name        string
+		handler     F5Handler
+		method      string
+		pathValue   string
+		credentials [2]string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:       "Common is built in",
+			handler:    PartitionListHandler{},
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantBody:   `"items":[{"kind":"tm:auth:partition:partitionstate","name":"Common","fullPath":"Common","defaultRouteDomain":0`,
+		},
+		{
+			name:       "create partition",
+			handler:    PartitionListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"Prod","description":"Production","defaultRouteDomain":2}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"name":"Prod","fullPath":"Prod","defaultRouteDomain":2,"description":"Production"`,
+		},
+		{
+			name:       "duplicate partition",
+			handler:    PartitionListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"Prod"}`,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "invalid partition name",
+			handler:    PartitionListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"Prod/sub"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "update partition",
+			handler:    PartitionHandler{},
+			method:     http.MethodPatch,
+			pathValue:  "Prod",
+			body:       `{"description":"Production apps"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"defaultRouteDomain":2,"description":"Production apps"`,
+		},
+		{
+			name:       "Common cannot be deleted",
+			handler:    PartitionHandler{},
+			method:     http.MethodDelete,
+			pathValue:  "Common",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "create folder",
+			handler:    FolderListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"app.app","partition":"Prod"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"name":"app.app","partition":"Prod","fullPath":"/Prod/app.app"`,
+		},
+		{
+			name:       "create nested folder by full path",
+			handler:    FolderListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"/Prod/app.app/sub","description":"nested"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"name":"sub","partition":"Prod","subPath":"app.app","fullPath":"/Prod/app.app/sub"`,
+		},
+		{
+			name:       "folder in a missing partition",
+			handler:    FolderListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"app.app","partition":"Missing"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "The requested folder (/Missing) was not found.",
+		},
+		{
+			name:       "partitions are not created as folders",
+			handler:    FolderListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"/Other"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "list folders",
+			handler:    FolderListHandler{},
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantBody:   `"fullPath":"/Prod"`,
+		},
+		{
+			name:       "get folder",
+			handler:    FolderHandler{},
+			method:     http.MethodGet,
+			pathValue:  "~Prod~app.app~sub",
+			wantStatus: http.StatusOK,
+			wantBody:   `"description":"nested","selfLink":"https://localhost/mgmt/tm/sys/folder/~Prod~app.app~sub?ver=17.0.0.0"`,
+		},
+		{
+			name:       "profile in a missing partition",
+			handler:    ClientSSLListHandler{},
+			method:     http.MethodPost,
+			body:       `{"name":"web","partition":"Missing"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "The requested folder (/Missing) was not found.",
+		},
+		{
+			name:       "key in a missing folder",
+			handler:    CryptoKeyHandler{},
+			method:     http.MethodPost,
+			body:       `{"command":"create","name":"/Prod/missing.app/web.key"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "The requested folder (/Prod/missing.app) was not found.",
+		},
+		{
+			name:       "key in a folder",
+			handler:    CryptoKeyHandler{},
+			method:     http.MethodPost,
+			body:       `{"command":"create","name":"/Prod/app.app/web.key"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "folder in another partition",
+			handler:     FolderListHandler{},
+			method:      http.MethodPost,
+			credentials: devAdmin,
+			body:        `{"name":"other.app","partition":"Prod"}`,
+			wantStatus:  http.StatusForbidden,
+			wantBody:    "Access Denied: User (devadmin) may not modify objects in partition (Prod)",
+		},
+		{
+			name:        "update a folder of another partition",
+			handler:     FolderHandler{},
+			method:      http.MethodPatch,
+			pathValue:   "~Prod~app.app",
+			credentials: devAdmin,
+			body:        `{"description":"changed"}`,
+			wantStatus:  http.StatusForbidden,
+			wantBody:    "Access Denied: User (devadmin) may not modify objects in partition (Prod)",
+		},
+		{
+			name:        "delete a folder of another partition",
+			handler:     FolderHandler{},
+			method:      http.MethodDelete,
+			pathValue:   "~Prod~app.app~sub",
+			credentials: devAdmin,
+			wantStatus:  http.StatusForbidden,
+			wantBody:    "Access Denied: User (devadmin) may not modify objects in partition (Prod)",
+		},
+		{
+			name:       "non empty folder",
+			handler:    FolderHandler{},
+			method:     http.MethodDelete,
+			pathValue:  "~Prod~app.app",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "not empty: sys folder /Prod/app.app/sub, sys file ssl-key /Prod/app.app/web.key",
+		},
+		{
+			name:       "non empty partition",
+			handler:    PartitionHandler{},
+			method:     http.MethodDelete,
+			pathValue:  "Prod",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "sys folder /Prod/app.app",
+		},
+		{
+			name:       "delete empty folder",
+			handler:    FolderHandler{},
+			method:     http.MethodDelete,
+			pathValue:  "~Prod~app.app~sub",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "deleted folder",
+			handler:    FolderHandler{},
+			method:     http.MethodGet,
+			pathValue:  "~Prod~app.app~sub",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := F5HandlerWrapper{tt.handler, logger, &cfg}
+
+			req := httptest.NewRequest(tt.method, h.Route(), bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetPathValue("name", tt.pathValue)
+			req.SetPathValue("path", tt.pathValue)
+			if tt.credentials[0] != "" {
+				req.SetBasicAuth(tt.credentials[0], tt.credentials[1])
+			} else {
+				req.SetBasicAuth(cfg.Auth.AdminUsername, cfg.Auth.AdminPassword)
+			}
+
+			rr := httptest.NewRecorder()
+			h.Handler()(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			require.Contains(t, rr.Body.String(), tt.wantBody)
+		})
+	}
+}
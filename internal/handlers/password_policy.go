@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"unicode"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/f5Validator"
+	"github.com/iilun/f5-mock/pkg/models"
+)
+
+var errPasswordExpired = errors.New("password expired, a password change is required")
+
+type PasswordPolicyHandler struct{}
+
+func (h PasswordPolicyHandler) Route() string {
+	return "/mgmt/tm/auth/password-policy"
+}
+
+func (h PasswordPolicyHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				writeJSON(w, r, newPasswordPolicyResponse(r))
+			case http.MethodPatch:
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
+				}
+
+				policy := cache.GlobalCache.PasswordPolicy
+				err = json.Unmarshal(bodyBytes, &policy)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid patch request")
+					return
+				}
+
+				err = f5Validator.Validate.Struct(policy)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid password policy: %v", err)
+					return
+				}
+
+				cache.GlobalCache.PasswordPolicy = policy
+
+				writeJSON(w, r, newPasswordPolicyResponse(r))
+			default:
+				f5Error(w, r, http.StatusMethodNotAllowed, "invalid method")
+			}
+		})
+}
+
+func newPasswordPolicyResponse(r *http.Request) PasswordPolicyResponse {
+	version, _ := r.Context().Value(log.ContextVersion).(string)
+
+	policy := cache.GlobalCache.PasswordPolicy
+	if policy.PolicyEnforcement == "" {
+		policy.PolicyEnforcement = "disabled"
+	}
+
+	return PasswordPolicyResponse{
+		Kind:           "tm:auth:password-policy:password-policystate",
+		SelfLink:       "https://localhost/mgmt/tm/auth/password-policy?ver=" + version,
+		PasswordPolicy: policy,
+	}
+}
+
+// validatePassword checks a new password against the password policy, when it is enforced
+func validatePassword(password string) error {
+	policy := cache.GlobalCache.PasswordPolicy
+	if policy.PolicyEnforcement != "enabled" {
+		return nil
+	}
+
+	if len(password) < policy.MinimumLength {
+		return fmt.Errorf("password must be at least %d characters long", policy.MinimumLength)
+	}
+
+	var lower, upper, numeric, special int
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			lower++
+		case unicode.IsUpper(c):
+			upper++
+		case unicode.IsDigit(c):
+			numeric++
+		default:
+			special++
+		}
+	}
+
+	if lower < policy.RequiredLowercase {
+		return fmt.Errorf("password must contain at least %d lowercase characters", policy.RequiredLowercase)
+	}
+	if upper < policy.RequiredUppercase {
+		return fmt.Errorf("password must contain at least %d uppercase characters", policy.RequiredUppercase)
+	}
+	if numeric < policy.RequiredNumeric {
+		return fmt.Errorf("password must contain at least %d numeric characters", policy.RequiredNumeric)
+	}
+	if special < policy.RequiredSpecial {
+		return fmt.Errorf("password must contain at least %d special characters", policy.RequiredSpecial)
+	}
+
+	return nil
+}
+
+// isLocked reports whether the account is locked, unlocking it once the lockout duration is over
+func isLocked(user *models.User) bool {
+	if user.Locked && !user.LockedUntil.IsZero() && time.Now().After(user.LockedUntil) {
+		unlockUser(user)
+	}
+	return user.Locked
+}
+
+func unlockUser(user *models.User) {
+	user.Locked = false
+	user.LockedUntil = time.Time{}
+	user.FailedLogins = 0
+}
+
+// recordLoginFailure counts a failed login, locking the account past the policy threshold
+func recordLoginFailure(user *models.User) {
+	policy := cache.GlobalCache.PasswordPolicy
+
+	user.FailedLogins++
+	if policy.MaxLoginFailures == 0 || user.FailedLogins < policy.MaxLoginFailures {
+		return
+	}
+
+	user.Locked = true
+	if policy.LockoutDuration > 0 {
+		user.LockedUntil = time.Now().Add(time.Duration(policy.LockoutDuration) * time.Second)
+	}
+}
+
+func isPasswordExpired(user *models.User) bool {
+	if user.PasswordExpired {
+		return true
+	}
+
+	policy := cache.GlobalCache.PasswordPolicy
+	if policy.PolicyEnforcement != "enabled" || policy.MaxDuration == 0 || user.PasswordChanged.IsZero() {
+		return false
+	}
+
+	return time.Now().After(user.PasswordChanged.AddDate(0, 0, policy.MaxDuration))
+}
+
+// setPassword changes a user password after checking it against the password policy
+func setPassword(user *models.User, password string) error {
+	err := validatePassword(password)
+	if err != nil {
+		return err
+	}
+
+	user.Password = password
+	user.PasswordChanged = time.Now()
+	user.PasswordExpired = false
+	return nil
+}
+
+type PasswordPolicyResponse struct {
+	Kind     string `json:"kind"`
+	SelfLink string `json:"selfLink"`
+	models.PasswordPolicy
+}
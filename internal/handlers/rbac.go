@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/iilun/f5-mock/pkg/models"
+)
+
+type resourceKind int
+
+const (
+	resourceSystem resourceKind = iota
+	resourceLTM
+	resourceCrypto
+	resourceAuth
+	resourceToken
+)
+
+// resourceKinds maps API prefixes to the kind of objects they manage, first match wins
+var resourceKinds = []struct {
+	prefix string
+	kind   resourceKind
+}{
+	{"/mgmt/tm/sys/crypto/", resourceCrypto},
+	{"/mgmt/tm/sys/file/", resourceCrypto},
+	{"/mgmt/shared/file-transfer/ucs-", resourceSystem},
+	{"/mgmt/shared/file-transfer/", resourceCrypto},
+	{"/mgmt/tm/ltm/", resourceLTM},
+	{"/mgmt/shared/appsvcs/", resourceLTM},
+	{"/mgmt/tm/auth/", resourceAuth},
+	{"/mgmt/shared/authz/tokens", resourceToken},
+}
+
+// writeRoles lists the roles allowed to modify each kind of resource. Every role can read.
+var writeRoles = map[resourceKind][]string{
+	resourceSystem: {models.RoleAdmin},
+	resourceLTM:    {models.RoleAdmin, models.RoleManager},
+	resourceCrypto: {models.RoleAdmin, models.RoleManager, models.RoleCertificateManager},
+	resourceAuth:   {models.RoleAdmin},
+	// Token ownership is checked by the token handlers
+	resourceToken: {models.RoleAdmin, models.RoleManager, models.RoleCertificateManager, models.RoleOperator, models.RoleGuest},
+}
+
+type userCtxKey struct{}
+
+func resourceKindForPath(urlPath string) resourceKind {
+	for _, r := range resourceKinds {
+		if strings.HasPrefix(urlPath, r.prefix) {
+			return r.kind
+		}
+	}
+	return resourceSystem
+}
+
+func isWriteRequest(r *http.Request) bool {
+	return r.Method != http.MethodGet && r.Method != http.MethodHead
+}
+
+func canWrite(role string, kind resourceKind) bool {
+	return slices.Contains(writeRoles[kind], role)
+}
+
+// userRole returns the role the user has on a partition, or an empty string without access
+func userRole(user *models.User, partition string) string {
+	role := ""
+	for _, access := range user.PartitionAccess {
+		if access.Name == partition {
+			return access.Role
+		}
+		if access.Name == models.AllPartitions {
+			role = access.Role
+		}
+	}
+	return role
+}
+
+// isAuthorized checks the request verb against every role of the user,
+// partition restrictions are checked by the handlers once the partition is known
+func isAuthorized(user *models.User, r *http.Request) bool {
+	if !isWriteRequest(r) || isOwnUserUpdate(r, user) {
+		return true
+	}
+
+	kind := resourceKindForPath(r.URL.Path)
+	// Users and partitions are shared by every partition
+	if kind == resourceAuth {
+		return isAdmin(user)
+	}
+	for _, access := range user.PartitionAccess {
+		if canWrite(access.Role, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// isOwnUserUpdate reports whether the request updates the authenticated user, e.g. to change its password
+func isOwnUserUpdate(r *http.Request, user *models.User) bool {
+	return r.Method == http.MethodPatch && r.URL.Path == "/mgmt/tm/auth/user/"+user.Name
+}
+
+func withUser(r *http.Request, user *models.User) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userCtxKey{}, user))
+}
+
+func userFromRequest(r *http.Request) *models.User {
+	if user, ok := r.Context().Value(userCtxKey{}).(*models.User); ok {
+		return user
+	}
+	return &models.User{}
+}
+
+func isAdmin(user *models.User) bool {
+	return userRole(user, models.AllPartitions) == models.RoleAdmin
+}
+
+// checkAdmin writes a 403 error and returns false if the user is not an administrator of all partitions
+func checkAdmin(w http.ResponseWriter, r *http.Request, action string) bool {
+	user := userFromRequest(r)
+	if isAdmin(user) {
+		return true
+	}
+	f5Error(w, r, http.StatusForbidden, "Access Denied: User (%s) may not %s", user.Name, action)
+	return false
+}
+
+// canReadPartition reports whether objects of the partition are visible to the user.
+// Like on a real BIG-IP, everyone can read the Common partition.
+func canReadPartition(user *models.User, partition string) bool {
+	return partition == "Common" || userRole(user, partition) != ""
+}
+
+// checkPartitionAccess writes a 403 error and returns false if the request is not allowed on the partition
+func checkPartitionAccess(w http.ResponseWriter, r *http.Request, partition string) bool {
+	user := userFromRequest(r)
+
+	if !isWriteRequest(r) {
+		if canReadPartition(user, partition) {
+			return true
+		}
+		f5Error(w, r, http.StatusForbidden, "Access Denied: User (%s) may not access objects in partition (%s)", user.Name, partition)
+		return false
+	}
+
+	if canWrite(userRole(user, partition), resourceKindForPath(r.URL.Path)) {
+		return true
+	}
+	f5Error(w, r, http.StatusForbidden, "Access Denied: User (%s) may not modify objects in partition (%s)", user.Name, partition)
+	return false
+}
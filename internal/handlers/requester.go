@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/iilun/f5-mock/internal/config"
+	"github.com/iilun/f5-mock/internal/shell"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
+)
+
+// NewRequester returns a shell requester dispatching requests to the registered handlers as user, without going
+// through the listeners. Callers hold the store lock.
+func NewRequester(user *models.User) shell.Requester {
+	return func(method, uri string, body []byte) (int, []byte) {
+		req, err := http.NewRequest(method, uri, bytes.NewReader(body))
+		if err != nil {
+			return http.StatusBadRequest, nil
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+		internalMux.ServeHTTP(rec, withUser(req, user))
+		return rec.status, rec.body.Bytes()
+	}
+}
+
+// AdminUser returns the administrator configured from the environment, which local tools act as
+func AdminUser(auth config.Auth) *models.User {
+	return findUser(auth, auth.AdminUsername)
+}
+
+// LoginShell checks credentials like basic auth and returns the shell of the user. Only administrators have bash
+// and file transfers, others being restricted to tmsh. Commands hold the store lock while they run.
+func LoginShell(cfg *config.Config, username, password string) (*shell.Shell, error) {
+	cache.GlobalCache.Lock()
+	user, err := checkAuth(cfg.Auth, username, password)
+	cache.GlobalCache.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	sh := shell.New(cache.GlobalCache, cfg.DefaultVersion)
+	sh.Requester = NewRequester(user)
+	sh.Locker = cache.GlobalCache
+	sh.Restricted = !isAdmin(user)
+	return sh, nil
+}
+
+// responseRecorder keeps the response of an internal request
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(b)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/internal/shell"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTmshCommands(t *testing.T) {
+	cfg := testConfig()
+
+	_, _ = cache.New("")
+	cache.GlobalCache.Partitions = []*models.Partition{{Name: "Prod"}}
+	cache.GlobalCache.CipherRules = nil
+	cache.GlobalCache.CipherGroups = nil
+
+	logger := log.New(true)
+	defer logger.Close()
+
+	internalMux = http.NewServeMux()
+	for _, h := range []F5Handler{CipherRuleListHandler{}, CipherRuleHandler{}, CipherGroupListHandler{}, CipherGroupHandler{}, UserListHandler{}, UserHandler{}, SysConfigHandler{}, UCSListHandler{}} {
+		internalMux.HandleFunc(h.Route(), F5HandlerWrapper{h, logger, &cfg}.internalHandler())
+	}
+
+	admin := AdminUser(cfg.Auth)
+	manager := &models.User{Name: "manager", PartitionAccess: []models.PartitionAccess{{Name: "Prod", Role: models.RoleManager}}}
+
+	tests := []struct {
+		name       string
+		user       *models.User
+		command    string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:    "create rule",
+			user:    admin,
+			command: "create ltm cipher rule /Prod/gcm cipher ECDHE+AESGCM",
+		},
+		{
+			name:       "handler validation",
+			user:       admin,
+			command:    "create ltm cipher rule /Prod/bad cipher NOPE",
+			wantStatus: 1,
+			want:       "Cipher rule /Prod/bad: invalid cipher string 'NOPE': unknown keyword 'NOPE'\n",
+		},
+		{
+			name:    "create group as a manager",
+			user:    manager,
+			command: "create ltm cipher group /Prod/gcm allow { /Prod/gcm }",
+		},
+		{
+			name:       "partition access",
+			user:       manager,
+			command:    "create ltm cipher rule gcm cipher DEFAULT",
+			wantStatus: 1,
+			want:       "Access Denied: User (manager) may not modify objects in partition (Common)\n",
+		},
+		{
+			name:       "role",
+			user:       manager,
+			command:    "create auth user bob password Secret123! partition-access add { Prod { role guest } }",
+			wantStatus: 1,
+			want:       "Authorization failed: user=manager resource=/mgmt/tm/auth/user verb=POST\n",
+		},
+		{
+			name:    "modify group",
+			user:    admin,
+			command: "modify ltm cipher group /Prod/gcm allow add { f5-secure } ordering strength",
+		},
+		{
+			name:    "list",
+			user:    manager,
+			command: "list ltm cipher group /Prod/gcm",
+			want:    "ltm cipher group /Prod/gcm {\n    allow {\n        /Prod/gcm { }\n        /Common/f5-secure { }\n    }\n    ordering strength\n}\n\n",
+		},
+		{
+			name:       "delete used rule",
+			user:       admin,
+			command:    "delete ltm cipher rule /Prod/gcm",
+			wantStatus: 1,
+			want:       "The cipher rule (/Prod/gcm) cannot be deleted, it is used by cipher group /Prod/gcm.\n",
+		},
+		{
+			name:    "delete",
+			user:    admin,
+			command: "delete ltm cipher group /Prod/gcm",
+		},
+		{
+			name:    "save config",
+			user:    admin,
+			command: "save sys config",
+			want:    "Saving running configuration...\n  /config/bigip.conf\n  /config/bigip_base.conf\n  /config/bigip_user.conf\n",
+		},
+		{
+			name:    "load config",
+			user:    admin,
+			command: "load sys config",
+			want:    "Loading configuration...\n  /config/bigip.conf\n  /config/bigip_base.conf\n  /config/bigip_user.conf\n",
+		},
+		{
+			name:       "save ucs as a manager",
+			user:       manager,
+			command:    "save sys ucs backup",
+			wantStatus: 1,
+			want:       "Authorization failed: user=manager resource=/mgmt/tm/sys/ucs verb=POST\n",
+		},
+		{
+			name:    "save ucs",
+			user:    admin,
+			command: "save sys ucs tmsh",
+			want:    "Saving active configuration...\n/var/local/ucs/tmsh.ucs is saved.\n",
+		},
+		{
+			name:    "load ucs",
+			user:    admin,
+			command: "load sys ucs tmsh.ucs",
+			want:    "/var/local/ucs/tmsh.ucs is loaded.\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh := shell.New(cache.GlobalCache, cfg.DefaultVersion)
+			sh.Requester = NewRequester(tt.user)
+
+			args, err := shell.SplitWords(tt.command)
+			require.NoError(t, err)
+
+			output, status := sh.RunTmsh(args)
+
+			require.Equal(t, tt.wantStatus, status, output)
+			require.Equal(t, tt.want, output)
+		})
+	}
+
+	require.Empty(t, cache.GlobalCache.CipherGroups)
+	require.Len(t, cache.GlobalCache.CipherRules, 1)
+
+	require.NoError(t, cache.GlobalCache.Fs.Remove(cache.UCSDir+"/tmsh.ucs"))
+	cache.GlobalCache.Partitions = nil
+	cache.GlobalCache.CipherRules = nil
+}
@@ -1,14 +1,72 @@
 package handlers
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/iilun/f5-mock/internal/crypto"
+	"github.com/iilun/f5-mock/internal/log"
 	"github.com/iilun/f5-mock/pkg/cache"
 	"io/fs"
 	"net/http"
 	"path"
+	"strings"
 )
 
+type SSLCertListHandler struct{}
+
+func (h SSLCertListHandler) Route() string {
+	return "/mgmt/tm/sys/file/ssl-cert"
+}
+
+func (h SSLCertListHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				f5Error(w, r, http.StatusMethodNotAllowed, "only GET allowed")
+				return
+			}
+
+			items := []SSLCertResponse{}
+
+			for _, certPath := range cache.GlobalCache.Fs.List("/certs") {
+				contents, err := cache.GlobalCache.Fs.ReadFile(certPath)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "%v", err)
+					return
+				}
+
+				filePath := splitFilePath(strings.TrimPrefix(certPath, "/certs"))
+				if !canReadPartition(userFromRequest(r), filePath.partition) {
+					continue
+				}
+
+				resp, err := newSSLCertResponse(r, filePath, contents)
+				if err != nil {
+					// Not a certificate, this is not listed
+					continue
+				}
+				items = append(items, resp)
+			}
+
+			respBytes, err := json.Marshal(SSLCertListResponse{
+				Kind:  "tm:sys:file:ssl-cert:ssl-certcollectionstate",
+				Items: items,
+			})
+			if err != nil {
+				f5Error(w, r, http.StatusInternalServerError, "%v", err)
+				return
+			}
+
+			_, err = w.Write(respBytes)
+			if err != nil {
+				f5Error(w, r, http.StatusInternalServerError, "%v", err)
+				return
+			}
+		})
+}
+
 type SSLCertHandler struct{}
 
 func (h SSLCertHandler) Route() string {
@@ -20,28 +78,17 @@ func (h SSLCertHandler) Handler() http.HandlerFunc {
 		func(w http.ResponseWriter, r *http.Request) {
 			switch r.Method {
 			case http.MethodGet:
-				partition, certFile, err := parsePath(r.PathValue("path"))
-
-				if err != nil {
-					f5Error(w, r, http.StatusBadRequest, "%v", err)
+				certPath, contents, ok := readSSLCert(w, r)
+				if !ok {
 					return
 				}
 
-				destPath := path.Join("/certs", partition, certFile)
-
-				contents, err := cache.GlobalCache.Fs.ReadFile(destPath)
-
+				resp, err := newSSLCertResponse(r, certPath, contents)
 				if err != nil {
-					if errors.Is(err, fs.ErrNotExist) {
-						f5Error(w, r, http.StatusNotFound, "%v", err)
-					} else {
-						f5Error(w, r, http.StatusBadRequest, "%v", err)
-					}
+					f5Error(w, r, http.StatusBadRequest, "%v", err)
 					return
 				}
 
-				resp := SSLCertResponse{Cert: string(contents)}
-
 				respBytes, err := json.Marshal(resp)
 				if err != nil {
 					f5Error(w, r, http.StatusInternalServerError, "%v", err)
@@ -58,6 +105,195 @@ func (h SSLCertHandler) Handler() http.HandlerFunc {
 		})
 }
 
+type SSLCertBundleHandler struct{}
+
+func (h SSLCertBundleHandler) Route() string {
+	return "/mgmt/tm/sys/file/ssl-cert/{path}/bundle-certificates"
+}
+
+func (h SSLCertBundleHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				f5Error(w, r, http.StatusMethodNotAllowed, "only GET allowed")
+				return
+			}
+
+			_, contents, ok := readSSLCert(w, r)
+			if !ok {
+				return
+			}
+
+			certs, err := crypto.ParsePemCertificates(contents)
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "%v", err)
+				return
+			}
+
+			items := []SSLCertBundleCertificate{}
+			for _, cert := range certs {
+				items = append(items, SSLCertBundleCertificate{
+					Kind:             "tm:sys:file:ssl-cert:bundle-certificates:bundle-certificatesstate",
+					Name:             crypto.Fingerprint(cert),
+					CommonName:       cert.Subject.CommonName,
+					Subject:          cert.Subject.String(),
+					Issuer:           cert.Issuer.String(),
+					SerialNumber:     cert.SerialNumber.String(),
+					ExpirationDate:   cert.NotAfter.Unix(),
+					ExpirationString: crypto.ExpirationString(cert),
+				})
+			}
+
+			respBytes, err := json.Marshal(SSLCertBundleResponse{
+				Kind:  "tm:sys:file:ssl-cert:bundle-certificates:bundle-certificatescollectionstate",
+				Items: items,
+			})
+			if err != nil {
+				f5Error(w, r, http.StatusInternalServerError, "%v", err)
+				return
+			}
+
+			_, err = w.Write(respBytes)
+			if err != nil {
+				f5Error(w, r, http.StatusInternalServerError, "%v", err)
+				return
+			}
+		})
+}
+
+// readSSLCert reads the certificate file designated by the path parameter, writing the error if any
+func readSSLCert(w http.ResponseWriter, r *http.Request) (objectPath, []byte, bool) {
+	certPath, err := parsePath(r.PathValue("path"), configFromRequest(r).DefaultPartition)
+
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "%v", err)
+		return objectPath{}, nil, false
+	}
+
+	if !checkPartitionAccess(w, r, certPath.partition) {
+		return objectPath{}, nil, false
+	}
+
+	destPath := path.Join("/certs", certPath.fullPath())
+
+	contents, err := cache.GlobalCache.Fs.ReadFile(destPath)
+
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			f5Error(w, r, http.StatusNotFound, "%v", err)
+		} else {
+			f5Error(w, r, http.StatusBadRequest, "%v", err)
+		}
+		return objectPath{}, nil, false
+	}
+
+	return certPath, contents, true
+}
+
+func newSSLCertResponse(r *http.Request, certPath objectPath, contents []byte) (SSLCertResponse, error) {
+	certs, err := crypto.ParsePemCertificates(contents)
+	if err != nil {
+		return SSLCertResponse{}, err
+	}
+
+	// Bundles are described by their first certificate
+	cert := certs[0]
+
+	keyInfo, err := crypto.GetCertificateKeyInfo(cert)
+	if err != nil {
+		return SSLCertResponse{}, err
+	}
+
+	version, _ := r.Context().Value(log.ContextVersion).(string)
+	selfLink := fmt.Sprintf("https://localhost/mgmt/tm/sys/file/ssl-cert/%s", certPath.tildePath())
+
+	resp := SSLCertResponse{
+		Kind:             "tm:sys:file:ssl-cert:ssl-certstate",
+		Name:             certPath.name,
+		Partition:        certPath.partition,
+		SubPath:          certPath.subPath,
+		FullPath:         certPath.fullPath(),
+		SelfLink:         fmt.Sprintf("%s?ver=%s", selfLink, version),
+		Cert:             string(contents),
+		CommonName:       cert.Subject.CommonName,
+		Subject:          cert.Subject.String(),
+		Issuer:           cert.Issuer.String(),
+		SerialNumber:     cert.SerialNumber.String(),
+		Fingerprint:      crypto.Fingerprint(cert),
+		KeyType:          keyInfo.Type,
+		KeySize:          keyInfo.Size,
+		CurveName:        keyInfo.Curve,
+		ExpirationDate:   earliestExpiration(certs).NotAfter.Unix(),
+		ExpirationString: crypto.ExpirationString(earliestExpiration(certs)),
+		IsBundle:         "false",
+	}
+
+	if len(certs) > 1 {
+		resp.IsBundle = "true"
+		resp.BundleCertificatesReference = &Reference{
+			Link:            fmt.Sprintf("%s/bundle-certificates?ver=%s", selfLink, version),
+			IsSubcollection: true,
+		}
+	}
+
+	return resp, nil
+}
+
+// earliestExpiration returns the certificate expiring first, which is what matters for a bundle
+func earliestExpiration(certs []*x509.Certificate) *x509.Certificate {
+	earliest := certs[0]
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(earliest.NotAfter) {
+			earliest = cert
+		}
+	}
+	return earliest
+}
+
+type Reference struct {
+	Link            string `json:"link"`
+	IsSubcollection bool   `json:"isSubcollection,omitempty"`
+}
+
 type SSLCertResponse struct {
-	Cert string `json:"cert"`
+	Kind                        string     `json:"kind"`
+	Name                        string     `json:"name"`
+	Partition                   string     `json:"partition"`
+	SubPath                     string     `json:"subPath,omitempty"`
+	FullPath                    string     `json:"fullPath"`
+	SelfLink                    string     `json:"selfLink"`
+	Cert                        string     `json:"cert"`
+	CommonName                  string     `json:"commonName"`
+	Subject                     string     `json:"subject"`
+	Issuer                      string     `json:"issuer"`
+	SerialNumber                string     `json:"serialNumber"`
+	Fingerprint                 string     `json:"fingerprint"`
+	KeyType                     string     `json:"keyType"`
+	KeySize                     int        `json:"keySize"`
+	CurveName                   string     `json:"curveName,omitempty"`
+	ExpirationDate              int64      `json:"expirationDate"`
+	ExpirationString            string     `json:"expirationString"`
+	IsBundle                    string     `json:"isBundle"`
+	BundleCertificatesReference *Reference `json:"bundleCertificatesReference,omitempty"`
+}
+
+type SSLCertListResponse struct {
+	Kind  string            `json:"kind"`
+	Items []SSLCertResponse `json:"items"`
+}
+
+type SSLCertBundleCertificate struct {
+	Kind             string `json:"kind"`
+	Name             string `json:"name"`
+	CommonName       string `json:"commonName"`
+	Subject          string `json:"subject"`
+	Issuer           string `json:"issuer"`
+	SerialNumber     string `json:"serialNumber"`
+	ExpirationDate   int64  `json:"expirationDate"`
+	ExpirationString string `json:"expirationString"`
+}
+
+type SSLCertBundleResponse struct {
+	Kind  string                     `json:"kind"`
+	Items []SSLCertBundleCertificate `json:"items"`
 }
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/iilun/f5-mock/internal/crypto"
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSSLCertHandlers(t *testing.T) {
+	key, err := crypto.GenerateKey(crypto.KeyTypeRSA, 2048, "")
+	require.NoError(t, err)
+
+	longCert, err := crypto.CreateSelfSignedCertificate(key, crypto.Subject{CommonName: "long"}, crypto.SubjectAlternativeNames{}, 365*24*time.Hour)
+	require.NoError(t, err)
+	shortCert, err := crypto.CreateSelfSignedCertificate(key, crypto.Subject{CommonName: "short"}, crypto.SubjectAlternativeNames{}, 24*time.Hour)
+	require.NoError(t, err)
+
+	cfg := testConfig()
+
+	_, _ = cache.New("")
+
+	_, _ = cache.GlobalCache.Fs.WriteFile("/certs/Monitoring/single.crt", longCert)
+	_, _ = cache.GlobalCache.Fs.WriteFile("/certs/Monitoring/bundle.crt", append(append([]byte{}, longCert...), shortCert...))
+
+	shortParsed, err := crypto.ParsePemCertificate(shortCert)
+	require.NoError(t, err)
+
+	logger := log.New(true)
+	defer logger.Close()
+
+	tests := []struct {
+		name       string
+		handler    F5Handler
+		path       string
+		wantStatus int
+		check      func(t *testing.T, body []byte)
+	}{
+		{
+			name:       "single certificate",
+			handler:    SSLCertHandler{},
+			path:       "~Monitoring~single.crt",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var resp SSLCertResponse
+				require.NoError(t, json.Unmarshal(body, &resp))
+				require.Equal(t, "false", resp.IsBundle)
+				require.Nil(t, resp.BundleCertificatesReference)
+				require.Equal(t, "long", resp.CommonName)
+				require.Equal(t, "rsa-public", resp.KeyType)
+				require.Equal(t, "/Monitoring/single.crt", resp.FullPath)
+			},
+		},
+		{
+			name:       "bundle reports the earliest expiration",
+			handler:    SSLCertHandler{},
+			path:       "~Monitoring~bundle.crt",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var resp SSLCertResponse
+				require.NoError(t, json.Unmarshal(body, &resp))
+				require.Equal(t, "true", resp.IsBundle)
+				require.Equal(t, shortParsed.NotAfter.Unix(), resp.ExpirationDate)
+				require.Equal(t, crypto.ExpirationString(shortParsed), resp.ExpirationString)
+				require.Equal(t, "https://localhost/mgmt/tm/sys/file/ssl-cert/~Monitoring~bundle.crt/bundle-certificates?ver=17.0.0.0", resp.BundleCertificatesReference.Link)
+			},
+		},
+		{
+			name:       "bundle certificates",
+			handler:    SSLCertBundleHandler{},
+			path:       "~Monitoring~bundle.crt",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var resp SSLCertBundleResponse
+				require.NoError(t, json.Unmarshal(body, &resp))
+				require.Len(t, resp.Items, 2)
+				require.Equal(t, "long", resp.Items[0].CommonName)
+				require.Equal(t, "short", resp.Items[1].CommonName)
+			},
+		},
+		{
+			name:       "unknown certificate",
+			handler:    SSLCertHandler{},
+			path:       "~Monitoring~missing.crt",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "list",
+			handler:    SSLCertListHandler{},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var resp SSLCertListResponse
+				require.NoError(t, json.Unmarshal(body, &resp))
+
+				var names []string
+				for _, item := range resp.Items {
+					if item.Partition == "Monitoring" {
+						names = append(names, item.Name)
+					}
+				}
+				require.Equal(t, []string{"bundle.crt", "single.crt"}, names)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := F5HandlerWrapper{tt.handler, logger, &cfg}
+
+			req := httptest.NewRequest(http.MethodGet, h.Route(), nil)
+			req.SetPathValue("path", tt.path)
+			req.SetBasicAuth(cfg.Auth.AdminUsername, cfg.Auth.AdminPassword)
+
+			rr := httptest.NewRecorder()
+			h.Handler()(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+
+			if tt.check != nil {
+				tt.check(t, rr.Body.Bytes())
+			}
+		})
+	}
+}
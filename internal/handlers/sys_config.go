@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+)
+
+// SysConfigHandler saves the running configuration and loads the last saved one. GET reports whether the running
+// configuration has unsaved changes, which is not part of the iControl REST API.
+type SysConfigHandler struct{}
+
+func (h SysConfigHandler) Route() string {
+	return "/mgmt/tm/sys/config"
+}
+
+func (h SysConfigHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				unsaved, err := cache.GlobalCache.UnsavedChanges()
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not compare configurations")
+					return
+				}
+
+				writeJSON(w, r, SysConfigResponse{
+					Kind:           "tm:sys:config:configstate",
+					UnsavedChanges: &unsaved,
+				})
+			case http.MethodPost:
+				// Loading replaces the objects of every partition
+				if !checkAdmin(w, r, "save or load the configuration") {
+					return
+				}
+
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
+				}
+
+				var req SysConfigRequest
+				err = json.Unmarshal(bodyBytes, &req)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid post request")
+					return
+				}
+
+				version, _ := r.Context().Value(log.ContextVersion).(string)
+				switch req.Command {
+				case "save":
+					err = cache.GlobalCache.SaveConfig(version)
+					if err != nil {
+						f5Error(w, r, http.StatusInternalServerError, "could not save configuration: %v", err)
+						return
+					}
+				case "load":
+					// Factory defaults are not modeled
+					if req.Name != "" {
+						f5Error(w, r, http.StatusBadRequest, "unsupported configuration: %s, only the saved configuration can be loaded", req.Name)
+						return
+					}
+
+					changes, err := cache.GlobalCache.LoadConfig()
+					if err != nil {
+						f5Error(w, r, http.StatusBadRequest, "could not load configuration: %v", err)
+						return
+					}
+					logger := loggerFromRequest(r)
+					for _, change := range changes {
+						logger.Info("Loaded configuration: %s", change)
+					}
+				default:
+					f5Error(w, r, http.StatusBadRequest, "invalid command: %s, only save and load are supported", req.Command)
+					return
+				}
+
+				writeJSON(w, r, SysConfigResponse{
+					Kind:    "tm:sys:config:" + req.Command + "state",
+					Command: req.Command,
+					Name:    req.Name,
+				})
+			default:
+				f5Error(w, r, http.StatusMethodNotAllowed, "only GET and POST allowed")
+			}
+		})
+}
+
+type SysConfigRequest struct {
+	Command string `json:"command"`
+	Name    string `json:"name"`
+}
+
+type SysConfigResponse struct {
+	Kind           string `json:"kind"`
+	Command        string `json:"command,omitempty"`
+	Name           string `json:"name,omitempty"`
+	UnsavedChanges *bool  `json:"unsavedChanges,omitempty"`
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+)
+
+type UCSListHandler struct{}
+
+func (h UCSListHandler) Route() string {
+	return "/mgmt/tm/sys/ucs"
+}
+
+func (h UCSListHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			version, _ := r.Context().Value(log.ContextVersion).(string)
+
+			switch r.Method {
+			case http.MethodGet:
+				// Archives are listed as stats, like on a BIG-IP
+				entries := map[string]UCSStats{}
+				for i, archive := range cache.GlobalCache.ListUCS() {
+					created := ""
+					if !archive.Created.IsZero() {
+						created = archive.Created.UTC().Format(time.RFC3339)
+					}
+					var stats UCSStats
+					stats.NestedStats.Entries.APIRawValues = UCSDescription{
+						Filename:        archive.Path,
+						FileSize:        fmt.Sprintf("%d (in bytes)", archive.Size),
+						FileCreatedDate: created,
+						Encrypted:       "no",
+						Version:         archive.Version,
+					}
+					entries[fmt.Sprintf("https://localhost/mgmt/tm/sys/ucs/%d", i)] = stats
+				}
+
+				writeJSON(w, r, UCSListResponse{
+					Kind:     "tm:sys:ucs:ucscollectionstats",
+					SelfLink: fmt.Sprintf("https://localhost/mgmt/tm/sys/ucs?ver=%s", version),
+					Entries:  entries,
+				})
+			case http.MethodPost:
+				// Archives hold and replace the objects of every partition
+				if !checkAdmin(w, r, "save or load UCS archives") {
+					return
+				}
+
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
+				}
+
+				var req UCSRequest
+				err = json.Unmarshal(bodyBytes, &req)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid post request")
+					return
+				}
+
+				if req.Passphrase != "" {
+					f5Error(w, r, http.StatusBadRequest, "encrypted UCS archives are not supported")
+					return
+				}
+				ucsFile, ok := ucsPath(req.Name)
+				if !ok {
+					f5Error(w, r, http.StatusBadRequest, "invalid UCS name: %s", req.Name)
+					return
+				}
+
+				switch req.Command {
+				case "save":
+					err = cache.GlobalCache.CreateUCS(ucsFile, version)
+					if err != nil {
+						f5Error(w, r, http.StatusInternalServerError, "could not save UCS archive: %v", err)
+						return
+					}
+				case "load":
+					if !cache.GlobalCache.Fs.Exists(ucsFile) {
+						f5Error(w, r, http.StatusNotFound, "UCS file %s not found", ucsFile)
+						return
+					}
+
+					changes, err := cache.GlobalCache.LoadUCS(ucsFile, version)
+					if err != nil {
+						f5Error(w, r, http.StatusBadRequest, "could not load UCS archive %s: %v", ucsFile, err)
+						return
+					}
+					logger := loggerFromRequest(r)
+					for _, change := range changes {
+						logger.Info("Loaded %s: %s", ucsFile, change)
+					}
+				default:
+					f5Error(w, r, http.StatusBadRequest, "invalid command: %s, only save and load are supported", req.Command)
+					return
+				}
+
+				writeJSON(w, r, UCSResponse{
+					Kind:    "tm:sys:ucs:" + req.Command + "state",
+					Command: req.Command,
+					Name:    req.Name,
+				})
+			default:
+				f5Error(w, r, http.StatusMethodNotAllowed, "only GET and POST allowed")
+			}
+		})
+}
+
+type UCSHandler struct{}
+
+func (h UCSHandler) Route() string {
+	return "/mgmt/tm/sys/ucs/{name}"
+}
+
+func (h UCSHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodDelete {
+				f5Error(w, r, http.StatusMethodNotAllowed, "only DELETE allowed")
+				return
+			}
+
+			if !checkAdmin(w, r, "delete UCS archives") {
+				return
+			}
+
+			ucsFile, ok := ucsPath(r.PathValue("name"))
+			if !ok || cache.GlobalCache.Fs.Remove(ucsFile) != nil {
+				f5Error(w, r, http.StatusNotFound, "UCS file %s not found", r.PathValue("name"))
+				return
+			}
+		})
+}
+
+// UCSDownloadHandler serves the UCS archives of /var/local/ucs, supporting range requests for chunked downloads
+type UCSDownloadHandler struct{}
+
+func (h UCSDownloadHandler) Route() string {
+	return "/mgmt/shared/file-transfer/ucs-downloads/{name}"
+}
+
+func (h UCSDownloadHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				f5Error(w, r, http.StatusMethodNotAllowed, "only GET allowed")
+				return
+			}
+
+			// Archives hold every key and password hash
+			if !checkAdmin(w, r, "download UCS archives") {
+				return
+			}
+
+			name := r.PathValue("name")
+			if strings.Contains(name, "/") {
+				f5Error(w, r, http.StatusBadRequest, "invalid path")
+				return
+			}
+			content, err := cache.GlobalCache.Fs.ReadFile(path.Join(cache.UCSDir, name))
+			if err != nil {
+				f5Error(w, r, http.StatusNotFound, "file %s not found", name)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/octet-stream")
+			http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(content))
+		})
+}
+
+// UCSUploadHandler stores uploaded UCS archives in /var/local/ucs, where they can be loaded from
+type UCSUploadHandler struct{}
+
+func (h UCSUploadHandler) Route() string {
+	return "/mgmt/shared/file-transfer/ucs-uploads/{path}"
+}
+
+func (h UCSUploadHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			if !checkAdmin(w, r, "upload UCS archives") {
+				return
+			}
+			uploadFile(w, r, cache.UCSDir)
+		})
+}
+
+// ucsPath returns the path of a UCS archive from its name, relative names being in /var/local/ucs and the .ucs
+// extension being optional like with tmsh. Archives are only stored in /var/local/ucs.
+func ucsPath(name string) (string, bool) {
+	filePath := name
+	if !strings.HasPrefix(filePath, "/") {
+		filePath = cache.UCSDir + "/" + filePath
+	}
+	if !strings.HasSuffix(filePath, ".ucs") {
+		filePath += ".ucs"
+	}
+
+	fileName, found := strings.CutPrefix(filePath, cache.UCSDir+"/")
+	if !found || fileName == ".ucs" || strings.Contains(fileName, "/") {
+		return "", false
+	}
+	return filePath, true
+}
+
+type UCSRequest struct {
+	Command    string `json:"command"`
+	Name       string `json:"name"`
+	Passphrase string `json:"passphrase"`
+}
+
+type UCSResponse struct {
+	Kind    string `json:"kind"`
+	Command string `json:"command"`
+	Name    string `json:"name"`
+}
+
+type UCSDescription struct {
+	Filename        string `json:"filename"`
+	FileSize        string `json:"file_size"`
+	FileCreatedDate string `json:"file_created_date"`
+	Encrypted       string `json:"encrypted"`
+	Version         string `json:"version,omitempty"`
+}
+
+type UCSStats struct {
+	NestedStats struct {
+		Entries struct {
+			APIRawValues UCSDescription `json:"apiRawValues"`
+		} `json:"entries"`
+	} `json:"nestedStats"`
+}
+
+type UCSListResponse struct {
+	Kind     string              `json:"kind"`
+	SelfLink string              `json:"selfLink"`
+	Entries  map[string]UCSStats `json:"entries"`
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSysConfigAndUCS(t *testing.T) {
+	cfg := testConfig()
+
+	_, _ = cache.New("")
+	cache.GlobalCache.Partitions = []*models.Partition{{Name: "Prod"}}
+	cache.GlobalCache.Users = []*models.User{
+		{Name: "guest", Password: "guest", PartitionAccess: []models.PartitionAccess{{Name: models.AllPartitions, Role: models.RoleGuest}}},
+		{Name: "prodadmin", Password: "prodadmin", PartitionAccess: []models.PartitionAccess{{Name: "Prod", Role: models.RoleAdmin}}},
+	}
+	require.NoError(t, cache.GlobalCache.SaveConfig(cfg.DefaultVersion))
+
+	logger := log.New(true)
+	defer logger.Close()
+
+	// The archive saved by the steps below, to be uploaded again
+	var archive []byte
+
+	tests := []struct {
+		name       string
+		handler    F5Handler
+		method     string
+		uri        string
+		username   string
+		body       string
+		setup      func()
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "saved",
+			handler:    SysConfigHandler{},
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"kind":"tm:sys:config:configstate","unsavedChanges":false}`,
+		},
+		{
+			name:    "unsaved changes",
+			handler: SysConfigHandler{},
+			method:  http.MethodGet,
+			setup: func() {
+				cache.GlobalCache.Partitions = append(cache.GlobalCache.Partitions, &models.Partition{Name: "Dev"})
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"kind":"tm:sys:config:configstate","unsavedChanges":true}`,
+		},
+		{
+			name:       "load",
+			handler:    SysConfigHandler{},
+			method:     http.MethodPost,
+			body:       `{"command":"load"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"kind":"tm:sys:config:loadstate","command":"load"}`,
+		},
+		{
+			name:       "load defaults",
+			handler:    SysConfigHandler{},
+			method:     http.MethodPost,
+			body:       `{"command":"load","name":"default"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "unsupported configuration: default",
+		},
+		{
+			name:    "save",
+			handler: SysConfigHandler{},
+			method:  http.MethodPost,
+			body:    `{"command":"save"}`,
+			setup: func() {
+				cache.GlobalCache.Partitions = append(cache.GlobalCache.Partitions, &models.Partition{Name: "Staging"})
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"kind":"tm:sys:config:savestate","command":"save"}`,
+		},
+		{
+			name:       "invalid command",
+			handler:    SysConfigHandler{},
+			method:     http.MethodPost,
+			body:       `{"command":"merge"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid command: merge, only save and load are supported",
+		},
+		{
+			name:       "save as a guest",
+			handler:    SysConfigHandler{},
+			method:     http.MethodPost,
+			username:   "guest",
+			body:       `{"command":"save"}`,
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "Authorization failed: user=guest resource=/mgmt/tm/sys/config verb=POST",
+		},
+		{
+			name:       "load as a partition admin",
+			handler:    SysConfigHandler{},
+			method:     http.MethodPost,
+			username:   "prodadmin",
+			body:       `{"command":"load"}`,
+			wantStatus: http.StatusForbidden,
+			wantBody:   "Access Denied: User (prodadmin) may not save or load the configuration",
+		},
+		{
+			name:       "save ucs as a partition admin",
+			handler:    UCSListHandler{},
+			method:     http.MethodPost,
+			username:   "prodadmin",
+			body:       `{"command":"save","name":"backup"}`,
+			wantStatus: http.StatusForbidden,
+			wantBody:   "Access Denied: User (prodadmin) may not save or load UCS archives",
+		},
+		{
+			name:       "save ucs",
+			handler:    UCSListHandler{},
+			method:     http.MethodPost,
+			body:       `{"command":"save","name":"backup"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"kind":"tm:sys:ucs:savestate","command":"save","name":"backup"}`,
+		},
+		{
+			name:       "encrypted ucs",
+			handler:    UCSListHandler{},
+			method:     http.MethodPost,
+			body:       `{"command":"save","name":"secret","passphrase":"secret"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "encrypted UCS archives are not supported",
+		},
+		{
+			name:       "ucs outside of /var/local/ucs",
+			handler:    UCSListHandler{},
+			method:     http.MethodPost,
+			body:       `{"command":"save","name":"/tmp/backup.ucs"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid UCS name: /tmp/backup.ucs",
+		},
+		{
+			name:       "list ucs",
+			handler:    UCSListHandler{},
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantBody:   `"entries":{"https://localhost/mgmt/tm/sys/ucs/0":{"nestedStats":{"entries":{"apiRawValues":{"filename":"/var/local/ucs/backup.ucs","file_size":"`,
+		},
+		{
+			name:       "download as a guest",
+			handler:    UCSDownloadHandler{},
+			method:     http.MethodGet,
+			uri:        "/mgmt/shared/file-transfer/ucs-downloads/backup.ucs",
+			username:   "guest",
+			wantStatus: http.StatusForbidden,
+			wantBody:   "Access Denied: User (guest) may not download UCS archives",
+		},
+		{
+			name:       "download",
+			handler:    UCSDownloadHandler{},
+			method:     http.MethodGet,
+			uri:        "/mgmt/shared/file-transfer/ucs-downloads/backup.ucs",
+			wantStatus: http.StatusOK,
+			wantBody:   "\x1f\x8b",
+		},
+		{
+			name:       "delete ucs as a partition admin",
+			handler:    UCSHandler{},
+			method:     http.MethodDelete,
+			uri:        "/mgmt/tm/sys/ucs/backup.ucs",
+			username:   "prodadmin",
+			wantStatus: http.StatusForbidden,
+			wantBody:   "Access Denied: User (prodadmin) may not delete UCS archives",
+		},
+		{
+			name:       "delete ucs",
+			handler:    UCSHandler{},
+			method:     http.MethodDelete,
+			uri:        "/mgmt/tm/sys/ucs/backup.ucs",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "download deleted ucs",
+			handler:    UCSDownloadHandler{},
+			method:     http.MethodGet,
+			uri:        "/mgmt/shared/file-transfer/ucs-downloads/backup.ucs",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "file backup.ucs not found",
+		},
+		{
+			name:       "upload as a guest",
+			handler:    UCSUploadHandler{},
+			method:     http.MethodPost,
+			uri:        "/mgmt/shared/file-transfer/ucs-uploads/restored.ucs",
+			username:   "guest",
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "Authorization failed: user=guest resource=/mgmt/shared/file-transfer/ucs-uploads/restored.ucs verb=POST",
+		},
+		{
+			name:       "upload as a partition admin",
+			handler:    UCSUploadHandler{},
+			method:     http.MethodPost,
+			uri:        "/mgmt/shared/file-transfer/ucs-uploads/restored.ucs",
+			username:   "prodadmin",
+			wantStatus: http.StatusForbidden,
+			wantBody:   "Access Denied: User (prodadmin) may not upload UCS archives",
+		},
+		{
+			name:       "upload with encoded parent segments",
+			handler:    UCSUploadHandler{},
+			method:     http.MethodPost,
+			uri:        "/mgmt/shared/file-transfer/ucs-uploads/..%2F..%2F..%2Fkeys%2FProd%2Fevil.key",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid path",
+		},
+		{
+			name:    "upload",
+			handler: UCSUploadHandler{},
+			method:  http.MethodPost,
+			uri:     "/mgmt/shared/file-transfer/ucs-uploads/restored.ucs",
+			setup: func() {
+				// Partitions added after the archive was saved are removed when loading it
+				cache.GlobalCache.Partitions = append(cache.GlobalCache.Partitions, &models.Partition{Name: "Later"})
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "load ucs",
+			handler:    UCSListHandler{},
+			method:     http.MethodPost,
+			body:       `{"command":"load","name":"restored.ucs"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"kind":"tm:sys:ucs:loadstate","command":"load","name":"restored.ucs"}`,
+		},
+		{
+			name:       "load missing ucs",
+			handler:    UCSListHandler{},
+			method:     http.MethodPost,
+			body:       `{"command":"load","name":"missing"}`,
+			wantStatus: http.StatusNotFound,
+			wantBody:   "UCS file /var/local/ucs/missing.ucs not found",
+		},
+		{
+			name:       "delete missing ucs",
+			handler:    UCSHandler{},
+			method:     http.MethodDelete,
+			uri:        "/mgmt/tm/sys/ucs/backup.ucs",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "UCS file backup.ucs not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+
+			h := F5HandlerWrapper{tt.handler, logger, &cfg}
+			mux := http.NewServeMux()
+			mux.HandleFunc(h.Route(), h.Handler())
+
+			uri := tt.uri
+			if uri == "" {
+				uri = h.Route()
+			}
+			body := bytes.NewBufferString(tt.body)
+			contentType := "application/json"
+			if tt.handler == (UCSUploadHandler{}) {
+				body = bytes.NewBuffer(archive)
+				contentType = "application/octet-stream"
+			}
+
+			req := httptest.NewRequest(tt.method, uri, body)
+			req.Header.Set("Content-Type", contentType)
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.username)
+			} else {
+				req.SetBasicAuth(cfg.Auth.AdminUsername, cfg.Auth.AdminPassword)
+			}
+
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			require.Contains(t, rr.Body.String(), tt.wantBody)
+			if tt.handler == (UCSDownloadHandler{}) && rr.Code == http.StatusOK {
+				archive = rr.Body.Bytes()
+			}
+		})
+	}
+
+	// The restored archive holds the configuration saved with it
+	var partitions []string
+	for _, partition := range cache.GlobalCache.Partitions {
+		partitions = append(partitions, partition.Name)
+	}
+	require.Equal(t, []string{"Prod", "Staging"}, partitions)
+	unsaved, err := cache.GlobalCache.UnsavedChanges()
+	require.NoError(t, err)
+	require.False(t, unsaved)
+
+	require.False(t, cache.GlobalCache.Fs.Exists("/keys/Prod/evil.key"))
+	require.NoError(t, cache.GlobalCache.Fs.Remove(cache.UCSDir+"/restored.ucs"))
+	cache.GlobalCache.Partitions = nil
+	cache.GlobalCache.Users = nil
+}
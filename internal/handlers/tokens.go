@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
+	"io"
+	"net/http"
+)
+
+type TokenListHandler struct{}
+
+func (h TokenListHandler) Route() string {
+	return "/mgmt/shared/authz/tokens"
+}
+
+func (h TokenListHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				f5Error(w, r, http.StatusMethodNotAllowed, "only GET allowed")
+				return
+			}
+
+			tokens, err := cache.GlobalCache.ListTokens()
+			if err != nil {
+				f5Error(w, r, http.StatusInternalServerError, "could not list tokens")
+				return
+			}
+
+			// Only administrators can see the tokens of other users
+			user := userFromRequest(r)
+			items := []models.AuthToken{}
+			for _, token := range tokens {
+				if isAdmin(user) || token.UserName == user.Name {
+					items = append(items, token)
+				}
+			}
+
+			respBytes, err := json.Marshal(TokenListResponse{
+				Kind:  "shared:authz:tokens:authtokencollectionstate",
+				Items: items,
+			})
+			if err != nil {
+				f5Error(w, r, http.StatusInternalServerError, "could not marshal response")
+				return
+			}
+
+			_, err = w.Write(respBytes)
+			if err != nil {
+				f5Error(w, r, http.StatusInternalServerError, "could not write response")
+				return
+			}
+		})
+}
+
+type TokenHandler struct{}
+
+func (h TokenHandler) Route() string {
+	return "/mgmt/shared/authz/tokens/{token}"
+}
+
+func (h TokenHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			token, err := cache.GlobalCache.GetToken(r.PathValue("token"))
+			if err == nil && !isAdmin(userFromRequest(r)) && token.UserName != userFromRequest(r).Name {
+				err = cache.ErrTokenNotFound
+			}
+			if err != nil {
+				if errors.Is(err, cache.ErrTokenNotFound) || errors.Is(err, cache.ErrTokenExpired) {
+					f5Error(w, r, http.StatusNotFound, "Object not found - %s", r.PathValue("token"))
+				} else {
+					f5Error(w, r, http.StatusInternalServerError, "%v", err)
+				}
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				writeToken(w, r, token)
+			case http.MethodPatch:
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
+				}
+
+				var request TokenPatchRequest
+				err = json.Unmarshal(bodyBytes, &request)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid patch request")
+					return
+				}
+
+				if request.Timeout == nil {
+					writeToken(w, r, token)
+					return
+				}
+
+				if *request.Timeout <= 0 || *request.Timeout > cache.MaxTokenTimeout {
+					f5Error(w, r, http.StatusBadRequest, "invalid timeout value %d, the maximum allowed value is %d", *request.Timeout, cache.MaxTokenTimeout)
+					return
+				}
+
+				err = cache.SetTokenTimeout(&token, *request.Timeout)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not update token: %v", err)
+					return
+				}
+
+				err = cache.GlobalCache.SetToken(token)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not set cache entry")
+					return
+				}
+
+				writeToken(w, r, token)
+			case http.MethodDelete:
+				err = cache.GlobalCache.DeleteToken(token.Token)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not delete token: %v", err)
+					return
+				}
+
+				writeToken(w, r, token)
+			default:
+				f5Error(w, r, http.StatusMethodNotAllowed, "invalid method")
+			}
+		})
+}
+
+func writeToken(w http.ResponseWriter, r *http.Request, token models.AuthToken) {
+	respBytes, err := json.Marshal(token)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not marshal response")
+		return
+	}
+
+	_, err = w.Write(respBytes)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not write response")
+		return
+	}
+}
+
+type TokenPatchRequest struct {
+	Timeout *int `json:"timeout"`
+}
+
+type TokenListResponse struct {
+	Kind  string             `json:"kind"`
+	Items []models.AuthToken `json:"items"`
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenHandler(t *testing.T) {
+	cfg := testConfig()
+
+	_, _ = cache.New("")
+
+	logger := log.New(true)
+	defer logger.Close()
+
+	token := cache.NewAuthToken("admin", "tmos", "127.0.0.1")
+	require.NoError(t, cache.GlobalCache.SetToken(token))
+
+	tests := []struct {
+		name       string
+		handler    F5Handler
+		method     string
+		token      string
+		body       string
+		wantStatus int
+		wantBody   string
+		check      func(t *testing.T, body []byte)
+	}{
+		{
+			name:       "get token",
+			handler:    TokenHandler{},
+			method:     http.MethodGet,
+			token:      token.Token,
+			wantStatus: http.StatusOK,
+			wantBody:   `"timeout":1200`,
+		},
+		{
+			name:       "list tokens",
+			handler:    TokenListHandler{},
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantBody:   token.Token,
+		},
+		{
+			name:       "extend timeout",
+			handler:    TokenHandler{},
+			method:     http.MethodPatch,
+			token:      token.Token,
+			body:       `{"timeout":36000}`,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var resp models.AuthToken
+				require.NoError(t, json.Unmarshal(body, &resp))
+				require.Equal(t, 36000, resp.Timeout)
+				require.Equal(t, token.ExpirationMicros+int64(36000-1200)*1_000_000, resp.ExpirationMicros)
+			},
+		},
+		{
+			name:       "timeout above maximum",
+			handler:    TokenHandler{},
+			method:     http.MethodPatch,
+			token:      token.Token,
+			body:       `{"timeout":36001}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid timeout value 36001, the maximum allowed value is 36000",
+		},
+		{
+			name:       "revoke token",
+			handler:    TokenHandler{},
+			method:     http.MethodDelete,
+			token:      token.Token,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "revoked token is gone",
+			handler:    TokenHandler{},
+			method:     http.MethodGet,
+			token:      token.Token,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := F5HandlerWrapper{tt.handler, logger, &cfg}
+
+			req := httptest.NewRequest(tt.method, h.Route(), bytes.NewBufferString(tt.body))
+			req.SetPathValue("token", tt.token)
+			req.SetBasicAuth(cfg.Auth.AdminUsername, cfg.Auth.AdminPassword)
+
+			rr := httptest.NewRecorder()
+			h.Handler()(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+			require.Contains(t, rr.Body.String(), tt.wantBody)
+
+			if tt.check != nil {
+				tt.check(t, rr.Body.Bytes())
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"github.com/iilun/f5-mock/internal/config"
 	"github.com/iilun/f5-mock/internal/log"
 	"net/http"
 )
@@ -14,6 +15,7 @@ type F5Handler interface {
 type F5HandlerWrapper struct {
 	wrapped F5Handler
 	logger  log.Logger
+	config  *config.Config
 }
 
 func (w F5HandlerWrapper) Route() string {
@@ -21,12 +23,21 @@ func (w F5HandlerWrapper) Route() string {
 }
 
 func (w F5HandlerWrapper) Handler() http.HandlerFunc {
-	return loggingMiddleware(w.logger, applyVersionMiddleware(w.wrapped.Handler()))
+	return loggingMiddleware(w.logger, configMiddleware(w.config, lockStoreMiddleware(applyVersionMiddleware(w.wrapped.Handler()))))
 }
 
-func RegisterHandler(h F5Handler, log log.Logger) {
+// internalHandler applies the base middlewares but the store lock, held by the callers of internal requests
+func (w F5HandlerWrapper) internalHandler() http.HandlerFunc {
+	return loggingMiddleware(w.logger, configMiddleware(w.config, applyVersionMiddleware(w.wrapped.Handler())))
+}
+
+// internalMux routes the requests of NewRequester
+var internalMux = http.NewServeMux()
+
+func RegisterHandler(h F5Handler, log log.Logger, cfg *config.Config) {
 	// Wrap to apply all base middlewares
-	wrapped := F5HandlerWrapper{h, log}
+	wrapped := F5HandlerWrapper{h, log, cfg}
 
 	http.HandleFunc(wrapped.Route(), wrapped.Handler())
+	internalMux.HandleFunc(wrapped.Route(), wrapped.internalHandler())
 }
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"path"
+	"strings"
 )
 
 type UploadHandler struct{}
@@ -16,42 +17,64 @@ func (h UploadHandler) Route() string {
 func (h UploadHandler) Handler() http.HandlerFunc {
 	return authenticatedRequestMiddleware(
 		func(w http.ResponseWriter, r *http.Request) {
-			if r.Method != http.MethodPost {
-				f5Error(w, r, http.StatusMethodNotAllowed, "only POST allowed")
-				return
-			}
-
-			err := checkContentType(r, "application/octet-stream")
-			if err != nil {
-				f5Error(w, r, http.StatusUnsupportedMediaType, "%v", err)
-				return
-			}
-
-			uploadPath := r.PathValue("path")
-			if uploadPath == "" {
-				f5Error(w, r, http.StatusBadRequest, "invalid path")
-				return
-			}
-
-			// Path is stored
-			uploadPath = path.Join("/var/config/rest/downloads/", uploadPath)
-
-			// Check if path not already exists
-			if cache.GlobalCache.Fs.Exists(uploadPath) {
-				f5Error(w, r, http.StatusBadRequest, "file already exists")
-				return
-			}
-
-			toWrite, err := io.ReadAll(r.Body)
-			if err != nil {
-				f5Error(w, r, http.StatusInternalServerError, "could not read request")
-				return
-			}
-
-			_, err = cache.GlobalCache.Fs.WriteFile(uploadPath, toWrite)
-			if err != nil {
-				f5Error(w, r, http.StatusInternalServerError, "could not write request")
-				return
-			}
+			uploadFile(w, r, cache.UploadsDir)
 		})
 }
+
+// uploadFile stores the body of an upload request in dir, under the path of the request
+func uploadFile(w http.ResponseWriter, r *http.Request, dir string) {
+	if r.Method != http.MethodPost {
+		f5Error(w, r, http.StatusMethodNotAllowed, "only POST allowed")
+		return
+	}
+
+	err := checkContentType(r, "application/octet-stream")
+	if err != nil {
+		f5Error(w, r, http.StatusUnsupportedMediaType, "%v", err)
+		return
+	}
+
+	// Path values are unescaped, so encoded slashes could reach other directories
+	uploadPath := r.PathValue("path")
+	if uploadPath == "" || uploadPath == "." || uploadPath == ".." || strings.Contains(uploadPath, "/") {
+		f5Error(w, r, http.StatusBadRequest, "invalid path")
+		return
+	}
+
+	// Path is stored
+	uploadPath = path.Join(dir, uploadPath)
+
+	// Check if path not already exists
+	if cache.GlobalCache.Fs.Exists(uploadPath) {
+		f5Error(w, r, http.StatusBadRequest, "file already exists")
+		return
+	}
+
+	toWrite, err := io.ReadAll(r.Body)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not read request")
+		return
+	}
+
+	_, err = cache.GlobalCache.Fs.WriteFile(uploadPath, toWrite)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not write request")
+		return
+	}
+}
+
+// readUpload reads the local file a crypto object is installed from, which must be an upload: other files, like the
+// keys of other partitions, cannot be installed
+func readUpload(w http.ResponseWriter, r *http.Request, filePath string) ([]byte, bool) {
+	if !strings.HasPrefix(path.Clean(filePath), cache.UploadsDir+"/") {
+		f5Error(w, r, http.StatusBadRequest, "local file %s is not in %s", filePath, cache.UploadsDir)
+		return nil, false
+	}
+
+	contents, err := cache.GlobalCache.Fs.ReadFile(path.Clean(filePath))
+	if err != nil {
+		f5Error(w, r, http.StatusBadRequest, "could not read local file")
+		return nil, false
+	}
+	return contents, true
+}
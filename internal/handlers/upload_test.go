@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadHandler(t *testing.T) {
+	cfg := testConfig()
+
+	_, _ = cache.New("")
+
+	logger := log.New(true)
+	defer logger.Close()
+
+	tests := []struct {
+		name       string
+		uri        string
+		wantStatus int
+		wantBody   string
+		wantFile   string
+	}{
+		{
+			name:       "upload",
+			uri:        "/mgmt/shared/file-transfer/uploads/app.crt",
+			wantStatus: http.StatusOK,
+			wantFile:   "/var/config/rest/downloads/app.crt",
+		},
+		{
+			name:       "existing file",
+			uri:        "/mgmt/shared/file-transfer/uploads/app.crt",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "file already exists",
+		},
+		{
+			name:       "encoded parent segments",
+			uri:        "/mgmt/shared/file-transfer/uploads/..%2F..%2F..%2F..%2Fkeys%2FProd%2Fevil.key",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid path",
+		},
+		{
+			name:       "encoded slash",
+			uri:        "/mgmt/shared/file-transfer/uploads/sub%2Fapp.crt",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid path",
+		},
+		{
+			name:       "encoded parent",
+			uri:        "/mgmt/shared/file-transfer/uploads/%2E%2E",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := F5HandlerWrapper{UploadHandler{}, logger, &cfg}
+			mux := http.NewServeMux()
+			mux.HandleFunc(h.Route(), h.Handler())
+
+			req := httptest.NewRequest(http.MethodPost, tt.uri, bytes.NewBufferString("content"))
+			req.Header.Set("Content-Type", "application/octet-stream")
+			req.SetBasicAuth(cfg.Auth.AdminUsername, cfg.Auth.AdminPassword)
+
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			require.Contains(t, rr.Body.String(), tt.wantBody)
+			if tt.wantFile != "" {
+				require.True(t, cache.GlobalCache.Fs.Exists(tt.wantFile))
+			}
+		})
+	}
+
+	require.False(t, cache.GlobalCache.Fs.Exists("/keys/Prod/evil.key"))
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/f5Validator"
+	"github.com/iilun/f5-mock/pkg/models"
+)
+
+type UserListHandler struct{}
+
+func (h UserListHandler) Route() string {
+	return "/mgmt/tm/auth/user"
+}
+
+func (h UserListHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				auth := configFromRequest(r).Auth
+
+				items := []UserResponse{newUserResponse(r, findUser(auth, auth.AdminUsername))}
+				for _, user := range cache.GlobalCache.Users {
+					if user.Name == auth.AdminUsername {
+						// Shadowed by the configured admin
+						continue
+					}
+					items = append(items, newUserResponse(r, user))
+				}
+
+				writeJSON(w, r, UserListResponse{
+					Kind:  "tm:auth:user:usercollectionstate",
+					Items: items,
+				})
+			case http.MethodPost:
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
+				}
+
+				var user models.User
+				err = json.Unmarshal(bodyBytes, &user)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid post request")
+					return
+				}
+
+				err = f5Validator.Validate.Struct(user)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid user: %v", err)
+					return
+				}
+
+				if !checkAccessPartitions(w, r, user.PartitionAccess) {
+					return
+				}
+
+				if user.Password == "" {
+					f5Error(w, r, http.StatusBadRequest, "password is required")
+					return
+				}
+
+				err = setPassword(&user, user.Password)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "%v", err)
+					return
+				}
+
+				if findUser(configFromRequest(r).Auth, user.Name) != nil {
+					f5Error(w, r, http.StatusConflict, "user %s already exists", user.Name)
+					return
+				}
+
+				logger := loggerFromRequest(r)
+				logger.Debug("Added %s user", user.Name)
+
+				cache.GlobalCache.Users = append(cache.GlobalCache.Users, &user)
+
+				writeJSON(w, r, newUserResponse(r, &user))
+			default:
+				f5Error(w, r, http.StatusMethodNotAllowed, "invalid method")
+			}
+		})
+}
+
+type UserHandler struct{}
+
+func (h UserHandler) Route() string {
+	return "/mgmt/tm/auth/user/{name}"
+}
+
+func (h UserHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			auth := configFromRequest(r).Auth
+
+			name := r.PathValue("name")
+			user := findUser(auth, name)
+			if user == nil {
+				f5Error(w, r, http.StatusNotFound, "user %s not found", name)
+				return
+			}
+
+			if r.Method == http.MethodDelete && name == auth.AdminUsername {
+				f5Error(w, r, http.StatusBadRequest, "user %s is configured from the environment and cannot be modified", name)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				writeJSON(w, r, newUserResponse(r, user))
+			case http.MethodPatch:
+				bodyBytes, err := io.ReadAll(r.Body)
+				if err != nil {
+					f5Error(w, r, http.StatusInternalServerError, "could not read request")
+					return
+				}
+
+				// Apply the patch on a copy so that invalid values leave the user untouched
+				updated := *user
+				// Unmarshal would otherwise reuse the backing array of the user slice
+				updated.PartitionAccess = slices.Clone(user.PartitionAccess)
+				err = json.Unmarshal(bodyBytes, &updated)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid patch request")
+					return
+				}
+
+				if updated.Name != user.Name {
+					f5Error(w, r, http.StatusBadRequest, "user name cannot be modified")
+					return
+				}
+
+				// The configured admin can only be unlocked
+				if name == auth.AdminUsername && !onlyChanged(*user, updated, func(u *models.User) { u.Locked = updated.Locked }) {
+					f5Error(w, r, http.StatusBadRequest, "user %s is configured from the environment and cannot be modified", name)
+					return
+				}
+
+				// Users can change their own password without being administrators
+				requester := userFromRequest(r)
+				if !isAdmin(requester) && (name != requester.Name || !onlyChanged(*user, updated, func(u *models.User) { u.Password = updated.Password })) {
+					f5Error(w, r, http.StatusForbidden, "Access Denied: User (%s) may only change its password", requester.Name)
+					return
+				}
+
+				err = f5Validator.Validate.Struct(updated)
+				if err != nil {
+					f5Error(w, r, http.StatusBadRequest, "invalid user: %v", err)
+					return
+				}
+
+				if !checkAccessPartitions(w, r, updated.PartitionAccess) {
+					return
+				}
+
+				if updated.Password != user.Password {
+					err = setPassword(&updated, updated.Password)
+					if err != nil {
+						f5Error(w, r, http.StatusBadRequest, "%v", err)
+						return
+					}
+				}
+
+				if user.Locked && !updated.Locked {
+					unlockUser(&updated)
+				}
+
+				*user = updated
+
+				writeJSON(w, r, newUserResponse(r, user))
+			case http.MethodDelete:
+				cache.GlobalCache.Users = slices.DeleteFunc(cache.GlobalCache.Users, func(u *models.User) bool {
+					return u == user
+				})
+
+				writeJSON(w, r, newUserResponse(r, user))
+			default:
+				f5Error(w, r, http.StatusMethodNotAllowed, "invalid method")
+			}
+		})
+}
+
+func newUserResponse(r *http.Request, user *models.User) UserResponse {
+	version, _ := r.Context().Value(log.ContextVersion).(string)
+
+	return UserResponse{
+		Kind:            "tm:auth:user:userstate",
+		Name:            user.Name,
+		FullPath:        user.Name,
+		Description:     user.Description,
+		PartitionAccess: user.PartitionAccess,
+		Locked:          isLocked(user),
+		PasswordExpired: isPasswordExpired(user),
+		SelfLink:        fmt.Sprintf("https://localhost/mgmt/tm/auth/user/%s?ver=%s", user.Name, version),
+	}
+}
+
+// checkAccessPartitions writes a 400 error and returns false if partition accesses cannot be granted
+func checkAccessPartitions(w http.ResponseWriter, r *http.Request, access []models.PartitionAccess) bool {
+	if err := accessPartitionsError(access); err != nil {
+		f5Error(w, r, http.StatusBadRequest, "%v", err)
+		return false
+	}
+	return true
+}
+
+// accessPartitionsError reports a partition access naming a missing partition, or giving the admin role on a
+// single partition
+func accessPartitionsError(access []models.PartitionAccess) error {
+	for _, partitionAccess := range access {
+		if partitionAccess.Role == models.RoleAdmin && partitionAccess.Name != models.AllPartitions {
+			return fmt.Errorf("The role (%s) can only be assigned to all partitions, not to partition (%s).", models.RoleAdmin, partitionAccess.Name)
+		}
+		if partitionAccess.Name != models.AllPartitions && cache.GlobalCache.FindPartition(partitionAccess.Name) == nil {
+			return fmt.Errorf("The requested partition (%s) was not found.", partitionAccess.Name)
+		}
+	}
+	return nil
+}
+
+// onlyChanged reports whether updated only differs from original by the fields set by apply
+func onlyChanged(original, updated models.User, apply func(u *models.User)) bool {
+	apply(&original)
+	return reflect.DeepEqual(original, updated)
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, response any) {
+	respBytes, err := json.Marshal(response)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not marshal response")
+		return
+	}
+
+	_, err = w.Write(respBytes)
+	if err != nil {
+		f5Error(w, r, http.StatusInternalServerError, "could not write response")
+		return
+	}
+}
+
+type UserResponse struct {
+	Kind            string                   `json:"kind"`
+	Name            string                   `json:"name"`
+	FullPath        string                   `json:"fullPath"`
+	Description     string                   `json:"description,omitempty"`
+	PartitionAccess []models.PartitionAccess `json:"partitionAccess"`
+	Locked          bool                     `json:"locked"`
+	PasswordExpired bool                     `json:"passwordExpired"`
+	SelfLink        string                   `json:"selfLink"`
+}
+
+type UserListResponse struct {
+	Kind  string         `json:"kind"`
+	Items []UserResponse `json:"items"`
+}
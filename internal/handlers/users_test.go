@@ -0,0 +1,470 @@
+package handlers
+
+import (
+	"bytes"
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUserRoles(t *testing.T) {
+	cfg := testConfig()
+
+	_, _ = cache.New("")
+
+	logger := log.New(true)
+	defer logger.Close()
+
+	cache.GlobalCache.Partitions = []*models.Partition{{Name: "Prod"}}
+	cache.GlobalCache.Users = []*models.User{
+		{
+			Name:            "certmgr",
+			Password:        "certpass",
+			PartitionAccess: []models.PartitionAccess{{Name: "Common", Role: models.RoleCertificateManager}},
+		},
+		{
+			Name:            "viewer",
+			Password:        "viewpass",
+			PartitionAccess: []models.PartitionAccess{{Name: models.AllPartitions, Role: models.RoleGuest}},
+		},
+		{
+			Name:            "prodadmin",
+			Password:        "prodpass",
+			PartitionAccess: []models.PartitionAccess{{Name: "Prod", Role: models.RoleAdmin}},
+		},
+	}
+
+	admin := [2]string{cfg.Auth.AdminUsername, cfg.Auth.AdminPassword}
+	certmgr := [2]string{"certmgr", "certpass"}
+	viewer := [2]string{"viewer", "viewpass"}
+	prodAdmin := [2]string{"prodadmin", "prodpass"}
+
+	tests := []struct {
+		name        string
+		handler     F5Handler
+		method      string
+		credentials [2]string
+		pathValue   string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:        "certificate manager creates a key",
+			handler:     CryptoKeyHandler{},
+			method:      http.MethodPost,
+			credentials: certmgr,
+			body:        `{"command":"create","name":"/Common/rbac.key"}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "certificate manager cannot use another partition",
+			handler:     CryptoKeyHandler{},
+			method:      http.MethodPost,
+			credentials: certmgr,
+			body:        `{"command":"create","name":"/Prod/rbac.key"}`,
+			wantStatus:  http.StatusForbidden,
+			wantBody:    "Access Denied: User (certmgr) may not modify objects in partition (Prod)",
+		},
+		{
+			name:        "certificate manager cannot create profiles",
+			handler:     ClientSSLListHandler{},
+			method:      http.MethodPost,
+			credentials: certmgr,
+			body:        `{"name":"rbac","partition":"Common"}`,
+			wantStatus:  http.StatusUnauthorized,
+			wantBody:    "Authorization failed: user=certmgr",
+		},
+		{
+			name:        "guest can read profiles",
+			handler:     ClientSSLListHandler{},
+			method:      http.MethodGet,
+			credentials: viewer,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "guest cannot create keys",
+			handler:     CryptoKeyHandler{},
+			method:      http.MethodPost,
+			credentials: viewer,
+			body:        `{"command":"create","name":"/Common/guest.key"}`,
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "bad password",
+			handler:     UserListHandler{},
+			method:      http.MethodGet,
+			credentials: [2]string{"certmgr", "wrong"},
+			wantStatus:  http.StatusUnauthorized,
+			wantBody:    "bad authentication",
+		},
+		{
+			name:        "list users hides passwords",
+			handler:     UserListHandler{},
+			method:      http.MethodGet,
+			credentials: viewer,
+			wantStatus:  http.StatusOK,
+			wantBody:    `"name":"certmgr"`,
+		},
+		{
+			name:        "only admins create users",
+			handler:     UserListHandler{},
+			method:      http.MethodPost,
+			credentials: certmgr,
+			body:        `{"name":"other","password":"pass","partitionAccess":[{"name":"all-partitions","role":"admin"}]}`,
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "partition admins cannot create users",
+			handler:     UserListHandler{},
+			method:      http.MethodPost,
+			credentials: prodAdmin,
+			body:        `{"name":"other","password":"pass","partitionAccess":[{"name":"all-partitions","role":"admin"}]}`,
+			wantStatus:  http.StatusUnauthorized,
+			wantBody:    "Authorization failed: user=prodadmin",
+		},
+		{
+			name:        "partition admins cannot create partitions",
+			handler:     PartitionListHandler{},
+			method:      http.MethodPost,
+			credentials: prodAdmin,
+			body:        `{"name":"Other"}`,
+			wantStatus:  http.StatusUnauthorized,
+			wantBody:    "Authorization failed: user=prodadmin",
+		},
+		{
+			name:        "partition admins cannot delete partitions",
+			handler:     PartitionHandler{},
+			method:      http.MethodDelete,
+			credentials: prodAdmin,
+			pathValue:   "Prod",
+			wantStatus:  http.StatusUnauthorized,
+			wantBody:    "Authorization failed: user=prodadmin",
+		},
+		{
+			name:        "admin role on a single partition",
+			handler:     UserListHandler{},
+			method:      http.MethodPost,
+			credentials: admin,
+			body:        `{"name":"ops","password":"opspass","partitionAccess":[{"name":"Prod","role":"admin"}]}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "The role (admin) can only be assigned to all partitions, not to partition (Prod).",
+		},
+		{
+			name:        "invalid role",
+			handler:     UserListHandler{},
+			method:      http.MethodPost,
+			credentials: admin,
+			body:        `{"name":"ops","password":"opspass","partitionAccess":[{"name":"Common","role":"superuser"}]}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "unknown partition",
+			handler:     UserListHandler{},
+			method:      http.MethodPost,
+			credentials: admin,
+			body:        `{"name":"ops","password":"opspass","partitionAccess":[{"name":"Missing","role":"manager"}]}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `The requested partition (Missing) was not found.`,
+		},
+		{
+			name:        "create user",
+			handler:     UserListHandler{},
+			method:      http.MethodPost,
+			credentials: admin,
+			body:        `{"name":"ops","password":"opspass","partitionAccess":[{"name":"Prod","role":"manager"}]}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `"partitionAccess":[{"name":"Prod","role":"manager"}]`,
+		},
+		{
+			name:        "created user can log in",
+			handler:     CryptoKeyHandler{},
+			method:      http.MethodPost,
+			credentials: [2]string{"ops", "opspass"},
+			body:        `{"command":"create","name":"/Prod/ops.key"}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "update role",
+			handler:     UserHandler{},
+			method:      http.MethodPatch,
+			credentials: admin,
+			pathValue:   "ops",
+			body:        `{"partitionAccess":[{"name":"Prod","role":"operator"}]}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `"role":"operator"`,
+		},
+		{
+			name:        "rejected patch",
+			handler:     UserHandler{},
+			method:      http.MethodPatch,
+			credentials: admin,
+			pathValue:   "ops",
+			body:        `{"partitionAccess":[{"name":"Prod","role":"superuser"}]}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "admin role on a single partition in a patch",
+			handler:     UserHandler{},
+			method:      http.MethodPatch,
+			credentials: admin,
+			pathValue:   "ops",
+			body:        `{"partitionAccess":[{"name":"Prod","role":"admin"}]}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "can only be assigned to all partitions",
+		},
+		{
+			name:        "rejected patch leaves roles unchanged",
+			handler:     UserHandler{},
+			method:      http.MethodGet,
+			credentials: admin,
+			pathValue:   "ops",
+			wantStatus:  http.StatusOK,
+			wantBody:    `"partitionAccess":[{"name":"Prod","role":"operator"}]`,
+		},
+		{
+			name:        "updated role is enforced",
+			handler:     CryptoKeyHandler{},
+			method:      http.MethodPost,
+			credentials: [2]string{"ops", "opspass"},
+			body:        `{"command":"create","name":"/Prod/ops2.key"}`,
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "delete user",
+			handler:     UserHandler{},
+			method:      http.MethodDelete,
+			credentials: admin,
+			pathValue:   "ops",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "deleted user cannot log in",
+			handler:     UserListHandler{},
+			method:      http.MethodGet,
+			credentials: [2]string{"ops", "opspass"},
+			wantStatus:  http.StatusUnauthorized,
+			wantBody:    "unknown username",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := F5HandlerWrapper{tt.handler, logger, &cfg}
+
+			req := httptest.NewRequest(tt.method, h.Route(), bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetPathValue("name", tt.pathValue)
+			req.SetBasicAuth(tt.credentials[0], tt.credentials[1])
+
+			rr := httptest.NewRecorder()
+			h.Handler()(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			require.Contains(t, rr.Body.String(), tt.wantBody)
+			require.NotContains(t, rr.Body.String(), `"password":`)
+		})
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	cfg := testConfig()
+
+	_, _ = cache.New("")
+
+	logger := log.New(true)
+	defer logger.Close()
+
+	cache.GlobalCache.PasswordPolicy = models.PasswordPolicy{
+		PolicyEnforcement: "enabled",
+		MinimumLength:     8,
+		RequiredNumeric:   1,
+		MaxLoginFailures:  2,
+	}
+	defer func() { cache.GlobalCache.PasswordPolicy = models.PasswordPolicy{} }()
+
+	guest := []models.PartitionAccess{{Name: models.AllPartitions, Role: models.RoleGuest}}
+	cache.GlobalCache.Users = []*models.User{
+		{Name: "locky", Password: "Passw0rd!", PartitionAccess: guest},
+		{Name: "expired", Password: "Old-pass1", PartitionAccess: guest, PasswordExpired: true},
+	}
+
+	admin := [2]string{cfg.Auth.AdminUsername, cfg.Auth.AdminPassword}
+
+	tests := []struct {
+		name        string
+		handler     F5Handler
+		method      string
+		path        string
+		credentials [2]string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:        "first failure",
+			handler:     UserListHandler{},
+			method:      http.MethodGet,
+			credentials: [2]string{"locky", "wrong"},
+			wantStatus:  http.StatusUnauthorized,
+			wantBody:    "bad authentication",
+		},
+		{
+			name:        "second failure locks the account",
+			handler:     UserListHandler{},
+			method:      http.MethodGet,
+			credentials: [2]string{"locky", "wrong"},
+			wantStatus:  http.StatusUnauthorized,
+			wantBody:    "bad authentication",
+		},
+		{
+			name:        "locked account",
+			handler:     UserListHandler{},
+			method:      http.MethodGet,
+			credentials: [2]string{"locky", "Passw0rd!"},
+			wantStatus:  http.StatusUnauthorized,
+			wantBody:    "account locky is locked",
+		},
+		{
+			name:        "lock is visible",
+			handler:     UserHandler{},
+			method:      http.MethodGet,
+			path:        "/mgmt/tm/auth/user/locky",
+			credentials: admin,
+			wantStatus:  http.StatusOK,
+			wantBody:    `"locked":true`,
+		},
+		{
+			name:        "admin unlocks",
+			handler:     UserHandler{},
+			method:      http.MethodPatch,
+			path:        "/mgmt/tm/auth/user/locky",
+			credentials: admin,
+			body:        `{"locked":false}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `"locked":false`,
+		},
+		{
+			name:        "unlocked account",
+			handler:     UserListHandler{},
+			method:      http.MethodGet,
+			credentials: [2]string{"locky", "Passw0rd!"},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "expired password",
+			handler:     UserListHandler{},
+			method:      http.MethodGet,
+			credentials: [2]string{"expired", "Old-pass1"},
+			wantStatus:  http.StatusUnauthorized,
+			wantBody:    "password expired, a password change is required",
+		},
+		{
+			name:        "new password must follow the policy",
+			handler:     UserHandler{},
+			method:      http.MethodPatch,
+			path:        "/mgmt/tm/auth/user/expired",
+			credentials: [2]string{"expired", "Old-pass1"},
+			body:        `{"password":"short"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "password must be at least 8 characters long",
+		},
+		{
+			name:        "only the password can be changed",
+			handler:     UserHandler{},
+			method:      http.MethodPatch,
+			path:        "/mgmt/tm/auth/user/expired",
+			credentials: [2]string{"expired", "Old-pass1"},
+			body:        `{"partitionAccess":[{"name":"all-partitions","role":"admin"}]}`,
+			wantStatus:  http.StatusForbidden,
+			wantBody:    "may only change its password",
+		},
+		{
+			name:        "change expired password",
+			handler:     UserHandler{},
+			method:      http.MethodPatch,
+			path:        "/mgmt/tm/auth/user/expired",
+			credentials: [2]string{"expired", "Old-pass1"},
+			body:        `{"password":"New-pass1"}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `"passwordExpired":false`,
+		},
+		{
+			name:        "new password works",
+			handler:     UserListHandler{},
+			method:      http.MethodGet,
+			credentials: [2]string{"expired", "New-pass1"},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "read policy",
+			handler:     PasswordPolicyHandler{},
+			method:      http.MethodGet,
+			credentials: [2]string{"locky", "Passw0rd!"},
+			wantStatus:  http.StatusOK,
+			wantBody:    `"maxLoginFailures":2`,
+		},
+		{
+			name:        "only admins update the policy",
+			handler:     PasswordPolicyHandler{},
+			method:      http.MethodPatch,
+			credentials: [2]string{"locky", "Passw0rd!"},
+			body:        `{"maxLoginFailures":0}`,
+			wantStatus:  http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := F5HandlerWrapper{tt.handler, logger, &cfg}
+
+			path := tt.path
+			if path == "" {
+				path = h.Route()
+			}
+
+			req := httptest.NewRequest(tt.method, path, bytes.NewBufferString(tt.body))
+			req.SetPathValue("name", strings.TrimPrefix(path, "/mgmt/tm/auth/user/"))
+			req.SetBasicAuth(tt.credentials[0], tt.credentials[1])
+
+			rr := httptest.NewRecorder()
+			h.Handler()(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			require.Contains(t, rr.Body.String(), tt.wantBody)
+		})
+	}
+
+	t.Run("password of another user", func(t *testing.T) {
+		h := F5HandlerWrapper{UserHandler{}, logger, &cfg}
+		// The authorization middleware lets users patch themselves, the handler checks the user it updates
+		req := httptest.NewRequest(http.MethodPatch, "/mgmt/tm/auth/user/expired", bytes.NewBufferString(`{"password":"Hijacked1"}`))
+		req.SetPathValue("name", "locky")
+		req.SetBasicAuth("expired", "New-pass1")
+
+		rr := httptest.NewRecorder()
+		h.Handler()(rr, req)
+
+		require.Equal(t, http.StatusForbidden, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), "Access Denied: User (expired) may only change its password")
+		require.Equal(t, "Passw0rd!", cache.GlobalCache.Users[0].Password)
+	})
+
+	t.Run("login of a locked account", func(t *testing.T) {
+		cache.GlobalCache.Users[0].Locked = true
+
+		h := F5HandlerWrapper{LoginHandler{}, logger, &cfg}
+		req := httptest.NewRequest(http.MethodPost, h.Route(), bytes.NewBufferString(`{"username":"locky","password":"Passw0rd!","loginProvider":"tmos"}`))
+
+		rr := httptest.NewRecorder()
+		h.Handler()(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "account locky is locked")
+	})
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/internal/shell"
+	"github.com/iilun/f5-mock/pkg/cache"
+)
+
+// UtilBashHandler runs bash -c scripts in the emulated shell, whose commands operate on the mock store.
+// Nothing is executed on the host.
+type UtilBashHandler struct{}
+
+func (h UtilBashHandler) Route() string {
+	return "/mgmt/tm/util/bash"
+}
+
+func (h UtilBashHandler) Handler() http.HandlerFunc {
+	return authenticatedRequestMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				f5Error(w, r, http.StatusMethodNotAllowed, "only POST allowed")
+				return
+			}
+
+			// File commands reach the stores of every partition
+			user := userFromRequest(r)
+			if !isAdmin(user) {
+				f5Error(w, r, http.StatusForbidden, "Access Denied: User (%s) may not run bash", user.Name)
+				return
+			}
+
+			bodyBytes, err := io.ReadAll(r.Body)
+			if err != nil {
+				f5Error(w, r, http.StatusInternalServerError, "could not read request")
+				return
+			}
+
+			var req UtilBashRequest
+			err = json.Unmarshal(bodyBytes, &req)
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "invalid post request")
+				return
+			}
+
+			if req.Command != "run" {
+				f5Error(w, r, http.StatusBadRequest, "invalid command: %s, only run is supported", req.Command)
+				return
+			}
+
+			args, err := shell.SplitWords(req.UtilCmdArgs)
+			if err != nil {
+				f5Error(w, r, http.StatusBadRequest, "invalid utilCmdArgs: %v", err)
+				return
+			}
+
+			version, _ := r.Context().Value(log.ContextVersion).(string)
+			sh := shell.New(cache.GlobalCache, version)
+			sh.Requester = NewRequester(user)
+
+			var result string
+			switch {
+			case len(args) == 2 && args[0] == "-c":
+				logger := loggerFromRequest(r)
+				logger.Debug("Running bash script %q", args[1])
+				result, _ = sh.Run(args[1])
+			case len(args) == 0:
+				f5Error(w, r, http.StatusBadRequest, "utilCmdArgs is required")
+				return
+			default:
+				// bash reads scripts from files, which are not supported
+				result = "/bin/bash: " + args[0] + ": No such file or directory\n"
+			}
+
+			writeJSON(w, r, UtilBashResponse{
+				Kind:          "tm:util:bash:runstate",
+				Command:       req.Command,
+				UtilCmdArgs:   req.UtilCmdArgs,
+				CommandResult: result,
+			})
+		})
+}
+
+type UtilBashRequest struct {
+	Command     string `json:"command"`
+	UtilCmdArgs string `json:"utilCmdArgs"`
+}
+
+type UtilBashResponse struct {
+	Kind          string `json:"kind"`
+	Command       string `json:"command"`
+	UtilCmdArgs   string `json:"utilCmdArgs"`
+	CommandResult string `json:"commandResult,omitempty"`
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUtilBash(t *testing.T) {
+	cfg := testConfig()
+
+	_, _ = cache.New("")
+	_, _ = cache.GlobalCache.Fs.WriteFile("/shared/bash/app.crt", []byte("not a certificate"))
+	cache.GlobalCache.Users = []*models.User{
+		{Name: "guest", Password: "guest", PartitionAccess: []models.PartitionAccess{{Name: models.AllPartitions, Role: models.RoleGuest}}},
+		{Name: "prodadmin", Password: "prodadmin", PartitionAccess: []models.PartitionAccess{{Name: "Prod", Role: models.RoleAdmin}}},
+	}
+
+	logger := log.New(true)
+	defer logger.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		username   string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "run script",
+			method:     http.MethodPost,
+			body:       `{"command":"run","utilCmdArgs":"-c 'ls /shared/bash; tmsh show sys version | grep Product'"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"kind":"tm:util:bash:runstate","command":"run","utilCmdArgs":"-c 'ls /shared/bash; tmsh show sys version | grep Product'","commandResult":"app.crt\n  Product   BIG-IP\n"}`,
+		},
+		{
+			name:       "failing command",
+			method:     http.MethodPost,
+			body:       `{"command":"run","utilCmdArgs":"-c \"openssl x509 -noout -text -in /shared/bash/app.crt\""}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"commandResult":"unable to load certificate\n"`,
+		},
+		{
+			name:       "unknown command",
+			method:     http.MethodPost,
+			body:       `{"command":"run","utilCmdArgs":"-c 'wget http://example.com'"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"commandResult":"/bin/bash: wget: command not found\n"`,
+		},
+		{
+			name:       "script file",
+			method:     http.MethodPost,
+			body:       `{"command":"run","utilCmdArgs":"/tmp/script.sh"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"commandResult":"/bin/bash: /tmp/script.sh: No such file or directory\n"`,
+		},
+		{
+			name:       "command without output",
+			method:     http.MethodPost,
+			body:       `{"command":"run","utilCmdArgs":"-c 'rm /shared/bash/app.crt'"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"utilCmdArgs":"-c 'rm /shared/bash/app.crt'"}`,
+		},
+		{
+			name:       "invalid command",
+			method:     http.MethodPost,
+			body:       `{"command":"load","utilCmdArgs":"-c 'ls'"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid command: load",
+		},
+		{
+			name:       "get",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "guest",
+			method:     http.MethodPost,
+			username:   "guest",
+			body:       `{"command":"run","utilCmdArgs":"-c 'ls'"}`,
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "Authorization failed: user=guest resource=/mgmt/tm/util/bash verb=POST",
+		},
+		{
+			name:       "partition admin",
+			method:     http.MethodPost,
+			username:   "prodadmin",
+			body:       `{"command":"run","utilCmdArgs":"-c 'ls'"}`,
+			wantStatus: http.StatusForbidden,
+			wantBody:   "Access Denied: User (prodadmin) may not run bash",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := F5HandlerWrapper{UtilBashHandler{}, logger, &cfg}
+
+			req := httptest.NewRequest(tt.method, h.Route(), bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.username)
+			} else {
+				req.SetBasicAuth(cfg.Auth.AdminUsername, cfg.Auth.AdminPassword)
+			}
+
+			rr := httptest.NewRecorder()
+			h.Handler()(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			require.Contains(t, rr.Body.String(), tt.wantBody)
+		})
+	}
+
+	require.False(t, cache.GlobalCache.Fs.Exists("/shared/bash/app.crt"))
+
+	cache.GlobalCache.Users = nil
+}
@@ -4,8 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"net/url"
+	"path"
 	"strings"
+
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
 )
 
 func checkContentType(r *http.Request, ct string) error {
@@ -15,18 +19,92 @@ func checkContentType(r *http.Request, ct string) error {
 	return nil
 }
 
-func parsePath(path string) (string, string, error) {
-	splitProfile := strings.Split(path, "~")
+// objectPath locates an object by its partition, the optional folders under the partition and its name
+type objectPath struct {
+	partition string
+	subPath   string
+	name      string
+}
+
+func (p objectPath) fullPath() string {
+	return models.FullPath(p.partition, p.subPath, p.name)
+}
+
+// folder returns the full path of the partition or folder holding the object
+func (p objectPath) folder() string {
+	return path.Join("/", p.partition, p.subPath)
+}
+
+// tildePath returns the ~partition~subPath~name form used in URLs
+func (p objectPath) tildePath() string {
+	return strings.ReplaceAll(p.fullPath(), "/", "~")
+}
 
-	if os.Getenv("F5_DEFAULT_PARTITION") != "" && len(splitProfile) == 1 {
-		return os.Getenv("F5_DEFAULT_PARTITION"), path, nil
+// parsePath parses an object path in the ~partition~folder~name or /partition/folder/name forms, URL encoded
+// or not. Relative paths belong to defaultPartition, or to Common when it is not set.
+func parsePath(value, defaultPartition string) (objectPath, error) {
+	unescaped, err := url.PathUnescape(value)
+	if err != nil {
+		return objectPath{}, fmt.Errorf("invalid path %s: %w", value, err)
 	}
 
-	if len(splitProfile) != 3 {
-		return "", "", errors.New("invalid path")
+	fullPath := strings.ReplaceAll(unescaped, "~", "/")
+	if !strings.HasPrefix(fullPath, "/") {
+		if defaultPartition == "" {
+			defaultPartition = cache.CommonPartition
+		}
+		fullPath = "/" + defaultPartition + "/" + fullPath
+	}
+
+	// Paths must be clean: empty segments are invalid, and . or .. segments would move the object out of the
+	// partition callers check
+	cleaned := path.Clean(fullPath)
+	segments := strings.Split(strings.TrimPrefix(cleaned, "/"), "/")
+	if cleaned != fullPath || len(segments) < 2 {
+		return objectPath{}, fmt.Errorf("invalid path %s", value)
+	}
+	return newObjectPath(segments), nil
+}
+
+// resolvePath returns the path of an object created with a name, which can be a full path, and an optional
+// partition and sub path. Objects without partition belong to defaultPartition, or to Common when it is not set.
+func resolvePath(name, partition, subPath, defaultPartition string) (objectPath, error) {
+	if strings.HasPrefix(name, "/") || strings.HasPrefix(name, "~") {
+		return parsePath(name, defaultPartition)
+	}
+
+	if partition == "" {
+		partition = defaultPartition
+	}
+	if partition == "" {
+		partition = cache.CommonPartition
+	}
+	return parsePath(models.FullPath(partition, subPath, name), defaultPartition)
+}
+
+// splitFilePath splits a path relative to a file store, or the name of a file object, names without
+// partition belonging to Common
+func splitFilePath(filePath string) objectPath {
+	cleaned := path.Clean("/" + filePath)
+	if path.Dir(cleaned) == "/" {
+		return objectPath{partition: cache.CommonPartition, name: path.Base(cleaned)}
 	}
+	return newObjectPath(strings.Split(strings.TrimPrefix(cleaned, "/"), "/"))
+}
 
-	return splitProfile[1], splitProfile[2], nil
+// storePath returns where the file object of a name is stored under a file store directory like /certs, names
+// without partition belonging to Common
+func storePath(store, name string) string {
+	return path.Join(store, splitFilePath(name).fullPath())
+}
+
+// newObjectPath returns the path of partition, folders and name segments
+func newObjectPath(segments []string) objectPath {
+	return objectPath{
+		partition: segments[0],
+		subPath:   path.Join(segments[1 : len(segments)-1]...),
+		name:      segments[len(segments)-1],
+	}
 }
 
 func parseAS3Path(path string) (string, string, error) {
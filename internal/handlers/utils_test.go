@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		name             string
+		value            string
+		defaultPartition string
+		want             objectPath
+		wantFullPath     string
+		wantErr          bool
+	}{
+		{
+			name:         "tilde form",
+			value:        "~Sample_02~profile",
+			want:         objectPath{partition: "Sample_02", name: "profile"},
+			wantFullPath: "/Sample_02/profile",
+		},
+		{
+			name:         "slash form",
+			value:        "/Sample_02/profile",
+			want:         objectPath{partition: "Sample_02", name: "profile"},
+			wantFullPath: "/Sample_02/profile",
+		},
+		{
+			name:         "URL encoded",
+			value:        "%7ECommon%7Eapp.app%7Eprofile",
+			want:         objectPath{partition: "Common", subPath: "app.app", name: "profile"},
+			wantFullPath: "/Common/app.app/profile",
+		},
+		{
+			name:         "nested folders",
+			value:        "~Common~a~b~profile",
+			want:         objectPath{partition: "Common", subPath: "a/b", name: "profile"},
+			wantFullPath: "/Common/a/b/profile",
+		},
+		{
+			name:         "name defaults to Common",
+			value:        "profile",
+			want:         objectPath{partition: "Common", name: "profile"},
+			wantFullPath: "/Common/profile",
+		},
+		{
+			name:             "name in the default partition",
+			value:            "profile",
+			defaultPartition: "Sample_02",
+			want:             objectPath{partition: "Sample_02", name: "profile"},
+			wantFullPath:     "/Sample_02/profile",
+		},
+		{
+			name:             "relative folder path",
+			value:            "app.app~profile",
+			defaultPartition: "Sample_02",
+			want:             objectPath{partition: "Sample_02", subPath: "app.app", name: "profile"},
+			wantFullPath:     "/Sample_02/app.app/profile",
+		},
+		{
+			name:    "partition only",
+			value:   "~Common",
+			wantErr: true,
+		},
+		{
+			name:    "empty segment",
+			value:   "~Common~~profile",
+			wantErr: true,
+		},
+		{
+			name:    "parent segment",
+			value:   "~Common~..~Prod~secret.crt",
+			wantErr: true,
+		},
+		{
+			name:    "URL encoded parent segment",
+			value:   "%7ECommon%7E..%7EProd%7Esecret.crt",
+			wantErr: true,
+		},
+		{
+			name:    "relative parent segment",
+			value:   "..~Prod~secret.crt",
+			wantErr: true,
+		},
+		{
+			name:    "current segment",
+			value:   "~Common~.~profile",
+			wantErr: true,
+		},
+		{
+			name:    "invalid escape",
+			value:   "~Common~%zz",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePath(tt.value, tt.defaultPartition)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantFullPath, got.fullPath())
+		})
+	}
+}
+
+func TestSplitFilePath(t *testing.T) {
+	require.Equal(t, objectPath{partition: "Common", name: "cert.pem"}, splitFilePath("cert.pem"))
+	require.Equal(t, objectPath{partition: "Sample_02", name: "cert.pem"}, splitFilePath("/Sample_02/cert.pem"))
+	require.Equal(t, objectPath{partition: "Sample_02", subPath: "app.app", name: "cert.pem"}, splitFilePath("Sample_02/app.app/cert.pem"))
+}
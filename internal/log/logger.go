@@ -67,3 +67,8 @@ func New(debug bool) Logger {
 
 	return Default
 }
+
+// Nop returns a logger discarding every message, for commands whose output would be mixed with logs
+func Nop() Logger {
+	return loggerImpl{zap.NewNop().Sugar()}
+}
@@ -0,0 +1,163 @@
+package reload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/iilun/f5-mock/internal/config"
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+)
+
+// restartFields are configuration fields only read on startup
+var restartFields = map[string]bool{
+	"Debug":           true,
+	"Listeners":       true,
+	"ShutdownTimeout": true,
+	"TLS":             true,
+	"ReloadInterval":  true,
+}
+
+// Reloader re-applies the configuration and seed files to the running mock
+type Reloader struct {
+	configPath string
+	config     *config.Config
+	logger     log.Logger
+	stamps     map[string]fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// New returns a Reloader updating cfg, which was loaded from configPath
+func New(configPath string, cfg *config.Config, logger log.Logger) *Reloader {
+	r := &Reloader{configPath: configPath, config: cfg, logger: logger}
+	r.changed()
+	return r
+}
+
+// Reload re-reads the configuration and seed files. Nothing is applied unless both are valid.
+func (r *Reloader) Reload() error {
+	cfg := *r.config
+	if r.configPath != "" {
+		var err error
+		cfg, err = config.Load(r.configPath)
+		if err != nil {
+			return fmt.Errorf("rejected configuration: %w", err)
+		}
+	}
+
+	var seed cache.SeedData
+	if cfg.SeedFile != "" {
+		var err error
+		seed, err = cache.LoadSeedData(cfg.SeedFile)
+		if err != nil {
+			return fmt.Errorf("rejected seed file: %w", err)
+		}
+		for _, warning := range seed.Warnings {
+			r.logger.Info("Seed file: %s", warning)
+		}
+	}
+
+	cache.GlobalCache.Lock()
+	defer cache.GlobalCache.Unlock()
+
+	changes := configChanges(*r.config, cfg)
+	if cfg.SeedFile != "" {
+		changes = append(changes, cache.GlobalCache.ApplySeed(seed, cfg.ReloadPolicy == config.ReloadPolicyReplace)...)
+
+		// The reloaded seed becomes the saved configuration
+		err := cache.GlobalCache.SaveConfig(cfg.DefaultVersion)
+		if err != nil {
+			return fmt.Errorf("could not save configuration: %w", err)
+		}
+	}
+	*r.config = cfg
+
+	if len(changes) == 0 {
+		r.logger.Info("Reloaded, nothing changed")
+	}
+	for _, change := range changes {
+		r.logger.Info("Reloaded: %s", change)
+	}
+
+	return nil
+}
+
+// Watch reloads on signals and, every interval, when the configuration or seed file changed, until ctx is done
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, signals <-chan os.Signal) {
+	var ticks <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-signals:
+			r.logger.Info("Received %s, reloading", sig)
+			r.changed()
+		case <-ticks:
+			if !r.changed() {
+				continue
+			}
+			r.logger.Info("Configuration or seed file changed, reloading")
+		}
+
+		err := r.Reload()
+		if err != nil {
+			r.logger.Error("%v, keeping the running state", err)
+		}
+	}
+}
+
+// changed reports whether the watched files changed since the last call
+func (r *Reloader) changed() bool {
+	stamps := map[string]fileStamp{}
+	for _, path := range []string{r.configPath, r.config.SeedFile} {
+		if path == "" {
+			continue
+		}
+		// Missing files are watched as zero stamps
+		info, err := os.Stat(path)
+		if err == nil {
+			stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		} else {
+			stamps[path] = fileStamp{}
+		}
+	}
+
+	changed := r.stamps != nil && !reflect.DeepEqual(stamps, r.stamps)
+	r.stamps = stamps
+	return changed
+}
+
+// configChanges describes the configuration fields that differ, without their values which may be secrets
+func configChanges(previous, next config.Config) []string {
+	var changes []string
+
+	previousValue := reflect.ValueOf(previous)
+	nextValue := reflect.ValueOf(next)
+	for i := range previousValue.NumField() {
+		field := previousValue.Type().Field(i)
+		if reflect.DeepEqual(previousValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			continue
+		}
+
+		change := fmt.Sprintf("updated configuration %s", field.Tag.Get("yaml"))
+		if restartFields[field.Name] {
+			change += ", a restart is required to apply it"
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
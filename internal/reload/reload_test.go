@@ -0,0 +1,168 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/iilun/f5-mock/internal/config"
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+const seedUsers = `
+users:
+  - name: seeded
+    password: secret
+    partition_access:
+      - name: Common
+        role: guest
+`
+
+func TestReload(t *testing.T) {
+	logger := log.New(true)
+	defer logger.Close()
+	t.Setenv("F5_ADMIN_PASSWORD", "password")
+
+	_, _ = cache.New("")
+
+	dir := t.TempDir()
+	seedPath := filepath.Join(dir, "seed.yaml")
+	configPath := filepath.Join(dir, "config.yaml")
+
+	guest := []models.PartitionAccess{{Name: "Common", Role: models.RoleGuest}}
+
+	tests := []struct {
+		name       string
+		config     string
+		seed       string
+		wantErr    string
+		wantUsers  []string
+		wantConfig func(t *testing.T, cfg config.Config)
+	}{
+		{
+			name:      "merge keeps runtime objects",
+			config:    "seed_file: " + seedPath + "\n",
+			seed:      seedUsers,
+			wantUsers: []string{"runtime", "seeded"},
+		},
+		{
+			name:      "replace drops runtime objects",
+			config:    "seed_file: " + seedPath + "\nreload_policy: replace\n",
+			seed:      seedUsers,
+			wantUsers: []string{"seeded"},
+		},
+		{
+			name:      "configuration changes are applied",
+			config:    "seed_file: " + seedPath + "\ndefault_partition: Common\nauth:\n  mode: both\n",
+			seed:      seedUsers,
+			wantUsers: []string{"runtime", "seeded"},
+			wantConfig: func(t *testing.T, cfg config.Config) {
+				require.Equal(t, "Common", cfg.DefaultPartition)
+				require.Equal(t, config.AuthModeBoth, cfg.Auth.Mode)
+			},
+		},
+		{
+			name:      "invalid seed is rejected",
+			config:    "seed_file: " + seedPath + "\ndefault_partition: Common\n",
+			seed:      "users:\n  - name: nopartition\n    password: secret\n",
+			wantErr:   "rejected seed file",
+			wantUsers: []string{"runtime"},
+		},
+		{
+			name:      "malformed seed is rejected",
+			config:    "seed_file: " + seedPath + "\n",
+			seed:      "users: [",
+			wantErr:   "rejected seed file",
+			wantUsers: []string{"runtime"},
+		},
+		{
+			name:      "invalid configuration is rejected",
+			config:    "seed_file: " + seedPath + "\nreload_policy: sometimes\n",
+			seed:      seedUsers,
+			wantErr:   "rejected configuration",
+			wantUsers: []string{"runtime"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache.GlobalCache.Users = []*models.User{{Name: "runtime", Password: "secret", PartitionAccess: guest}}
+
+			require.NoError(t, os.WriteFile(configPath, []byte(tt.config), 0o600))
+			require.NoError(t, os.WriteFile(seedPath, []byte(tt.seed), 0o600))
+
+			cfg := config.Default()
+			err := New(configPath, &cfg, logger).Reload()
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				// The running state is left untouched
+				require.Equal(t, config.Default(), cfg)
+			} else {
+				require.NoError(t, err)
+			}
+
+			var users []string
+			for _, user := range cache.GlobalCache.Users {
+				users = append(users, user.Name)
+			}
+			require.Equal(t, tt.wantUsers, users)
+
+			if tt.wantConfig != nil {
+				tt.wantConfig(t, cfg)
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	logger := log.New(true)
+	defer logger.Close()
+
+	_, _ = cache.New("")
+	cache.GlobalCache.Users = nil
+
+	seedPath := filepath.Join(t.TempDir(), "seed.yaml")
+	require.NoError(t, os.WriteFile(seedPath, []byte("users: []\n"), 0o600))
+
+	cfg := config.Default()
+	cfg.SeedFile = seedPath
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	go New("", &cfg, logger).Watch(ctx, 10*time.Millisecond, signals)
+
+	hasUser := func(name string) func() bool {
+		return func() bool {
+			cache.GlobalCache.Lock()
+			defer cache.GlobalCache.Unlock()
+			for _, user := range cache.GlobalCache.Users {
+				if user.Name == name {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	t.Run("file change", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(seedPath, []byte(seedUsers), 0o600))
+		require.Eventually(t, hasUser("seeded"), time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("signal", func(t *testing.T) {
+		cache.GlobalCache.Lock()
+		cache.GlobalCache.Users = nil
+		cache.GlobalCache.Unlock()
+
+		signals <- syscall.SIGHUP
+		require.Eventually(t, hasUser("seeded"), time.Second, 10*time.Millisecond)
+	})
+}
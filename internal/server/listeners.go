@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iilun/f5-mock/internal/config"
+	"github.com/iilun/f5-mock/internal/log"
+)
+
+func listen(l config.Listener) (net.Listener, error) {
+	if l.Scheme != config.SchemeUnix {
+		return net.Listen("tcp", l.Address)
+	}
+
+	// Remove a socket left over by a previous run
+	err := os.Remove(l.Address)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return net.Listen("unix", l.Address)
+}
+
+// Serve serves handler on all listeners until ctx is done, then drains in-flight requests
+// for at most shutdownTimeout. It returns early if a listener fails.
+func Serve(ctx context.Context, listeners []config.Listener, handler http.Handler, tlsConfig *tls.Config, shutdownTimeout time.Duration, logger log.Logger) error {
+	var servers []*http.Server
+	errs := make(chan error, len(listeners))
+
+	var serveErr error
+	for _, listener := range listeners {
+		ln, err := listen(listener)
+		if err != nil {
+			serveErr = fmt.Errorf("could not listen on %s: %w", listener, err)
+			break
+		}
+
+		srv := &http.Server{Handler: handler, TLSConfig: tlsConfig}
+		servers = append(servers, srv)
+
+		go func() {
+			var err error
+			if listener.Scheme == config.SchemeHTTPS {
+				// Certificates are provided by the TLS configuration
+				err = srv.ServeTLS(ln, "", "")
+			} else {
+				err = srv.Serve(ln)
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("%s: %w", listener, err)
+			}
+		}()
+
+		logger.Info("Listening on %s", listener)
+	}
+
+	if serveErr == nil {
+		select {
+		case <-ctx.Done():
+			logger.Info("Shutting down, draining in-flight requests")
+		case serveErr = <-errs:
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	shutdownErrs := make([]error, len(servers))
+	for i, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			shutdownErrs[i] = srv.Shutdown(shutdownCtx)
+		}()
+	}
+	wg.Wait()
+
+	for _, listener := range listeners {
+		if listener.Scheme == config.SchemeUnix {
+			_ = os.Remove(listener.Address)
+		}
+	}
+
+	return errors.Join(append([]error{serveErr}, shutdownErrs...)...)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iilun/f5-mock/internal/config"
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeDrainsRequests(t *testing.T) {
+	logger := log.New(true)
+	defer logger.Close()
+
+	// Reserve a free port
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	socket := filepath.Join(t.TempDir(), "f5.sock")
+
+	started := make(chan struct{}, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, []config.Listener{{Scheme: config.SchemeHTTP, Address: address}, {Scheme: config.SchemeUnix, Address: socket}}, handler, nil, time.Second, logger)
+	}()
+
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	// Both listeners serve at the same time
+	bodies := make(chan string, 2)
+	for _, client := range []*http.Client{http.DefaultClient, unixClient} {
+		go func() {
+			resp, err := client.Get("http://" + address + "/")
+			if err != nil {
+				bodies <- err.Error()
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			bodies <- string(body)
+		}()
+	}
+
+	<-started
+	<-started
+	cancel()
+
+	require.Equal(t, "done", <-bodies)
+	require.Equal(t, "done", <-bodies)
+	require.NoError(t, <-served)
+
+	_, err = net.Dial("tcp", address)
+	require.Error(t, err)
+}
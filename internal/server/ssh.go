@@ -0,0 +1,304 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/iilun/f5-mock/internal/crypto"
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/internal/shell"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshVersion is the identification of the SSH server of a BIG-IP
+const sshVersion = "SSH-2.0-OpenSSH_7.4"
+
+// SSHLogin checks the credentials of an SSH user, returning its shell
+type SSHLogin func(username, password string) (*shell.Shell, error)
+
+// LoadSSHHostKey reads the SSH host key, generating one when the file does not exist
+func LoadSSHHostKey(path string, logger log.Logger) (ssh.Signer, error) {
+	content, err := os.ReadFile(path)
+	if err == nil {
+		return ssh.ParsePrivateKey(content)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	content, err = crypto.GenerateKey(crypto.KeyTypeEC, 0, crypto.CurveP256)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate SSH host key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(content)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("%s not found, using a generated SSH host key with fingerprint %s", path, ssh.FingerprintSHA256(signer.PublicKey()))
+
+	return signer, nil
+}
+
+// ServeSSH gives the shells of authenticated users over SSH on address until ctx is done, then closes the open
+// connections. Sessions run tmsh interactively, exec commands, scp uploads and the sftp subsystem.
+func ServeSSH(ctx context.Context, address string, hostKey ssh.Signer, login SSHLogin, logger log.Logger) error {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("could not listen on ssh://%s: %w", address, err)
+	}
+	logger.Info("Listening on ssh://%s", address)
+
+	var mu sync.Mutex
+	conns := map[net.Conn]struct{}{}
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+		for conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("ssh://%s: %w", address, err)
+		}
+
+		mu.Lock()
+		conns[conn] = struct{}{}
+		mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveSSHConn(conn, hostKey, login, logger)
+
+			mu.Lock()
+			delete(conns, conn)
+			mu.Unlock()
+		}()
+	}
+}
+
+func serveSSHConn(conn net.Conn, hostKey ssh.Signer, login SSHLogin, logger log.Logger) {
+	defer conn.Close()
+
+	// The configuration is per connection, to keep the shell of the user authenticated on it
+	var sh *shell.Shell
+	config := &ssh.ServerConfig{
+		ServerVersion: sshVersion,
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			var err error
+			sh, err = login(meta.User(), string(password))
+			if err != nil {
+				logger.Info("SSH login of %s from %s failed: %v", meta.User(), meta.RemoteAddr(), err)
+			}
+			return nil, err
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	sshConn, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		logger.Debug("SSH handshake with %s failed: %v", conn.RemoteAddr(), err)
+		return
+	}
+	defer sshConn.Close()
+	logger.Debug("SSH login of %s from %s", sshConn.User(), sshConn.RemoteAddr())
+
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		// Sessions have their own working directory
+		session := *sh
+		go serveSSHSession(channel, channelRequests, &session, sshConn.User(), logger)
+	}
+}
+
+// serveSSHSession runs the shell, command or subsystem a session requests, sending its exit status
+func serveSSHSession(channel ssh.Channel, requests <-chan *ssh.Request, sh *shell.Shell, user string, logger log.Logger) {
+	defer channel.Close()
+
+	pty, started := false, false
+	run := func(f func() int) {
+		started = true
+		go func() {
+			status := 1
+			// A failing session must not stop the mock, which serves the API in the same process
+			defer func() {
+				if err := recover(); err != nil {
+					logger.Error("SSH session of %s failed: %v", user, err)
+				}
+				_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+				_ = channel.Close()
+			}()
+			status = f()
+		}()
+	}
+
+	// Requests are served until the channel is closed, or the connection would hang
+	for req := range requests {
+		var payload struct{ Value string }
+		switch {
+		case req.Type == "pty-req" && !started:
+			pty = true
+			_ = req.Reply(true, nil)
+		case req.Type == "env" || req.Type == "window-change":
+			_ = req.Reply(true, nil)
+		case req.Type == "shell" && !started:
+			_ = req.Reply(true, nil)
+			run(func() int {
+				var rw io.ReadWriter = channel
+				if pty {
+					rw = &terminal{channel: channel}
+				}
+				err := sh.InteractTmsh(rw, rw, user)
+				if err != nil {
+					logger.Debug("SSH shell of %s failed: %v", user, err)
+					return 1
+				}
+				return 0
+			})
+		case req.Type == "exec" && !started && ssh.Unmarshal(req.Payload, &payload) == nil:
+			_ = req.Reply(true, nil)
+			logger.Debug("Running SSH command %q of %s", payload.Value, user)
+			run(func() int {
+				return runSSHCommand(channel, sh, payload.Value, pty)
+			})
+		case req.Type == "subsystem" && !started && ssh.Unmarshal(req.Payload, &payload) == nil && payload.Value == "sftp":
+			_ = req.Reply(true, nil)
+			run(func() int {
+				err := sh.ServeSFTP(channel)
+				if err != nil {
+					logger.Debug("SFTP session of %s failed: %v", user, err)
+					return 1
+				}
+				return 0
+			})
+		default:
+			_ = req.Reply(false, nil)
+		}
+	}
+}
+
+// runSSHCommand runs the command of an exec request, scp uploads being received from the channel
+func runSSHCommand(channel ssh.Channel, sh *shell.Shell, command string, pty bool) int {
+	args, err := shell.SplitWords(command)
+	if err == nil && len(args) > 0 && args[0] == "scp" {
+		return sh.ReceiveSCP(channel, channel, args[1:])
+	}
+
+	var out io.Writer = channel
+	if pty {
+		out = &terminal{channel: channel}
+	}
+	output, status := sh.Exec(command)
+	_, _ = io.WriteString(out, output)
+	return status
+}
+
+// terminal gives echo and line editing to sessions with a pseudo terminal, and writes newlines as CRLF
+type terminal struct {
+	channel ssh.Channel
+	// line is the line being typed, pending the lines typed but not read yet
+	line    []byte
+	pending []byte
+	// escape is set while skipping an escape sequence, like those of arrow keys
+	escape  bool
+	eof     bool
+	afterCR bool
+}
+
+func (t *terminal) Read(p []byte) (int, error) {
+	buf := make([]byte, 256)
+	for len(t.pending) == 0 {
+		if t.eof {
+			return 0, io.EOF
+		}
+		n, err := t.channel.Read(buf)
+		t.input(buf[:n])
+		if err != nil && len(t.pending) == 0 {
+			return 0, err
+		}
+	}
+
+	n := copy(p, t.pending)
+	t.pending = t.pending[n:]
+	return n, nil
+}
+
+// input edits the line being typed with keystrokes
+func (t *terminal) input(keys []byte) {
+	var echo strings.Builder
+	for _, key := range keys {
+		afterCR := t.afterCR
+		t.afterCR = key == '\r'
+
+		switch {
+		case t.eof:
+			return
+		case t.escape:
+			// Sequences end with a letter, their brackets being skipped too
+			t.escape = key == '[' || key == 'O' || !(key >= 0x40 && key <= 0x7e)
+		case key == 0x1b:
+			t.escape = true
+		case key == '\n' && afterCR:
+		case key == '\r' || key == '\n':
+			echo.WriteString("\r\n")
+			t.pending = append(append(t.pending, t.line...), '\n')
+			t.line = t.line[:0]
+		case key == 0x7f || key == '\b':
+			if len(t.line) > 0 {
+				t.line = t.line[:len(t.line)-1]
+				echo.WriteString("\b \b")
+			}
+		case key == 0x03:
+			// Ctrl-C discards the line
+			echo.WriteString("^C\r\n")
+			t.pending = append(t.pending, '\n')
+			t.line = t.line[:0]
+		case key == 0x04:
+			// Ctrl-D ends the input on an empty line
+			if len(t.line) == 0 {
+				t.eof = true
+			}
+		case key >= 0x20:
+			t.line = append(t.line, key)
+			echo.WriteByte(key)
+		}
+	}
+	_, _ = io.WriteString(t.channel, echo.String())
+}
+
+func (t *terminal) Write(p []byte) (int, error) {
+	_, err := io.WriteString(t.channel, strings.ReplaceAll(string(p), "\n", "\r\n"))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/internal/shell"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestServeSSH(t *testing.T) {
+	logger := log.New(true)
+	defer logger.Close()
+
+	store, err := cache.New("")
+	require.NoError(t, err)
+
+	login := func(username, password string) (*shell.Shell, error) {
+		if password != "password" {
+			return nil, errors.New("Authentication failed")
+		}
+		sh := shell.New(store, "17.0.0.0")
+		sh.Locker = store
+		sh.Restricted = username != "admin"
+		return sh, nil
+	}
+
+	hostKey, err := LoadSSHHostKey(filepath.Join(t.TempDir(), "missing"), logger)
+	require.NoError(t, err)
+
+	// Reserve a free port
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeSSH(ctx, address, hostKey, login, logger)
+	}()
+
+	dial := func(user, password string) (*ssh.Client, error) {
+		config := &ssh.ClientConfig{
+			User:            user,
+			Auth:            []ssh.AuthMethod{ssh.Password(password)},
+			HostKeyCallback: ssh.FixedHostKey(hostKey.PublicKey()),
+			Timeout:         time.Second,
+		}
+		var client *ssh.Client
+		var err error
+		require.Eventually(t, func() bool {
+			client, err = ssh.Dial("tcp", address, config)
+			return err == nil || !strings.Contains(err.Error(), "connection refused")
+		}, time.Second, 10*time.Millisecond)
+		return client, err
+	}
+
+	_, err = dial("admin", "wrong")
+	require.ErrorContains(t, err, "unable to authenticate")
+
+	admin, err := dial("admin", "password")
+	require.NoError(t, err)
+	defer admin.Close()
+	operator, err := dial("operator", "password")
+	require.NoError(t, err)
+	defer operator.Close()
+
+	t.Run("exec", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			client     *ssh.Client
+			command    string
+			wantStatus int
+			want       string
+		}{
+			{
+				name:    "bash",
+				client:  admin,
+				command: "echo hello && pwd",
+				want:    "hello\n/\n",
+			},
+			{
+				name:       "exit status",
+				client:     admin,
+				command:    "ls /missing",
+				wantStatus: 2,
+				want:       "ls: cannot access '/missing': No such file or directory\n",
+			},
+			{
+				name:    "tmsh",
+				client:  operator,
+				command: "tmsh show sys version",
+				want:    "\nSys::Version\nMain Package\n  Product   BIG-IP\n  Version   17.0.0\n  Build     0.0.1\n  Edition   Final\n\n",
+			},
+			{
+				name:       "restricted",
+				client:     operator,
+				command:    "ls /",
+				wantStatus: 1,
+				want:       "Syntax Error: \"ls\" unknown command\n",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				session, err := tt.client.NewSession()
+				require.NoError(t, err)
+				defer session.Close()
+
+				output, err := session.CombinedOutput(tt.command)
+				if tt.wantStatus == 0 {
+					require.NoError(t, err)
+				} else {
+					var exitErr *ssh.ExitError
+					require.ErrorAs(t, err, &exitErr)
+					require.Equal(t, tt.wantStatus, exitErr.ExitStatus())
+				}
+				require.Equal(t, tt.want, string(output))
+			})
+		}
+	})
+
+	t.Run("interactive tmsh", func(t *testing.T) {
+		session, err := operator.NewSession()
+		require.NoError(t, err)
+		defer session.Close()
+
+		require.NoError(t, session.RequestPty("xterm", 24, 80, ssh.TerminalModes{}))
+		stdin, err := session.StdinPipe()
+		require.NoError(t, err)
+		stdout, err := session.StdoutPipe()
+		require.NoError(t, err)
+		require.NoError(t, session.Shell())
+
+		output := bufio.NewReader(stdout)
+		prompt := "operator@(localhost)(cfg-sync Standalone)(Active)(/Common)(tmos)# "
+		expect := func(want string) {
+			got := make([]byte, len(want))
+			_, err := io.ReadFull(output, got)
+			require.NoError(t, err)
+			require.Equal(t, want, string(got))
+		}
+
+		// Arrow keys are ignored and backspaces erase
+		expect(prompt)
+		_, err = io.WriteString(stdin, "list ltm poolx\x7f\x1b[A\r")
+		require.NoError(t, err)
+		expect("list ltm poolx\b \b\r\n" + prompt)
+
+		_, err = io.WriteString(stdin, "bash\r")
+		require.NoError(t, err)
+		expect("bash\r\nAccess denied: user (operator) does not have bash access\r\n" + prompt)
+
+		_, err = io.WriteString(stdin, "quit\r")
+		require.NoError(t, err)
+		expect("quit\r\n")
+		require.NoError(t, session.Wait())
+	})
+
+	t.Run("scp", func(t *testing.T) {
+		session, err := admin.NewSession()
+		require.NoError(t, err)
+		defer session.Close()
+
+		stdin, err := session.StdinPipe()
+		require.NoError(t, err)
+		stdout, err := session.StdoutPipe()
+		require.NoError(t, err)
+		require.NoError(t, session.Start("scp -t /var/config/rest/downloads/ssh/"))
+
+		acks := bufio.NewReader(stdout)
+		ack := func() {
+			b, err := acks.ReadByte()
+			require.NoError(t, err)
+			require.Equal(t, byte(0), b)
+		}
+		ack()
+		_, err = io.WriteString(stdin, "C0644 5 app.crt\n")
+		require.NoError(t, err)
+		ack()
+		_, err = io.WriteString(stdin, "hello\x00")
+		require.NoError(t, err)
+		ack()
+		require.NoError(t, stdin.Close())
+		require.NoError(t, session.Wait())
+
+		store.Lock()
+		defer store.Unlock()
+		content, err := store.Fs.ReadFile("/var/config/rest/downloads/ssh/app.crt")
+		require.NoError(t, err)
+		require.Equal(t, "hello", string(content))
+	})
+
+	t.Run("sftp", func(t *testing.T) {
+		session, err := admin.NewSession()
+		require.NoError(t, err)
+		defer session.Close()
+
+		stdin, err := session.StdinPipe()
+		require.NoError(t, err)
+		stdout, err := session.StdoutPipe()
+		require.NoError(t, err)
+		require.NoError(t, session.RequestSubsystem("sftp"))
+
+		_, err = stdin.Write([]byte{0, 0, 0, 5, 1, 0, 0, 0, 3})
+		require.NoError(t, err)
+		version := make([]byte, 9)
+		_, err = io.ReadFull(stdout, version)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0, 0, 0, 5, 2, 0, 0, 0, 3}, version)
+		require.NoError(t, stdin.Close())
+
+		rest, err := io.ReadAll(stdout)
+		require.NoError(t, err)
+		require.Empty(t, rest)
+	})
+
+	cancel()
+	require.NoError(t, <-served)
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/iilun/f5-mock/internal/config"
+	"github.com/iilun/f5-mock/internal/crypto"
+	"github.com/iilun/f5-mock/internal/log"
+)
+
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewTLSConfig builds the management interface TLS configuration,
+// generating a self-signed certificate when the configured one does not exist
+func NewTLSConfig(settings config.TLS, logger log.Logger) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		MaxVersion: tls.VersionTLS13,
+	}
+
+	var err error
+	if settings.MinVersion != "" {
+		config.MinVersion, err = parseTLSVersion(settings.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if settings.MaxVersion != "" {
+		config.MaxVersion, err = parseTLSVersion(settings.MaxVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.MinVersion > config.MaxVersion {
+		return nil, fmt.Errorf("minimum TLS version %s is above maximum TLS version %s", settings.MinVersion, settings.MaxVersion)
+	}
+
+	for _, name := range settings.Ciphers {
+		id, err := parseCipherSuite(name)
+		if err != nil {
+			return nil, err
+		}
+		config.CipherSuites = append(config.CipherSuites, id)
+	}
+
+	certificate, err := loadCertificate(settings.CertPath, settings.KeyPath, logger)
+	if err != nil {
+		return nil, err
+	}
+	config.Certificates = []tls.Certificate{certificate}
+
+	clientAuth := settings.ClientAuth
+	if clientAuth == "" {
+		clientAuth = ClientAuthNone
+		if settings.ClientCAPath != "" {
+			clientAuth = ClientAuthOptional
+		}
+	}
+
+	switch clientAuth {
+	case ClientAuthNone:
+		config.ClientAuth = tls.NoClientCert
+		return config, nil
+	case ClientAuthOptional:
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("invalid client auth %s", clientAuth)
+	}
+
+	if settings.ClientCAPath == "" {
+		return nil, fmt.Errorf("a client CA is required for client auth %s", clientAuth)
+	}
+
+	caContents, err := os.ReadFile(settings.ClientCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read client CA: %w", err)
+	}
+
+	config.ClientCAs = x509.NewCertPool()
+	if !config.ClientCAs.AppendCertsFromPEM(caContents) {
+		return nil, fmt.Errorf("no certificate found in client CA %s", settings.ClientCAPath)
+	}
+
+	return config, nil
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	id, ok := tlsVersions[strings.TrimPrefix(version, "TLSv")]
+	if !ok {
+		return 0, fmt.Errorf("invalid TLS version %s", version)
+	}
+	return id, nil
+}
+
+func parseCipherSuite(name string) (uint16, error) {
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if suite.Name == name {
+			return suite.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown cipher suite %s", name)
+}
+
+func loadCertificate(certPath, keyPath string, logger log.Logger) (tls.Certificate, error) {
+	_, err := os.Stat(certPath)
+	if err == nil {
+		return tls.LoadX509KeyPair(certPath, keyPath)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return tls.Certificate{}, err
+	}
+
+	certificate, err := generateSelfSignedCertificate()
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not generate management certificate: %w", err)
+	}
+
+	logger.Info("%s not found, using a generated self-signed certificate with fingerprint %s", certPath, crypto.Fingerprint(certificate.Leaf))
+
+	return certificate, nil
+}
+
+// generateSelfSignedCertificate mimics the default BIG-IP management certificate
+func generateSelfSignedCertificate() (tls.Certificate, error) {
+	keyPEM, err := crypto.GenerateKey(crypto.KeyTypeRSA, 2048, "")
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	sans, err := crypto.ParseSubjectAlternativeNames("DNS:localhost.localdomain, DNS:localhost, IP:127.0.0.1, IP:::1")
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	subject := crypto.Subject{
+		CommonName:         "localhost.localdomain",
+		Country:            "--",
+		State:              "WA",
+		City:               "Seattle",
+		Organization:       "MyCompany",
+		OrganizationalUnit: "IT",
+	}
+
+	certPEM, err := crypto.CreateSelfSignedCertificate(keyPEM, subject, sans, 10*365*24*time.Hour)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	// Leaf is populated by X509KeyPair
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iilun/f5-mock/internal/config"
+	"github.com/iilun/f5-mock/internal/crypto"
+	"github.com/iilun/f5-mock/internal/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	logger := log.New(true)
+	defer logger.Close()
+
+	dir := t.TempDir()
+
+	key, err := crypto.GenerateKey(crypto.KeyTypeRSA, 2048, "")
+	require.NoError(t, err)
+	cert, err := crypto.CreateSelfSignedCertificate(key, crypto.Subject{CommonName: "mgmt"}, crypto.SubjectAlternativeNames{}, time.Hour)
+	require.NoError(t, err)
+
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certPath, cert, 0o600))
+	require.NoError(t, os.WriteFile(keyPath, key, 0o600))
+
+	missingPath := filepath.Join(dir, "missing.pem")
+
+	tests := []struct {
+		name     string
+		settings config.TLS
+		wantErr  string
+		check    func(t *testing.T, config *tls.Config)
+	}{
+		{
+			name:     "defaults",
+			settings: config.TLS{CertPath: certPath, KeyPath: keyPath},
+			check: func(t *testing.T, config *tls.Config) {
+				require.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+				require.Equal(t, uint16(tls.VersionTLS13), config.MaxVersion)
+				require.Equal(t, tls.NoClientCert, config.ClientAuth)
+				require.Equal(t, "mgmt", config.Certificates[0].Leaf.Subject.CommonName)
+			},
+		},
+		{
+			name:     "generated certificate",
+			settings: config.TLS{CertPath: missingPath, KeyPath: missingPath},
+			check: func(t *testing.T, config *tls.Config) {
+				require.Equal(t, "localhost.localdomain", config.Certificates[0].Leaf.Subject.CommonName)
+			},
+		},
+		{
+			name:     "versions and ciphers",
+			settings: config.TLS{CertPath: certPath, KeyPath: keyPath, MinVersion: "1.2", MaxVersion: "TLSv1.2", Ciphers: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}},
+			check: func(t *testing.T, config *tls.Config) {
+				require.Equal(t, uint16(tls.VersionTLS12), config.MaxVersion)
+				require.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, config.CipherSuites)
+			},
+		},
+		{
+			name:     "invalid version",
+			settings: config.TLS{CertPath: certPath, KeyPath: keyPath, MinVersion: "1.4"},
+			wantErr:  "invalid TLS version 1.4",
+		},
+		{
+			name:     "inverted versions",
+			settings: config.TLS{CertPath: certPath, KeyPath: keyPath, MinVersion: "1.3", MaxVersion: "1.2"},
+			wantErr:  "minimum TLS version 1.3 is above maximum TLS version 1.2",
+		},
+		{
+			name:     "unknown cipher",
+			settings: config.TLS{CertPath: certPath, KeyPath: keyPath, Ciphers: []string{"ECDHE-RSA-AES128-GCM-SHA256"}},
+			wantErr:  "unknown cipher suite ECDHE-RSA-AES128-GCM-SHA256",
+		},
+		{
+			name:     "client CA enables optional client auth",
+			settings: config.TLS{CertPath: certPath, KeyPath: keyPath, ClientCAPath: certPath},
+			check: func(t *testing.T, config *tls.Config) {
+				require.Equal(t, tls.VerifyClientCertIfGiven, config.ClientAuth)
+				require.NotNil(t, config.ClientCAs)
+			},
+		},
+		{
+			name:     "required client auth",
+			settings: config.TLS{CertPath: certPath, KeyPath: keyPath, ClientCAPath: certPath, ClientAuth: ClientAuthRequire},
+			check: func(t *testing.T, config *tls.Config) {
+				require.Equal(t, tls.RequireAndVerifyClientCert, config.ClientAuth)
+			},
+		},
+		{
+			name:     "client auth without CA",
+			settings: config.TLS{CertPath: certPath, KeyPath: keyPath, ClientAuth: ClientAuthRequire},
+			wantErr:  "a client CA is required for client auth require",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewTLSConfig(tt.settings, logger)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, config)
+		})
+	}
+}
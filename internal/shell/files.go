@@ -0,0 +1,261 @@
+package shell
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+func echo(s *Shell, args []string, io *stdio) int {
+	newline := true
+	if len(args) > 0 && args[0] == "-n" {
+		newline = false
+		args = args[1:]
+	}
+	io.stdout.WriteString(strings.Join(args, " "))
+	if newline {
+		io.stdout.WriteString("\n")
+	}
+	return 0
+}
+
+func pwd(s *Shell, args []string, io *stdio) int {
+	fmt.Fprintln(&io.stdout, s.Dir)
+	return 0
+}
+
+func cd(s *Shell, args []string, io *stdio) int {
+	if len(args) > 1 {
+		io.errorf("/bin/bash: line 1: cd: too many arguments")
+		return 1
+	}
+	dir := "/"
+	if len(args) == 1 {
+		dir = s.abs(args[0])
+	}
+	if !s.Store.Fs.IsDir(dir) {
+		if s.Store.Fs.Exists(dir) {
+			io.errorf("/bin/bash: line 1: cd: %s: Not a directory", args[0])
+		} else {
+			io.errorf("/bin/bash: line 1: cd: %s: No such file or directory", args[0])
+		}
+		return 1
+	}
+	s.Dir = dir
+	return 0
+}
+
+// children returns the names of the files and directories directly under dir
+func (s *Shell) children(dir string) []string {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	var names []string
+	for _, file := range s.Store.Fs.List(dir) {
+		name, _, _ := strings.Cut(strings.TrimPrefix(file, prefix), "/")
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func ls(s *Shell, args []string, io *stdio) int {
+	_, operands := splitFlags(args)
+	if len(operands) == 0 {
+		operands = []string{"."}
+	}
+
+	status := 0
+	var files, dirs []string
+	for _, operand := range operands {
+		p := s.abs(operand)
+		switch {
+		case s.Store.Fs.IsDir(p):
+			dirs = append(dirs, operand)
+		case s.Store.Fs.Exists(p):
+			files = append(files, operand)
+		default:
+			io.errorf("ls: cannot access '%s': No such file or directory", operand)
+			status = 2
+		}
+	}
+
+	// Like ls, files are listed first, then the content of each directory
+	for _, file := range files {
+		fmt.Fprintln(&io.stdout, file)
+	}
+	for i, dir := range dirs {
+		if len(operands) > 1 {
+			if i > 0 || len(files) > 0 {
+				io.stdout.WriteString("\n")
+			}
+			fmt.Fprintf(&io.stdout, "%s:\n", dir)
+		}
+		for _, name := range s.children(s.abs(dir)) {
+			fmt.Fprintln(&io.stdout, name)
+		}
+	}
+	return status
+}
+
+func cat(s *Shell, args []string, io *stdio) int {
+	_, operands := splitFlags(args)
+	if len(operands) == 0 {
+		io.stdout.WriteString(io.stdin)
+		return 0
+	}
+
+	status := 0
+	for _, operand := range operands {
+		if operand == "-" {
+			io.stdout.WriteString(io.stdin)
+			continue
+		}
+		content, ok := s.readFile("cat", operand, io)
+		if !ok {
+			status = 1
+			continue
+		}
+		io.stdout.Write(content)
+	}
+	return status
+}
+
+// readFile returns the content of a file, writing the error of the command otherwise
+func (s *Shell) readFile(name, operand string, io *stdio) ([]byte, bool) {
+	p := s.abs(operand)
+	if s.Store.Fs.IsDir(p) {
+		io.errorf("%s: %s: Is a directory", name, operand)
+		return nil, false
+	}
+	content, err := s.Store.Fs.ReadFile(p)
+	if err != nil {
+		io.errorf("%s: %s: No such file or directory", name, operand)
+		return nil, false
+	}
+	return content, true
+}
+
+func rm(s *Shell, args []string, io *stdio) int {
+	flags, operands := splitFlags(args)
+	force := strings.ContainsRune(flags, 'f')
+	recursive := strings.ContainsAny(flags, "rR")
+
+	if len(operands) == 0 {
+		if force {
+			return 0
+		}
+		io.errorf("rm: missing operand")
+		io.errorf("Try 'rm --help' for more information.")
+		return 1
+	}
+
+	status := 0
+	for _, operand := range operands {
+		p := s.abs(operand)
+		switch {
+		case p == "/":
+			io.errorf("rm: it is dangerous to operate recursively on '/'")
+			io.errorf("rm: use --no-preserve-root to override this failsafe")
+			status = 1
+		case s.Store.Fs.IsDir(p):
+			if !recursive {
+				io.errorf("rm: cannot remove '%s': Is a directory", operand)
+				status = 1
+				continue
+			}
+			for _, file := range s.Store.Fs.List(p) {
+				_ = s.Store.Fs.Remove(file)
+			}
+		case s.Store.Fs.Exists(p):
+			_ = s.Store.Fs.Remove(p)
+		case !force:
+			io.errorf("rm: cannot remove '%s': No such file or directory", operand)
+			status = 1
+		}
+	}
+	return status
+}
+
+func mv(s *Shell, args []string, io *stdio) int {
+	return transfer(s, "mv", args, io)
+}
+
+func cp(s *Shell, args []string, io *stdio) int {
+	return transfer(s, "cp", args, io)
+}
+
+// transfer moves or copies files, into the last operand when it is a directory
+func transfer(s *Shell, name string, args []string, io *stdio) int {
+	flags, operands := splitFlags(args)
+	recursive := name == "mv" || strings.ContainsAny(flags, "rRa")
+
+	switch len(operands) {
+	case 0:
+		io.errorf("%s: missing file operand", name)
+		io.errorf("Try '%s --help' for more information.", name)
+		return 1
+	case 1:
+		io.errorf("%s: missing destination file operand after '%s'", name, operands[0])
+		io.errorf("Try '%s --help' for more information.", name)
+		return 1
+	}
+
+	target := operands[len(operands)-1]
+	sources := operands[:len(operands)-1]
+	targetDir := s.Store.Fs.IsDir(s.abs(target))
+	if len(sources) > 1 && !targetDir {
+		io.errorf("%s: target '%s' is not a directory", name, target)
+		return 1
+	}
+
+	status := 0
+	for _, source := range sources {
+		src := s.abs(source)
+		dst := s.abs(target)
+		if targetDir {
+			dst = path.Join(dst, path.Base(src))
+		}
+
+		var files []string
+		switch {
+		case s.Store.Fs.IsDir(src):
+			if !recursive {
+				io.errorf("%s: -r not specified; omitting directory '%s'", name, source)
+				status = 1
+				continue
+			}
+			if src == "/" || strings.HasPrefix(dst+"/", src+"/") {
+				io.errorf("%s: cannot %s '%s' to a subdirectory of itself, '%s'", name, verbs[name], source, dst)
+				status = 1
+				continue
+			}
+			files = s.Store.Fs.List(src)
+		case s.Store.Fs.Exists(src):
+			files = []string{src}
+		default:
+			io.errorf("%s: cannot stat '%s': No such file or directory", name, source)
+			status = 1
+			continue
+		}
+
+		for _, file := range files {
+			dstFile := dst + strings.TrimPrefix(file, src)
+			if s.Store.Fs.IsDir(dstFile) {
+				io.errorf("%s: cannot overwrite directory '%s' with non-directory", name, dstFile)
+				status = 1
+				continue
+			}
+			if name == "mv" {
+				_ = s.Store.Fs.Rename(file, dstFile)
+				continue
+			}
+			content, _ := s.Store.Fs.ReadFile(file)
+			_ = s.Store.Fs.Remove(dstFile)
+			_, _ = s.Store.Fs.WriteFile(dstFile, slices.Clone(content))
+		}
+	}
+	return status
+}
+
+var verbs = map[string]string{"mv": "move", "cp": "copy"}
@@ -0,0 +1,169 @@
+package shell
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// input returns the lines of the operands of a filter, or of its standard input without operands
+func (s *Shell) input(name string, operands []string, io *stdio) ([]string, bool) {
+	text := io.stdin
+	ok := true
+	if len(operands) > 0 {
+		var b strings.Builder
+		for _, operand := range operands {
+			content, read := s.readFile(name, operand, io)
+			ok = ok && read
+			b.Write(content)
+		}
+		text = b.String()
+	}
+
+	if text == "" {
+		return nil, ok
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n"), ok
+}
+
+func grep(s *Shell, args []string, io *stdio) int {
+	var pattern string
+	var operands []string
+	ignoreCase, invert, count := false, false, false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-e" && i+1 < len(args):
+			pattern = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for _, flag := range arg[1:] {
+				switch flag {
+				case 'i':
+					ignoreCase = true
+				case 'v':
+					invert = true
+				case 'c':
+					count = true
+				case 'E', 'F':
+				default:
+					io.errorf("grep: invalid option -- '%c'", flag)
+					return 2
+				}
+			}
+		case pattern == "":
+			pattern = arg
+		default:
+			operands = append(operands, arg)
+		}
+	}
+	if pattern == "" {
+		io.errorf("Usage: grep [OPTION]... PATTERN [FILE]...")
+		return 2
+	}
+
+	lines, ok := s.input("grep", operands, io)
+	if ignoreCase {
+		pattern = strings.ToLower(pattern)
+	}
+
+	matched := 0
+	for _, line := range lines {
+		candidate := line
+		if ignoreCase {
+			candidate = strings.ToLower(line)
+		}
+		if strings.Contains(candidate, pattern) != invert {
+			matched++
+			if !count {
+				fmt.Fprintln(&io.stdout, line)
+			}
+		}
+	}
+	if count {
+		fmt.Fprintln(&io.stdout, matched)
+	}
+
+	switch {
+	case !ok:
+		return 2
+	case matched == 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func head(s *Shell, args []string, io *stdio) int {
+	return slice(s, "head", args, io)
+}
+
+func tail(s *Shell, args []string, io *stdio) int {
+	return slice(s, "tail", args, io)
+}
+
+// slice writes the first or last lines of its input, 10 by default
+func slice(s *Shell, name string, args []string, io *stdio) int {
+	n := 10
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value := ""
+		switch {
+		case arg == "-n" && i+1 < len(args):
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "-n"):
+			value = arg[2:]
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			value = arg[1:]
+		default:
+			operands = append(operands, arg)
+			continue
+		}
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			io.errorf("%s: invalid number of lines: '%s'", name, value)
+			return 1
+		}
+		n = count
+	}
+
+	lines, ok := s.input(name, operands, io)
+	n = min(n, len(lines))
+	if name == "head" {
+		lines = lines[:n]
+	} else {
+		lines = lines[len(lines)-n:]
+	}
+	for _, line := range lines {
+		fmt.Fprintln(&io.stdout, line)
+	}
+
+	if !ok {
+		return 1
+	}
+	return 0
+}
+
+func wc(s *Shell, args []string, io *stdio) int {
+	flags, operands := splitFlags(args)
+	lines, ok := s.input("wc", operands, io)
+
+	words, chars := 0, 0
+	for _, line := range lines {
+		words += len(strings.Fields(line))
+		chars += len(line) + 1
+	}
+	if strings.ContainsRune(flags, 'l') {
+		fmt.Fprintln(&io.stdout, len(lines))
+	} else {
+		fmt.Fprintf(&io.stdout, "%7d %7d %7d\n", len(lines), words, chars)
+	}
+
+	if !ok {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,143 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+const (
+	// tmshPrompt is the prompt of an interactive tmsh on a standalone device
+	tmshPrompt = "%s@(localhost)(cfg-sync Standalone)(Active)(/Common)(tmos)# "
+	// bashPrompt is the prompt of bash on a standalone device, followed by the working directory
+	bashPrompt = "[%s@localhost:Active:Standalone] %s # "
+)
+
+// InteractTmsh reads tmsh commands line by line until quit, exit or the end of the input, writing their output
+// after a prompt for user. bash and run util bash start an interactive bash, which exit returns from.
+func (s *Shell) InteractTmsh(in io.Reader, out io.Writer, user string) error {
+	err := s.interactTmsh(bufio.NewScanner(in), out, user)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// Exec runs the command of a remote session, as a bash script or as a tmsh command in restricted shells, returning
+// its output and exit status
+func (s *Shell) Exec(command string) (string, int) {
+	if !s.Restricted {
+		defer s.lock()()
+		return s.Run(command)
+	}
+
+	args, err := SplitWords(command)
+	if err != nil {
+		return fmt.Sprintf("Syntax Error: %v\n", err), 1
+	}
+	// Restricted users log in to tmsh, commands may still name it like in bash
+	if len(args) > 0 && args[0] == "tmsh" {
+		args = args[1:]
+	}
+	defer s.lock()()
+	return s.RunTmsh(args)
+}
+
+// lock takes the Locker, if any, returning the function releasing it
+func (s *Shell) lock() (unlock func()) {
+	if s.Locker == nil {
+		return func() {}
+	}
+	s.Locker.Lock()
+	return s.Locker.Unlock
+}
+
+func (s *Shell) interactTmsh(lines *bufio.Scanner, out io.Writer, user string) error {
+	for {
+		line, err := prompt(lines, out, fmt.Sprintf(tmshPrompt, user))
+		if err != nil {
+			return err
+		}
+
+		var output string
+		args, err := SplitWords(line)
+		switch {
+		case err != nil:
+			output = fmt.Sprintf("Syntax Error: %v\n", err)
+		case len(args) == 0:
+			continue
+		case slices.Contains([]string{"quit", "exit", "q"}, args[0]):
+			return nil
+		case slices.Equal(args, []string{"bash"}) || slices.Equal(args, []string{"run", "util", "bash"}):
+			if s.Restricted {
+				output = fmt.Sprintf("Access denied: user (%s) does not have bash access\n", user)
+				break
+			}
+			err = s.interactBash(lines, out, user)
+			if err != nil {
+				return err
+			}
+		default:
+			unlock := s.lock()
+			output, _ = s.RunTmsh(args)
+			unlock()
+		}
+
+		_, err = io.WriteString(out, output)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Shell) interactBash(lines *bufio.Scanner, out io.Writer, user string) error {
+	for {
+		dir := path.Base(s.Dir)
+		line, err := prompt(lines, out, fmt.Sprintf(bashPrompt, user, dir))
+		if err != nil {
+			return err
+		}
+
+		switch strings.TrimSpace(line) {
+		case "exit", "logout":
+			return nil
+		case "tmsh":
+			err = s.interactTmsh(lines, out, user)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		unlock := s.lock()
+		output, _ := s.Run(line)
+		unlock()
+		_, err = io.WriteString(out, output)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// prompt writes a prompt then reads a line, returning io.EOF at the end of the input
+func prompt(lines *bufio.Scanner, out io.Writer, text string) (string, error) {
+	_, err := io.WriteString(out, text)
+	if err != nil {
+		return "", err
+	}
+	if !lines.Scan() {
+		_, err = io.WriteString(out, "\n")
+		if err != nil {
+			return "", err
+		}
+		if lines.Err() != nil {
+			return "", lines.Err()
+		}
+		return "", io.EOF
+	}
+	return lines.Text(), nil
+}
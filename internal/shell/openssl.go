@@ -0,0 +1,390 @@
+package shell
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iilun/f5-mock/internal/crypto"
+)
+
+// opensslDateFormat is the date format of openssl, always in GMT
+const opensslDateFormat = "Jan _2 15:04:05 2006 GMT"
+
+// openssl emulates the OpenSSL 1.0.2 of BIG-IP, only the version and x509 commands being supported
+func openssl(s *Shell, args []string, io *stdio) int {
+	if len(args) == 0 {
+		io.errorf("openssl: interactive mode is not supported")
+		return 1
+	}
+	switch args[0] {
+	case "version":
+		fmt.Fprintln(&io.stdout, "OpenSSL 1.0.2s-fips  28 May 2019")
+		return 0
+	case "x509":
+		return x509Command(s, args[1:], io)
+	default:
+		io.errorf("openssl:Error: '%s' is an invalid command.", args[0])
+		return 1
+	}
+}
+
+// x509Command prints the certificate of -in, or of the standard input, the outputs following the order of the
+// options like openssl does
+func x509Command(s *Shell, args []string, io *stdio) int {
+	var in string
+	var outputs []string
+	noout := false
+	digest := "SHA1"
+	checkend := -1
+
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "-in", "-checkend", "-inform":
+			if i+1 >= len(args) {
+				io.errorf("missing argument for %s", arg)
+				return 1
+			}
+			i++
+			switch arg {
+			case "-in":
+				in = args[i]
+			case "-checkend":
+				seconds, err := strconv.Atoi(args[i])
+				if err != nil || seconds < 0 {
+					io.errorf("x509: Checkend time %s invalid", args[i])
+					return 1
+				}
+				checkend = seconds
+			case "-inform":
+				if !strings.EqualFold(args[i], "PEM") {
+					io.errorf("x509: only PEM input is supported")
+					return 1
+				}
+			}
+		case "-noout":
+			noout = true
+		case "-sha1", "-sha256":
+			digest = strings.ToUpper(arg[1:])
+		case "-text", "-subject", "-issuer", "-dates", "-startdate", "-enddate", "-serial", "-fingerprint", "-pubkey":
+			outputs = append(outputs, arg)
+		default:
+			io.errorf("unknown option %s", arg)
+			io.errorf("usage: x509 args")
+			return 1
+		}
+	}
+
+	content := []byte(io.stdin)
+	if in != "" {
+		var err error
+		content, err = s.Store.Fs.ReadFile(s.abs(in))
+		if err != nil || s.Store.Fs.IsDir(s.abs(in)) {
+			io.errorf("Error opening Certificate %s", in)
+			io.errorf("unable to load certificate")
+			return 1
+		}
+	}
+	cert, err := crypto.ParsePemCertificate(content)
+	if err != nil {
+		io.errorf("unable to load certificate")
+		return 1
+	}
+
+	for _, output := range outputs {
+		switch output {
+		case "-text":
+			writeCertificateText(&io.stdout, cert)
+		case "-subject":
+			fmt.Fprintf(&io.stdout, "subject= %s\n", onelineName(cert.Subject))
+		case "-issuer":
+			fmt.Fprintf(&io.stdout, "issuer= %s\n", onelineName(cert.Issuer))
+		case "-dates":
+			fmt.Fprintf(&io.stdout, "notBefore=%s\n", cert.NotBefore.UTC().Format(opensslDateFormat))
+			fmt.Fprintf(&io.stdout, "notAfter=%s\n", cert.NotAfter.UTC().Format(opensslDateFormat))
+		case "-startdate":
+			fmt.Fprintf(&io.stdout, "notBefore=%s\n", cert.NotBefore.UTC().Format(opensslDateFormat))
+		case "-enddate":
+			fmt.Fprintf(&io.stdout, "notAfter=%s\n", cert.NotAfter.UTC().Format(opensslDateFormat))
+		case "-serial":
+			fmt.Fprintf(&io.stdout, "serial=%s\n", serialHex(cert.SerialNumber))
+		case "-fingerprint":
+			var sum []byte
+			if digest == "SHA256" {
+				sum256 := sha256.Sum256(cert.Raw)
+				sum = sum256[:]
+			} else {
+				sum1 := sha1.Sum(cert.Raw)
+				sum = sum1[:]
+			}
+			fmt.Fprintf(&io.stdout, "%s Fingerprint=%s\n", digest, strings.ToUpper(hexBytes(sum)))
+		case "-pubkey":
+			der, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+			if err == nil {
+				_ = pem.Encode(&io.stdout, &pem.Block{Type: "PUBLIC KEY", Bytes: der})
+			}
+		}
+	}
+
+	if !noout {
+		_ = pem.Encode(&io.stdout, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+
+	if checkend >= 0 {
+		if time.Now().Add(time.Duration(checkend) * time.Second).After(cert.NotAfter) {
+			fmt.Fprintln(&io.stdout, "Certificate will expire")
+			return 1
+		}
+		fmt.Fprintln(&io.stdout, "Certificate will not expire")
+	}
+	return 0
+}
+
+// attributeNames are the short names openssl gives to distinguished name attributes
+var attributeNames = map[string]string{
+	"2.5.4.3":              "CN",
+	"2.5.4.5":              "serialNumber",
+	"2.5.4.6":              "C",
+	"2.5.4.7":              "L",
+	"2.5.4.8":              "ST",
+	"2.5.4.9":              "street",
+	"2.5.4.10":             "O",
+	"2.5.4.11":             "OU",
+	"2.5.4.17":             "postalCode",
+	"1.2.840.113549.1.9.1": "emailAddress",
+}
+
+func nameAttributes(name pkix.Name) []string {
+	var attributes []string
+	for _, rdn := range name.ToRDNSequence() {
+		for _, atv := range rdn {
+			key, found := attributeNames[atv.Type.String()]
+			if !found {
+				key = atv.Type.String()
+			}
+			attributes = append(attributes, fmt.Sprintf("%s=%v", key, atv.Value))
+		}
+	}
+	return attributes
+}
+
+// onelineName formats a name like the -subject and -issuer options of OpenSSL 1.0.2
+func onelineName(name pkix.Name) string {
+	return "/" + strings.Join(nameAttributes(name), "/")
+}
+
+// textName formats a name like the -text option of OpenSSL 1.0.2
+func textName(name pkix.Name) string {
+	return strings.Join(nameAttributes(name), ", ")
+}
+
+func hexBytes(b []byte) string {
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02x", v)
+	}
+	return strings.Join(parts, ":")
+}
+
+// writeHexBlock writes bytes as colon separated hexadecimal, perLine bytes per line
+func writeHexBlock(b *strings.Builder, data []byte, indent string, perLine int) {
+	for i := 0; i < len(data); i += perLine {
+		line := hexBytes(data[i:min(i+perLine, len(data))])
+		if i+perLine < len(data) {
+			line += ":"
+		}
+		fmt.Fprintf(b, "%s%s\n", indent, line)
+	}
+}
+
+func serialHex(serial *big.Int) string {
+	text := strings.ToUpper(serial.Text(16))
+	if len(text)%2 == 1 {
+		text = "0" + text
+	}
+	return text
+}
+
+var signatureAlgorithms = map[x509.SignatureAlgorithm]string{
+	x509.MD5WithRSA:       "md5WithRSAEncryption",
+	x509.SHA1WithRSA:      "sha1WithRSAEncryption",
+	x509.SHA256WithRSA:    "sha256WithRSAEncryption",
+	x509.SHA384WithRSA:    "sha384WithRSAEncryption",
+	x509.SHA512WithRSA:    "sha512WithRSAEncryption",
+	x509.SHA256WithRSAPSS: "rsassaPss",
+	x509.SHA384WithRSAPSS: "rsassaPss",
+	x509.SHA512WithRSAPSS: "rsassaPss",
+	x509.ECDSAWithSHA1:    "ecdsa-with-SHA1",
+	x509.ECDSAWithSHA256:  "ecdsa-with-SHA256",
+	x509.ECDSAWithSHA384:  "ecdsa-with-SHA384",
+	x509.ECDSAWithSHA512:  "ecdsa-with-SHA512",
+}
+
+var keyUsages = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "Digital Signature"},
+	{x509.KeyUsageContentCommitment, "Non Repudiation"},
+	{x509.KeyUsageKeyEncipherment, "Key Encipherment"},
+	{x509.KeyUsageDataEncipherment, "Data Encipherment"},
+	{x509.KeyUsageKeyAgreement, "Key Agreement"},
+	{x509.KeyUsageCertSign, "Certificate Sign"},
+	{x509.KeyUsageCRLSign, "CRL Sign"},
+}
+
+var extKeyUsages = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "Any Extended Key Usage",
+	x509.ExtKeyUsageServerAuth:      "TLS Web Server Authentication",
+	x509.ExtKeyUsageClientAuth:      "TLS Web Client Authentication",
+	x509.ExtKeyUsageCodeSigning:     "Code Signing",
+	x509.ExtKeyUsageEmailProtection: "E-mail Protection",
+	x509.ExtKeyUsageTimeStamping:    "Time Stamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSP Signing",
+}
+
+// extension OIDs of the extensions printed by -text
+var (
+	oidSubjectKeyID     = asn1.ObjectIdentifier{2, 5, 29, 14}
+	oidKeyUsage         = asn1.ObjectIdentifier{2, 5, 29, 15}
+	oidSubjectAltName   = asn1.ObjectIdentifier{2, 5, 29, 17}
+	oidBasicConstraints = asn1.ObjectIdentifier{2, 5, 29, 19}
+	oidAuthorityKeyID   = asn1.ObjectIdentifier{2, 5, 29, 35}
+	oidExtKeyUsage      = asn1.ObjectIdentifier{2, 5, 29, 37}
+)
+
+// writeCertificateText writes the certificate like the -text option of OpenSSL 1.0.2
+func writeCertificateText(b *strings.Builder, cert *x509.Certificate) {
+	signature, found := signatureAlgorithms[cert.SignatureAlgorithm]
+	if !found {
+		signature = cert.SignatureAlgorithm.String()
+	}
+
+	b.WriteString("Certificate:\n")
+	b.WriteString("    Data:\n")
+	fmt.Fprintf(b, "        Version: %d (0x%x)\n", cert.Version, cert.Version-1)
+	if cert.SerialNumber.IsInt64() && cert.SerialNumber.Sign() >= 0 {
+		fmt.Fprintf(b, "        Serial Number: %d (0x%x)\n", cert.SerialNumber, cert.SerialNumber)
+	} else {
+		b.WriteString("        Serial Number:\n")
+		fmt.Fprintf(b, "            %s\n", hexBytes(cert.SerialNumber.Bytes()))
+	}
+	fmt.Fprintf(b, "    Signature Algorithm: %s\n", signature)
+	fmt.Fprintf(b, "        Issuer: %s\n", textName(cert.Issuer))
+	b.WriteString("        Validity\n")
+	fmt.Fprintf(b, "            Not Before: %s\n", cert.NotBefore.UTC().Format(opensslDateFormat))
+	fmt.Fprintf(b, "            Not After : %s\n", cert.NotAfter.UTC().Format(opensslDateFormat))
+	fmt.Fprintf(b, "        Subject: %s\n", textName(cert.Subject))
+	b.WriteString("        Subject Public Key Info:\n")
+	writePublicKeyText(b, cert)
+
+	if len(cert.Extensions) > 0 {
+		b.WriteString("        X509v3 extensions:\n")
+		for _, ext := range cert.Extensions {
+			writeExtensionText(b, cert, ext)
+		}
+	}
+
+	fmt.Fprintf(b, "    Signature Algorithm: %s\n", signature)
+	writeHexBlock(b, cert.Signature, "         ", 18)
+}
+
+func writePublicKeyText(b *strings.Builder, cert *x509.Certificate) {
+	const indent = "                "
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		b.WriteString("            Public Key Algorithm: rsaEncryption\n")
+		fmt.Fprintf(b, "%sPublic-Key: (%d bit)\n", indent, key.N.BitLen())
+		fmt.Fprintf(b, "%sModulus:\n", indent)
+		// Like openssl, a leading zero marks the modulus as positive
+		writeHexBlock(b, append([]byte{0}, key.N.Bytes()...), indent+"    ", 15)
+		fmt.Fprintf(b, "%sExponent: %d (0x%x)\n", indent, key.E, key.E)
+	case *ecdsa.PublicKey:
+		b.WriteString("            Public Key Algorithm: id-ecPublicKey\n")
+		fmt.Fprintf(b, "%sPublic-Key: (%d bit)\n", indent, key.Curve.Params().BitSize)
+		if ecdhKey, err := key.ECDH(); err == nil {
+			fmt.Fprintf(b, "%spub: \n", indent)
+			writeHexBlock(b, ecdhKey.Bytes(), indent+"    ", 15)
+		}
+		if info, err := crypto.GetCertificateKeyInfo(cert); err == nil {
+			fmt.Fprintf(b, "%sASN1 OID: %s\n", indent, info.Curve)
+		}
+		fmt.Fprintf(b, "%sNIST CURVE: %s\n", indent, key.Curve.Params().Name)
+	default:
+		fmt.Fprintf(b, "            Public Key Algorithm: %s\n", cert.PublicKeyAlgorithm)
+	}
+}
+
+func writeExtensionText(b *strings.Builder, cert *x509.Certificate, ext pkix.Extension) {
+	const indent = "                "
+	critical := ""
+	if ext.Critical {
+		critical = " critical"
+	}
+
+	switch {
+	case ext.Id.Equal(oidBasicConstraints):
+		fmt.Fprintf(b, "            X509v3 Basic Constraints:%s\n", critical)
+		value := "CA:FALSE"
+		if cert.IsCA {
+			value = "CA:TRUE"
+			if cert.MaxPathLen > 0 || cert.MaxPathLenZero {
+				value += fmt.Sprintf(", pathlen:%d", cert.MaxPathLen)
+			}
+		}
+		fmt.Fprintf(b, "%s%s\n", indent, value)
+	case ext.Id.Equal(oidKeyUsage):
+		fmt.Fprintf(b, "            X509v3 Key Usage:%s\n", critical)
+		var usages []string
+		for _, ku := range keyUsages {
+			if cert.KeyUsage&ku.usage != 0 {
+				usages = append(usages, ku.name)
+			}
+		}
+		fmt.Fprintf(b, "%s%s\n", indent, strings.Join(usages, ", "))
+	case ext.Id.Equal(oidExtKeyUsage):
+		fmt.Fprintf(b, "            X509v3 Extended Key Usage:%s\n", critical)
+		var usages []string
+		for _, eku := range cert.ExtKeyUsage {
+			if name, found := extKeyUsages[eku]; found {
+				usages = append(usages, name)
+			}
+		}
+		fmt.Fprintf(b, "%s%s\n", indent, strings.Join(usages, ", "))
+	case ext.Id.Equal(oidSubjectKeyID):
+		fmt.Fprintf(b, "            X509v3 Subject Key Identifier:%s\n", critical)
+		fmt.Fprintf(b, "%s%s\n", indent, strings.ToUpper(hexBytes(cert.SubjectKeyId)))
+	case ext.Id.Equal(oidAuthorityKeyID):
+		fmt.Fprintf(b, "            X509v3 Authority Key Identifier:%s\n", critical)
+		fmt.Fprintf(b, "%skeyid:%s\n", indent, strings.ToUpper(hexBytes(cert.AuthorityKeyId)))
+	case ext.Id.Equal(oidSubjectAltName):
+		fmt.Fprintf(b, "            X509v3 Subject Alternative Name:%s\n", critical)
+		var names []string
+		for _, name := range cert.DNSNames {
+			names = append(names, "DNS:"+name)
+		}
+		for _, address := range cert.IPAddresses {
+			names = append(names, "IP Address:"+address.String())
+		}
+		for _, email := range cert.EmailAddresses {
+			names = append(names, "email:"+email)
+		}
+		for _, uri := range cert.URIs {
+			names = append(names, "URI:"+uri.String())
+		}
+		fmt.Fprintf(b, "%s%s\n", indent, strings.Join(names, ", "))
+	default:
+		fmt.Fprintf(b, "            %s:%s\n", ext.Id, critical)
+		writeHexBlock(b, ext.Value, indent, 18)
+	}
+}
@@ -0,0 +1,193 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// uploadsDir is the only directory files can be transferred to, like with the upload endpoint
+const uploadsDir = "/var/config/rest/downloads"
+
+// transferPath resolves the path of a transferred file, relative paths being in the uploads directory
+func transferPath(name string) string {
+	if !path.IsAbs(name) {
+		name = path.Join(uploadsDir, name)
+	}
+	return path.Clean(name)
+}
+
+// checkUploadPath returns an error if a file cannot be written at p
+func checkUploadPath(p string) error {
+	if !strings.HasPrefix(p, uploadsDir+"/") {
+		return fmt.Errorf("%s: Permission denied", p)
+	}
+	return nil
+}
+
+// writeUpload writes a transferred file, replacing any existing one
+func (s *Shell) writeUpload(p string, content []byte) error {
+	defer s.lock()()
+
+	if s.Store.Fs.Exists(p) {
+		err := s.Store.Fs.Remove(p)
+		if err != nil {
+			return err
+		}
+	}
+	// Files without contents would not exist
+	if content == nil {
+		content = []byte{}
+	}
+	_, err := s.Store.Fs.WriteFile(p, content)
+	return err
+}
+
+// ReceiveSCP runs scp -t, which receives the files of scp uploads from in until its end. Files can only be written
+// under /var/config/rest/downloads, relative targets being resolved there. It returns the exit status.
+func (s *Shell) ReceiveSCP(in io.Reader, out io.Writer, args []string) int {
+	if s.Restricted {
+		fmt.Fprint(out, "\x01scp: file transfers are not allowed for this user\n")
+		return 1
+	}
+
+	sink, dirTarget := false, false
+	target := "."
+	for _, arg := range args {
+		switch {
+		case arg == "-t":
+			sink = true
+		case arg == "-d":
+			dirTarget = true
+		case strings.HasPrefix(arg, "-"):
+			// -r, -p and -v need no handling by the sink
+		default:
+			target = arg
+		}
+	}
+	if !sink {
+		fmt.Fprint(out, "\x01scp: only uploads are supported\n")
+		return 1
+	}
+	// The uploads directory exists even without files
+	dirTarget = dirTarget || strings.HasSuffix(target, "/") || transferPath(target) == uploadsDir
+	target = transferPath(target)
+
+	status := 0
+	reply := func(format string, args ...any) error {
+		if format == "" {
+			_, err := out.Write([]byte{0})
+			return err
+		}
+		status = 1
+		_, err := fmt.Fprintf(out, "\x01scp: "+format+"\n", args...)
+		return err
+	}
+
+	// dirs are the directories entered with D records
+	var dirs []string
+	destination := func(name string) string {
+		if len(dirs) > 0 {
+			return path.Join(dirs[len(dirs)-1], name)
+		}
+		unlock := s.lock()
+		isDir := s.Store.Fs.IsDir(target)
+		unlock()
+		if dirTarget || isDir {
+			return path.Join(target, name)
+		}
+		return target
+	}
+
+	records := bufio.NewReader(in)
+	err := reply("")
+	for err == nil {
+		var line string
+		line, err = records.ReadString('\n')
+		if errors.Is(err, io.EOF) && line == "" {
+			return status
+		}
+		if err != nil {
+			return 1
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			_ = reply("protocol error: empty record")
+			return 1
+		}
+
+		switch line[0] {
+		case 'T':
+			err = reply("")
+		case 'E':
+			if len(dirs) > 0 {
+				dirs = dirs[:len(dirs)-1]
+			}
+			err = reply("")
+		case 'D', 'C':
+			size, name, parseErr := parseSCPRecord(line)
+			if parseErr != nil {
+				_ = reply("%v", parseErr)
+				return 1
+			}
+			p := destination(name)
+			if line[0] == 'D' {
+				dirs = append(dirs, p)
+				err = reply("")
+				continue
+			}
+			if pathErr := checkUploadPath(p); pathErr != nil {
+				// The source skips the contents of refused files
+				err = reply("%v", pathErr)
+				continue
+			}
+
+			err = reply("")
+			if err != nil {
+				return 1
+			}
+
+			// The contents are followed by a null byte
+			content := make([]byte, size+1)
+			_, err = io.ReadFull(records, content)
+			if err != nil {
+				return 1
+			}
+			err = s.writeUpload(p, content[:size])
+			if err != nil {
+				err = reply("%s: %v", p, err)
+				continue
+			}
+			err = reply("")
+		case '\x01':
+			// Warnings of the source
+		case '\x02':
+			return 1
+		default:
+			_ = reply("protocol error: unexpected record %q", line[:1])
+			return 1
+		}
+	}
+	return 1
+}
+
+// parseSCPRecord parses C and D records, made of a mode, a size and a name
+func parseSCPRecord(line string) (int, string, error) {
+	fields := strings.SplitN(line[1:], " ", 3)
+	if len(fields) != 3 {
+		return 0, "", fmt.Errorf("protocol error: invalid record %q", line)
+	}
+	size, err := strconv.Atoi(fields[1])
+	if err != nil || size < 0 {
+		return 0, "", fmt.Errorf("protocol error: invalid size %q", fields[1])
+	}
+	name := fields[2]
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return 0, "", fmt.Errorf("protocol error: unexpected filename: %s", name)
+	}
+	return size, name, nil
+}
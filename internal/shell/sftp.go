@@ -0,0 +1,433 @@
+package shell
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+)
+
+// Packet types of version 3 of the SFTP protocol, the one OpenSSH uses
+const (
+	sftpInit     = 1
+	sftpVersion  = 2
+	sftpOpen     = 3
+	sftpClose    = 4
+	sftpRead     = 5
+	sftpWrite    = 6
+	sftpLstat    = 7
+	sftpFstat    = 8
+	sftpSetstat  = 9
+	sftpFsetstat = 10
+	sftpOpendir  = 11
+	sftpReaddir  = 12
+	sftpRemove   = 13
+	sftpRealpath = 16
+	sftpStat     = 17
+	sftpStatus   = 101
+	sftpHandle   = 102
+	sftpData     = 103
+	sftpName     = 104
+	sftpAttrs    = 105
+)
+
+// Status codes
+const (
+	sftpOK               = 0
+	sftpEOF              = 1
+	sftpNoSuchFile       = 2
+	sftpPermissionDenied = 3
+	sftpFailure          = 4
+	sftpBadMessage       = 5
+	sftpOpUnsupported    = 8
+)
+
+// Open flags
+const (
+	sftpFlagWrite = 0x02
+	sftpFlagTrunc = 0x10
+	sftpFlagExcl  = 0x20
+)
+
+// Attribute flags
+const (
+	sftpAttrSize        = 0x01
+	sftpAttrPermissions = 0x04
+)
+
+// sftpMaxPacket bounds the packets read, OpenSSH sending at most 256KiB
+const sftpMaxPacket = 1 << 20
+
+// sftpMaxFile bounds the size of written files
+const sftpMaxFile = 1 << 30
+
+// sftpStatusError is an error answered with a status packet
+type sftpStatusError struct {
+	code    uint32
+	message string
+}
+
+func (e sftpStatusError) Error() string {
+	return e.message
+}
+
+var (
+	errSFTPNoSuchFile       = sftpStatusError{sftpNoSuchFile, "No such file"}
+	errSFTPPermissionDenied = sftpStatusError{sftpPermissionDenied, "Permission denied"}
+	errSFTPBadMessage       = sftpStatusError{sftpBadMessage, "Bad message"}
+	errSFTPInvalidHandle    = sftpStatusError{sftpFailure, "Invalid handle"}
+)
+
+// sftpFile is an open file or directory. Written files are stored when closed.
+type sftpFile struct {
+	path    string
+	write   bool
+	content []byte
+	dir     bool
+	// entries are the names of the directory left to read
+	entries []string
+}
+
+// sftpServer is the state of an SFTP session
+type sftpServer struct {
+	shell   *Shell
+	handles map[string]*sftpFile
+	next    int
+}
+
+// ServeSFTP runs the sftp subsystem on a session until the end of its input. Files can be read anywhere but only
+// written under /var/config/rest/downloads, which is the initial directory.
+func (s *Shell) ServeSFTP(rw io.ReadWriter) error {
+	if s.Restricted {
+		return errors.New("file transfers are not allowed for this user")
+	}
+
+	server := &sftpServer{shell: s, handles: map[string]*sftpFile{}}
+	for {
+		var length uint32
+		err := binary.Read(rw, binary.BigEndian, &length)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if length == 0 || length > sftpMaxPacket {
+			return fmt.Errorf("invalid sftp packet length %d", length)
+		}
+
+		packet := make([]byte, length)
+		_, err = io.ReadFull(rw, packet)
+		if err != nil {
+			return err
+		}
+
+		_, err = rw.Write(server.handle(packet))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handle answers a packet
+func (srv *sftpServer) handle(packet []byte) []byte {
+	r := &sftpReader{data: packet[1:]}
+	if packet[0] == sftpInit {
+		return sftpPacket(sftpVersion, binary.BigEndian.AppendUint32(nil, 3))
+	}
+
+	id := r.uint32()
+	reply, err := srv.reply(packet[0], r)
+	if err == nil && r.err != nil {
+		err = errSFTPBadMessage
+	}
+	if err != nil {
+		var status sftpStatusError
+		if !errors.As(err, &status) {
+			status = sftpStatusError{sftpFailure, err.Error()}
+		}
+		return sftpStatusPacket(id, status)
+	}
+	if reply == nil {
+		return sftpStatusPacket(id, sftpStatusError{sftpOK, "Success"})
+	}
+	return sftpPacket(reply[0], append(binary.BigEndian.AppendUint32(nil, id), reply[1:]...))
+}
+
+// reply runs a request, returning the type and payload of the answer, or nil for a success status
+func (srv *sftpServer) reply(kind byte, r *sftpReader) ([]byte, error) {
+	switch kind {
+	case sftpRealpath:
+		p := transferPath(r.string())
+		// Paths being created are resolved too
+		entry, _ := srv.stat(p)
+		entry.name, entry.longname = p, p
+		return sftpNames(sftpName, []sftpEntry{entry}), nil
+	case sftpStat, sftpLstat:
+		entry, err := srv.stat(transferPath(r.string()))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{sftpAttrs}, entry.attrs()...), nil
+	case sftpFstat:
+		h, err := srv.lookup(r.string())
+		if err != nil {
+			return nil, err
+		}
+		if h.write {
+			return append([]byte{sftpAttrs}, sftpEntry{size: len(h.content)}.attrs()...), nil
+		}
+		entry, err := srv.stat(h.path)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{sftpAttrs}, entry.attrs()...), nil
+	case sftpOpen:
+		return srv.open(transferPath(r.string()), r.uint32())
+	case sftpOpendir:
+		return srv.opendir(transferPath(r.string()))
+	case sftpRead:
+		h, err := srv.lookup(r.string())
+		if err != nil {
+			return nil, err
+		}
+		offset, length := r.uint64(), uint64(r.uint32())
+		if h.dir || offset >= uint64(len(h.content)) {
+			return nil, sftpStatusError{sftpEOF, "End of file"}
+		}
+		data := h.content[offset:min(offset+length, uint64(len(h.content)))]
+		return append([]byte{sftpData}, sftpString(data)...), nil
+	case sftpWrite:
+		h, err := srv.lookup(r.string())
+		if err != nil {
+			return nil, err
+		}
+		offset, data := r.uint64(), r.bytes()
+		if r.err != nil {
+			return nil, r.err
+		}
+		if !h.write {
+			return nil, errSFTPPermissionDenied
+		}
+		// The end wraps around for offsets near the maximum
+		end := offset + uint64(len(data))
+		if offset > sftpMaxFile || end < offset || end > sftpMaxFile {
+			return nil, sftpStatusError{sftpFailure, "File too large"}
+		}
+		if end > uint64(len(h.content)) {
+			h.content = append(h.content, make([]byte, end-uint64(len(h.content)))...)
+		}
+		copy(h.content[offset:], data)
+		return nil, nil
+	case sftpReaddir:
+		h, err := srv.lookup(r.string())
+		if err != nil {
+			return nil, err
+		}
+		if !h.dir || len(h.entries) == 0 {
+			return nil, sftpStatusError{sftpEOF, "End of file"}
+		}
+		var entries []sftpEntry
+		for _, name := range h.entries {
+			entry, err := srv.stat(path.Join(h.path, name))
+			if err != nil {
+				continue
+			}
+			entry.name = name
+			entry.longname = entry.long()
+			entries = append(entries, entry)
+		}
+		h.entries = nil
+		return sftpNames(sftpName, entries), nil
+	case sftpClose:
+		handle := r.string()
+		h, err := srv.lookup(handle)
+		if err != nil {
+			return nil, err
+		}
+		delete(srv.handles, handle)
+		if h.write {
+			return nil, srv.shell.writeUpload(h.path, h.content)
+		}
+		return nil, nil
+	case sftpRemove:
+		return nil, srv.remove(transferPath(r.string()))
+	case sftpSetstat, sftpFsetstat:
+		// Modes and times of files are not kept
+		return nil, nil
+	default:
+		return nil, sftpStatusError{sftpOpUnsupported, "Operation unsupported"}
+	}
+}
+
+func (srv *sftpServer) lookup(handle string) (*sftpFile, error) {
+	h, found := srv.handles[handle]
+	if !found {
+		return nil, errSFTPInvalidHandle
+	}
+	return h, nil
+}
+
+func (srv *sftpServer) newHandle(h *sftpFile) []byte {
+	srv.next++
+	handle := strconv.Itoa(srv.next)
+	srv.handles[handle] = h
+	return append([]byte{sftpHandle}, sftpString([]byte(handle))...)
+}
+
+func (srv *sftpServer) open(p string, flags uint32) ([]byte, error) {
+	defer srv.shell.lock()()
+	fs := srv.shell.Store.Fs
+
+	if flags&sftpFlagWrite == 0 {
+		content, err := fs.ReadFile(p)
+		if err != nil {
+			return nil, errSFTPNoSuchFile
+		}
+		return srv.newHandle(&sftpFile{path: p, content: content}), nil
+	}
+
+	if checkUploadPath(p) != nil {
+		return nil, errSFTPPermissionDenied
+	}
+	h := &sftpFile{path: p, write: true}
+	if fs.Exists(p) {
+		if flags&sftpFlagExcl != 0 {
+			return nil, sftpStatusError{sftpFailure, "File exists"}
+		}
+		if flags&sftpFlagTrunc == 0 {
+			content, _ := fs.ReadFile(p)
+			h.content = append([]byte{}, content...)
+		}
+	}
+	return srv.newHandle(h), nil
+}
+
+func (srv *sftpServer) opendir(p string) ([]byte, error) {
+	defer srv.shell.lock()()
+
+	if p != uploadsDir && !srv.shell.Store.Fs.IsDir(p) {
+		return nil, errSFTPNoSuchFile
+	}
+	return srv.newHandle(&sftpFile{path: p, dir: true, entries: srv.shell.children(p)}), nil
+}
+
+func (srv *sftpServer) remove(p string) error {
+	defer srv.shell.lock()()
+
+	if checkUploadPath(p) != nil {
+		return errSFTPPermissionDenied
+	}
+	if srv.shell.Store.Fs.Remove(p) != nil {
+		return errSFTPNoSuchFile
+	}
+	return nil
+}
+
+func (srv *sftpServer) stat(p string) (sftpEntry, error) {
+	defer srv.shell.lock()()
+	fs := srv.shell.Store.Fs
+
+	switch {
+	case p == uploadsDir || fs.IsDir(p):
+		return sftpEntry{dir: true}, nil
+	case fs.Exists(p):
+		content, _ := fs.ReadFile(p)
+		return sftpEntry{size: len(content)}, nil
+	default:
+		return sftpEntry{}, errSFTPNoSuchFile
+	}
+}
+
+// sftpEntry is a file or directory returned in a name packet
+type sftpEntry struct {
+	name     string
+	longname string
+	size     int
+	dir      bool
+}
+
+func (e sftpEntry) attrs() []byte {
+	mode := uint32(0o100644)
+	if e.dir {
+		mode = 0o040755
+	}
+	b := binary.BigEndian.AppendUint32(nil, sftpAttrSize|sftpAttrPermissions)
+	b = binary.BigEndian.AppendUint64(b, uint64(e.size))
+	return binary.BigEndian.AppendUint32(b, mode)
+}
+
+// long formats the entry like ls -l, which clients display
+func (e sftpEntry) long() string {
+	mode := "-rw-r--r--"
+	if e.dir {
+		mode = "drwxr-xr-x"
+	}
+	return fmt.Sprintf("%s    1 root     root     %8d Jan  1 00:00 %s", mode, e.size, e.name)
+}
+
+func sftpNames(kind byte, entries []sftpEntry) []byte {
+	b := binary.BigEndian.AppendUint32([]byte{kind}, uint32(len(entries)))
+	for _, entry := range entries {
+		b = append(b, sftpString([]byte(entry.name))...)
+		b = append(b, sftpString([]byte(entry.longname))...)
+		b = append(b, entry.attrs()...)
+	}
+	return b
+}
+
+func sftpStatusPacket(id uint32, status sftpStatusError) []byte {
+	b := binary.BigEndian.AppendUint32(nil, id)
+	b = binary.BigEndian.AppendUint32(b, status.code)
+	b = append(b, sftpString([]byte(status.message))...)
+	b = append(b, sftpString(nil)...)
+	return sftpPacket(sftpStatus, b)
+}
+
+func sftpPacket(kind byte, payload []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	return append(append(b, kind), payload...)
+}
+
+func sftpString(s []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(s))), s...)
+}
+
+// sftpReader decodes the fields of a packet, keeping the first error
+type sftpReader struct {
+	data []byte
+	err  error
+}
+
+func (r *sftpReader) next(n int) []byte {
+	if r.err != nil || len(r.data) < n {
+		r.err = errSFTPBadMessage
+		return make([]byte, n)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *sftpReader) uint32() uint32 {
+	return binary.BigEndian.Uint32(r.next(4))
+}
+
+func (r *sftpReader) uint64() uint64 {
+	return binary.BigEndian.Uint64(r.next(8))
+}
+
+func (r *sftpReader) bytes() []byte {
+	n := r.uint32()
+	if r.err != nil || uint64(n) > uint64(len(r.data)) {
+		r.err = errSFTPBadMessage
+		return nil
+	}
+	return r.next(int(n))
+}
+
+func (r *sftpReader) string() string {
+	return string(r.bytes())
+}
@@ -0,0 +1,326 @@
+// Package shell emulates the bash and tmsh commands scripts run on a BIG-IP, against the mock store. Nothing is
+// ever executed on the host.
+package shell
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/iilun/f5-mock/pkg/cache"
+)
+
+// Requester sends an iControl REST request on behalf of the user running the shell, returning the status and body
+// of the response. tmsh commands changing objects are run as REST requests, to share the checks of the handlers.
+type Requester func(method, uri string, body []byte) (int, []byte)
+
+// Shell runs command lines against a store, keeping a working directory across lines
+type Shell struct {
+	Store *cache.MemoryCaches
+	// Version is the emulated TMOS version, e.g. 17.0.0.0
+	Version string
+	// Dir is the working directory
+	Dir string
+	// Requester runs the REST requests of tmsh commands, which cannot change objects without it
+	Requester Requester
+	// Locker, when set, is held while each command of a session and each file transfer operation runs
+	Locker sync.Locker
+	// Restricted shells only run tmsh commands, like those of users without bash access on a device
+	Restricted bool
+}
+
+// New returns a shell working in the root directory
+func New(store *cache.MemoryCaches, version string) *Shell {
+	return &Shell{Store: store, Version: version, Dir: "/"}
+}
+
+// stdio holds the input and outputs of a command
+type stdio struct {
+	stdin  string
+	stdout strings.Builder
+	stderr strings.Builder
+}
+
+func (s *stdio) errorf(format string, args ...any) {
+	fmt.Fprintf(&s.stderr, format+"\n", args...)
+}
+
+type command func(s *Shell, args []string, io *stdio) int
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"cat":     cat,
+		"cd":      cd,
+		"cp":      cp,
+		"echo":    echo,
+		"grep":    grep,
+		"head":    head,
+		"ls":      ls,
+		"mv":      mv,
+		"openssl": openssl,
+		"pwd":     pwd,
+		"rm":      rm,
+		"tail":    tail,
+		"tmsh":    tmsh,
+		"wc":      wc,
+	}
+}
+
+// token is a word or an operator of a command line
+type token struct {
+	value    string
+	operator bool
+	// glob words hold unquoted wildcards
+	glob bool
+}
+
+// tokenize splits a command line into words and the ; && || | operators, following the bash quoting rules
+func tokenize(line string) ([]token, error) {
+	var tokens []token
+	var word strings.Builder
+	inWord, glob := false, false
+
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, token{value: word.String(), glob: glob})
+		}
+		word.Reset()
+		inWord, glob = false, false
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			flush()
+		case c == '\n' || c == ';':
+			flush()
+			tokens = append(tokens, token{value: ";", operator: true})
+		case c == '&' || c == '|':
+			flush()
+			if i+1 < len(line) && line[i+1] == c {
+				tokens = append(tokens, token{value: string([]byte{c, c}), operator: true})
+				i++
+			} else if c == '|' {
+				tokens = append(tokens, token{value: "|", operator: true})
+			} else {
+				return nil, fmt.Errorf("background jobs are not supported")
+			}
+		case c == '>' || c == '<':
+			return nil, fmt.Errorf("redirections are not supported")
+		case c == '`' || (c == '$' && i+1 < len(line) && line[i+1] == '('):
+			return nil, fmt.Errorf("command substitutions are not supported")
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unexpected EOF while looking for matching `''")
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			inWord = true
+			i += end + 1
+		case c == '"':
+			inWord = true
+			for i++; ; i++ {
+				if i >= len(line) {
+					return nil, fmt.Errorf("unexpected EOF while looking for matching `\"'")
+				}
+				if line[i] == '"' {
+					break
+				}
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\"\\$`", line[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(line[i])
+			}
+		case c == '\\' && i+1 < len(line):
+			i++
+			word.WriteByte(line[i])
+			inWord = true
+		default:
+			if c == '*' || c == '?' || c == '[' {
+				glob = true
+			}
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	flush()
+	return tokens, nil
+}
+
+// SplitWords splits arguments following the bash quoting rules, like the utilCmdArgs of util commands
+func SplitWords(line string) ([]string, error) {
+	tokens, err := tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	for _, t := range tokens {
+		if t.operator {
+			return nil, fmt.Errorf("syntax error near unexpected token `%s'", t.value)
+		}
+		words = append(words, t.value)
+	}
+	return words, nil
+}
+
+// Run runs a command line, returning its combined output and exit status like bash -c
+func (s *Shell) Run(line string) (string, int) {
+	tokens, err := tokenize(line)
+	if err != nil {
+		return fmt.Sprintf("/bin/bash: %v\n", err), 2
+	}
+
+	var output strings.Builder
+	status := 0
+	// skip is set when the previous && or || operator short-circuits the next pipeline
+	skip := false
+	var pipeline [][]token
+	var current []token
+
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && (!tokens[i].operator || tokens[i].value == "|") {
+			if tokens[i].value == "|" {
+				if len(current) == 0 {
+					return output.String() + "/bin/bash: syntax error near unexpected token `|'\n", 2
+				}
+				pipeline = append(pipeline, current)
+				current = nil
+			} else {
+				current = append(current, tokens[i])
+			}
+			continue
+		}
+
+		operator := ";"
+		if i < len(tokens) {
+			operator = tokens[i].value
+		}
+		if len(current) == 0 {
+			if len(pipeline) > 0 || (operator != ";" && i < len(tokens)) {
+				return output.String() + fmt.Sprintf("/bin/bash: syntax error near unexpected token `%s'\n", operator), 2
+			}
+			continue
+		}
+		pipeline = append(pipeline, current)
+
+		if !skip {
+			status = s.runPipeline(pipeline, &output)
+		}
+		switch operator {
+		case "&&":
+			skip = status != 0
+		case "||":
+			skip = status == 0
+		default:
+			skip = false
+		}
+		pipeline, current = nil, nil
+	}
+
+	return output.String(), status
+}
+
+// runPipeline runs commands, each reading the output of the previous one
+func (s *Shell) runPipeline(pipeline [][]token, output *strings.Builder) int {
+	status := 0
+	stdin := ""
+	for i, tokens := range pipeline {
+		args := s.expand(tokens)
+		io := &stdio{stdin: stdin}
+
+		cmd, found := commands[args[0]]
+		if found {
+			status = cmd(s, args[1:], io)
+		} else {
+			io.errorf("/bin/bash: %s: command not found", args[0])
+			status = 127
+		}
+
+		output.WriteString(io.stderr.String())
+		if i == len(pipeline)-1 {
+			output.WriteString(io.stdout.String())
+		}
+		stdin = io.stdout.String()
+	}
+	return status
+}
+
+// expand returns the words of a command, expanding wildcards against the files of the store
+func (s *Shell) expand(tokens []token) []string {
+	var args []string
+	for _, t := range tokens {
+		if !t.glob {
+			args = append(args, t.value)
+			continue
+		}
+
+		pattern := s.abs(t.value)
+		var matches []string
+		for _, candidate := range s.paths() {
+			if ok, _ := path.Match(pattern, candidate); ok {
+				if !path.IsAbs(t.value) {
+					candidate = s.rel(candidate)
+				}
+				matches = append(matches, candidate)
+			}
+		}
+		if len(matches) == 0 {
+			// Like bash, patterns matching nothing are kept as is
+			matches = []string{t.value}
+		}
+		args = append(args, matches...)
+	}
+	return args
+}
+
+// paths returns the files and directories of the store, sorted
+func (s *Shell) paths() []string {
+	var paths []string
+	for _, file := range s.Store.Fs.List("/") {
+		for dir := path.Dir(file); dir != "/"; dir = path.Dir(dir) {
+			if !slices.Contains(paths, dir) {
+				paths = append(paths, dir)
+			}
+		}
+		paths = append(paths, file)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// abs returns the absolute path of a path relative to the working directory
+func (s *Shell) abs(p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(s.Dir, p)
+}
+
+// rel returns an absolute path relative to the working directory when it is under it
+func (s *Shell) rel(p string) string {
+	prefix := strings.TrimSuffix(s.Dir, "/") + "/"
+	if strings.HasPrefix(p, prefix) {
+		return strings.TrimPrefix(p, prefix)
+	}
+	return p
+}
+
+// splitFlags separates the leading flags of arguments, "--" ending them
+func splitFlags(args []string) (string, []string) {
+	var flags strings.Builder
+	for i, arg := range args {
+		if arg == "--" {
+			return flags.String(), args[i+1:]
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return flags.String(), args[i:]
+		}
+		flags.WriteString(strings.TrimLeft(arg, "-"))
+	}
+	return flags.String(), nil
+}
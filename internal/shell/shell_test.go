@@ -0,0 +1,591 @@
+package shell
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iilun/f5-mock/internal/crypto"
+	"github.com/iilun/f5-mock/pkg/cache"
+	"github.com/iilun/f5-mock/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestShell(t *testing.T, key, cert []byte) *Shell {
+	store, err := cache.New("")
+	require.NoError(t, err)
+
+	_, _ = store.Fs.WriteFile("/certs/Common/www.crt", cert)
+	_, _ = store.Fs.WriteFile("/keys/Common/www.key", key)
+	_, _ = store.Fs.WriteFile("/var/config/rest/downloads/notes.txt", []byte("alpha\nbeta\ngamma\n"))
+	store.Pools = []*models.Pool{{Name: "web", Partition: "Common"}, {Name: "api", Partition: "Common"}}
+
+	return New(store, "17.0.0.0")
+}
+
+func TestRun(t *testing.T) {
+	key, err := crypto.GenerateKey(crypto.KeyTypeRSA, 2048, "")
+	require.NoError(t, err)
+	cert, err := crypto.CreateSelfSignedCertificate(key,
+		crypto.Subject{CommonName: "www.example.com", Organization: "Example"},
+		crypto.SubjectAlternativeNames{DNSNames: []string{"www.example.com", "example.com"}},
+		24*time.Hour)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		line       string
+		wantStatus int
+		want       string
+		wantAbsent string
+		check      func(t *testing.T, s *Shell)
+	}{
+		{
+			name: "echo and quoting",
+			line: `echo "a  b" 'c;d' e\ f`,
+			want: "a  b c;d e f\n",
+		},
+		{
+			name: "ls directory",
+			line: "ls /var/config/rest/downloads",
+			want: "notes.txt\n",
+		},
+		{
+			name: "ls root",
+			line: "ls -la /",
+			want: "certs\nkeys\nvar\n",
+		},
+		{
+			name:       "ls missing",
+			line:       "ls /nope",
+			wantStatus: 2,
+			want:       "ls: cannot access '/nope': No such file or directory\n",
+		},
+		{
+			name: "cd and glob",
+			line: "cd /var/config/rest/downloads && cat *.txt",
+			want: "alpha\nbeta\ngamma\n",
+		},
+		{
+			name:       "cd missing",
+			line:       "cd /nope",
+			wantStatus: 1,
+			want:       "/bin/bash: line 1: cd: /nope: No such file or directory\n",
+		},
+		{
+			name:       "cat directory",
+			line:       "cat /certs",
+			wantStatus: 1,
+			want:       "cat: /certs: Is a directory\n",
+		},
+		{
+			name: "pipes",
+			line: "cat /var/config/rest/downloads/notes.txt | grep -v beta | tail -n 1",
+			want: "gamma\n",
+		},
+		{
+			name:       "grep without match",
+			line:       "grep delta /var/config/rest/downloads/notes.txt || echo none",
+			wantStatus: 0,
+			want:       "none\n",
+		},
+		{
+			name: "wc",
+			line: "wc -l /var/config/rest/downloads/notes.txt",
+			want: "3\n",
+		},
+		{
+			name: "rm",
+			line: "rm /var/config/rest/downloads/notes.txt",
+			check: func(t *testing.T, s *Shell) {
+				require.False(t, s.Store.Fs.Exists("/var/config/rest/downloads/notes.txt"))
+			},
+		},
+		{
+			name:       "rm missing",
+			line:       "rm /nope; rm -f /nope",
+			wantStatus: 0,
+			want:       "rm: cannot remove '/nope': No such file or directory\n",
+		},
+		{
+			name:       "rm directory",
+			line:       "rm /certs",
+			wantStatus: 1,
+			want:       "rm: cannot remove '/certs': Is a directory\n",
+		},
+		{
+			name:       "rm root",
+			line:       "rm -rf /",
+			wantStatus: 1,
+			want:       "rm: it is dangerous to operate recursively on '/'",
+			check: func(t *testing.T, s *Shell) {
+				require.True(t, s.Store.Fs.Exists("/certs/Common/www.crt"))
+			},
+		},
+		{
+			name: "mv into a directory",
+			line: "mv /var/config/rest/downloads/notes.txt /certs",
+			check: func(t *testing.T, s *Shell) {
+				require.False(t, s.Store.Fs.Exists("/var/config/rest/downloads/notes.txt"))
+				require.True(t, s.Store.Fs.Exists("/certs/notes.txt"))
+			},
+		},
+		{
+			name:       "mv missing",
+			line:       "mv /nope /certs",
+			wantStatus: 1,
+			want:       "mv: cannot stat '/nope': No such file or directory\n",
+		},
+		{
+			name: "cp",
+			line: "cp /var/config/rest/downloads/notes.txt /tmp/notes.txt && cat /tmp/notes.txt",
+			want: "alpha\nbeta\ngamma\n",
+			check: func(t *testing.T, s *Shell) {
+				require.True(t, s.Store.Fs.Exists("/var/config/rest/downloads/notes.txt"))
+			},
+		},
+		{
+			name: "openssl text",
+			line: "openssl x509 -noout -text -in /certs/Common/www.crt",
+			want: "        Subject: O=Example, CN=www.example.com\n",
+		},
+		{
+			name: "openssl subject alternative names",
+			line: "openssl x509 -noout -text -in /certs/Common/www.crt | grep DNS",
+			want: "                DNS:www.example.com, DNS:example.com\n",
+		},
+		{
+			name: "openssl subject and dates",
+			line: "cd /certs/Common; openssl x509 -in www.crt -noout -subject -enddate",
+			want: "subject= /O=Example/CN=www.example.com\nnotAfter=",
+		},
+		{
+			name:       "openssl without certificate",
+			line:       "openssl x509 -noout -text -in /keys/Common/www.key",
+			wantStatus: 1,
+			want:       "unable to load certificate\n",
+		},
+		{
+			name:       "openssl missing file",
+			line:       "openssl x509 -noout -in /nope",
+			wantStatus: 1,
+			want:       "Error opening Certificate /nope\nunable to load certificate\n",
+		},
+		{
+			name:       "openssl unsupported command",
+			line:       "openssl s_client -connect localhost:443",
+			wantStatus: 1,
+			want:       "openssl:Error: 's_client' is an invalid command.\n",
+		},
+		{
+			name: "tmsh list",
+			line: "tmsh list ltm pool web",
+			want: "ltm pool /Common/web { }\n",
+		},
+		{
+			name:       "tmsh list module",
+			line:       "tmsh -q list ltm",
+			want:       "ltm pool /Common/api { }\n\nltm pool /Common/web { }\n",
+			wantAbsent: "sys file",
+		},
+		{
+			name:       "tmsh list missing object",
+			line:       "tmsh list ltm pool nope",
+			wantStatus: 1,
+			want:       "01020036:3: The requested ltm pool (/Common/nope) was not found.\n",
+		},
+		{
+			name:       "tmsh list unknown type",
+			line:       "tmsh list ltm monitor",
+			wantStatus: 1,
+			want:       "Syntax Error: \"monitor\" unknown property\n",
+		},
+		{
+			name: "tmsh show sys version",
+			line: "tmsh -c 'show sys version'",
+			want: "  Version   17.0.0\n",
+		},
+		{
+			name:       "unknown command",
+			line:       "curl -k https://localhost",
+			wantStatus: 127,
+			want:       "/bin/bash: curl: command not found\n",
+		},
+		{
+			name:       "redirections",
+			line:       "echo a > /tmp/a",
+			wantStatus: 2,
+			want:       "/bin/bash: redirections are not supported\n",
+		},
+		{
+			name:       "unterminated quote",
+			line:       "echo 'a",
+			wantStatus: 2,
+			want:       "unexpected EOF while looking for matching",
+		},
+		{
+			name:       "and list stops on failure",
+			line:       "cat /nope && echo reached",
+			wantStatus: 1,
+			wantAbsent: "reached",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestShell(t, key, cert)
+
+			output, status := s.Run(tt.line)
+
+			require.Equal(t, tt.wantStatus, status, output)
+			require.Contains(t, output, tt.want)
+			if tt.wantAbsent != "" {
+				require.NotContains(t, output, tt.wantAbsent)
+			}
+			if tt.check != nil {
+				tt.check(t, s)
+			}
+		})
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	words, err := SplitWords(`-c 'tmsh list ltm pool; ls "/var/config"'`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"-c", `tmsh list ltm pool; ls "/var/config"`}, words)
+
+	_, err = SplitWords("a; b")
+	require.Error(t, err)
+}
+
+func TestTmshRequests(t *testing.T) {
+	type request struct {
+		method string
+		uri    string
+		body   string
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		current      string
+		wantRequests []request
+		wantError    string
+	}{
+		{
+			name: "create client-ssl profile",
+			args: []string{"create", "ltm", "profile", "client-ssl", "app", "cert-key-chain", "replace-all-with", "{", "default", "{", "cert", "app.crt", "key", "app.key", "}", "}", "ciphers", "ECDHE+AESGCM"},
+			wantRequests: []request{
+				{"POST", "/mgmt/tm/ltm/profile/client-ssl?ver=17.0.0.0", `{"certKeyChain":[{"cert":"/Common/app.crt","key":"/Common/app.key","name":"default"}],"ciphers":"ECDHE+AESGCM","name":"/Common/app"}`},
+			},
+		},
+		{
+			name: "glued braces",
+			args: []string{"create", "ltm", "cipher", "group", "/Prod/g", "allow", "{r1", "/Prod/r2}"},
+			wantRequests: []request{
+				{"POST", "/mgmt/tm/ltm/cipher/group?ver=17.0.0.0", `{"allow":[{"name":"/Common/r1"},{"name":"/Prod/r2"}],"name":"/Prod/g"}`},
+			},
+		},
+		{
+			name:    "add to a collection",
+			args:    []string{"modify", "auth", "user", "bob", "partition-access", "add", "{", "Prod", "{", "role", "manager", "}", "}"},
+			current: `{"name":"bob","partitionAccess":[{"name":"Common","role":"guest"},{"name":"Prod","role":"guest"}]}`,
+			wantRequests: []request{
+				{"GET", "/mgmt/tm/auth/user/bob?ver=17.0.0.0", ""},
+				{"PATCH", "/mgmt/tm/auth/user/bob?ver=17.0.0.0", `{"partitionAccess":[{"name":"Common","role":"guest"},{"name":"Prod","role":"manager"}]}`},
+			},
+		},
+		{
+			name:    "delete from a collection",
+			args:    []string{"modify", "ltm", "cipher", "group", "g", "allow", "delete", "{", "f5-aes", "}"},
+			current: `{"allow":[{"name":"f5-aes","partition":"Common","fullPath":"/Common/f5-aes"},{"name":"r1","partition":"Prod","fullPath":"/Prod/r1"}]}`,
+			wantRequests: []request{
+				{"GET", "/mgmt/tm/ltm/cipher/group/~Common~g?ver=17.0.0.0", ""},
+				{"PATCH", "/mgmt/tm/ltm/cipher/group/~Common~g?ver=17.0.0.0", `{"allow":[{"fullPath":"/Prod/r1","name":"/Prod/r1","partition":"Prod"}]}`},
+			},
+		},
+		{
+			name: "modify singleton",
+			args: []string{"modify", "auth", "password-policy", "minimum-length", "12", "policy-enforcement", "enabled"},
+			wantRequests: []request{
+				{"PATCH", "/mgmt/tm/auth/password-policy?ver=17.0.0.0", `{"minimumLength":12,"policyEnforcement":"enabled"}`},
+			},
+		},
+		{
+			name: "delete objects",
+			args: []string{"delete", "sys", "folder", "/Prod/a.app", "/Prod/b.app"},
+			wantRequests: []request{
+				{"DELETE", "/mgmt/tm/sys/folder/~Prod~a.app?ver=17.0.0.0", ""},
+				{"DELETE", "/mgmt/tm/sys/folder/~Prod~b.app?ver=17.0.0.0", ""},
+			},
+		},
+		{
+			name: "install certificate",
+			args: []string{"install", "sys", "crypto", "cert", "app.crt", "from-local-file", "/var/config/rest/downloads/app.crt"},
+			wantRequests: []request{
+				{"POST", "/mgmt/tm/sys/crypto/cert?ver=17.0.0.0", `{"command":"install","from-local-file":"/var/config/rest/downloads/app.crt","name":"app.crt"}`},
+			},
+		},
+		{
+			name:      "unknown property",
+			args:      []string{"modify", "ltm", "cipher", "rule", "r1", "ciphers", "DEFAULT"},
+			wantError: `Syntax Error: "ciphers" unknown property`,
+		},
+		{
+			name:      "invalid number",
+			args:      []string{"modify", "auth", "password-policy", "minimum-length", "eight"},
+			wantError: `Syntax Error: "eight" invalid number`,
+		},
+		{
+			name:      "mismatched braces",
+			args:      []string{"create", "ltm", "cipher", "group", "g", "allow", "{", "r1"},
+			wantError: "Syntax Error: mismatched braces",
+		},
+		{
+			name:      "unmodeled type",
+			args:      []string{"create", "ltm", "virtual", "vs"},
+			wantError: "ltm virtual objects cannot be created in the mock",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := cache.New("")
+			require.NoError(t, err)
+			s := New(store, "17.0.0.0")
+
+			var requests []request
+			s.Requester = func(method, uri string, body []byte) (int, []byte) {
+				requests = append(requests, request{method, uri, string(body)})
+				if method == "GET" {
+					return 200, []byte(tt.current)
+				}
+				return 200, []byte("{}")
+			}
+
+			output, status := s.RunTmsh(tt.args)
+
+			if tt.wantError != "" {
+				require.Equal(t, 1, status)
+				require.Equal(t, tt.wantError+"\n", output)
+				require.Empty(t, requests)
+				return
+			}
+			require.Equal(t, 0, status, output)
+			require.Empty(t, output)
+			require.Equal(t, tt.wantRequests, requests)
+		})
+	}
+}
+
+func TestReceiveSCP(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		restricted bool
+		input      string
+		wantStatus int
+		want       string
+		wantFiles  map[string]string
+	}{
+		{
+			name:      "into the uploads directory",
+			args:      []string{"-t", "/var/config/rest/downloads/scp/"},
+			input:     "C0644 5 app.crt\nhello\x00",
+			want:      "\x00\x00\x00",
+			wantFiles: map[string]string{"/var/config/rest/downloads/scp/app.crt": "hello"},
+		},
+		{
+			name:      "relative target",
+			args:      []string{"-t", "scp/renamed.crt"},
+			input:     "T1700000000 0 1700000000 0\nC0644 2 app.crt\nhi\x00",
+			want:      "\x00\x00\x00\x00",
+			wantFiles: map[string]string{"/var/config/rest/downloads/scp/renamed.crt": "hi"},
+		},
+		{
+			name:      "replace",
+			args:      []string{"-d", "-t", "scp/replaced"},
+			input:     "C0644 3 app.crt\nold\x00C0644 3 app.crt\nnew\x00",
+			want:      "\x00\x00\x00\x00\x00",
+			wantFiles: map[string]string{"/var/config/rest/downloads/scp/replaced/app.crt": "new"},
+		},
+		{
+			name:      "recursive",
+			args:      []string{"-r", "-d", "-t", "scp"},
+			input:     "D0755 0 certs\nC0644 1 a\na\x00C0644 0 empty\n\x00E\n",
+			want:      "\x00\x00\x00\x00\x00\x00\x00",
+			wantFiles: map[string]string{"/var/config/rest/downloads/scp/certs/a": "a", "/var/config/rest/downloads/scp/certs/empty": ""},
+		},
+		{
+			name:       "outside of the uploads directory",
+			args:       []string{"-t", "/certs/Common/"},
+			input:      "C0644 5 app.crt\n",
+			wantStatus: 1,
+			want:       "\x00\x01scp: /certs/Common/app.crt: Permission denied\n",
+		},
+		{
+			name:       "invalid name",
+			args:       []string{"-t", "."},
+			input:      "C0644 5 ../app.crt\n",
+			wantStatus: 1,
+			want:       "\x00\x01scp: protocol error: unexpected filename: ../app.crt\n",
+		},
+		{
+			name:       "downloads",
+			args:       []string{"-f", "notes.txt"},
+			wantStatus: 1,
+			want:       "\x01scp: only uploads are supported\n",
+		},
+		{
+			name:       "restricted",
+			args:       []string{"-t", "."},
+			restricted: true,
+			wantStatus: 1,
+			want:       "\x01scp: file transfers are not allowed for this user\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestShell(t, nil, nil)
+			s.Restricted = tt.restricted
+
+			var out bytes.Buffer
+			status := s.ReceiveSCP(strings.NewReader(tt.input), &out, tt.args)
+
+			require.Equal(t, tt.wantStatus, status)
+			require.Equal(t, tt.want, out.String())
+			for p, content := range tt.wantFiles {
+				got, err := s.Store.Fs.ReadFile(p)
+				require.NoError(t, err, p)
+				require.Equal(t, content, string(got))
+			}
+		})
+	}
+}
+
+func TestServeSFTP(t *testing.T) {
+	packet := func(kind byte, fields ...any) []byte {
+		b := []byte{kind}
+		for _, field := range fields {
+			switch v := field.(type) {
+			case uint32:
+				b = binary.BigEndian.AppendUint32(b, v)
+			case uint64:
+				b = binary.BigEndian.AppendUint64(b, v)
+			case string:
+				b = append(b, sftpString([]byte(v))...)
+			}
+		}
+		return append(binary.BigEndian.AppendUint32(nil, uint32(len(b))), b...)
+	}
+	status := func(id, code uint32, message string) []byte {
+		return packet(sftpStatus, id, code, message, "")
+	}
+	attrs := func(size uint64, mode uint32) []any {
+		return []any{uint32(sftpAttrSize | sftpAttrPermissions), size, mode}
+	}
+
+	requests := [][]byte{
+		packet(sftpInit, uint32(3)),
+		packet(sftpRealpath, uint32(1), "."),
+		packet(sftpOpen, uint32(2), "sftp/app.crt", uint32(0x1a), uint32(0)),
+		packet(sftpWrite, uint32(3), "1", uint64(0), "hel"),
+		packet(sftpWrite, uint32(4), "1", uint64(3), "lo"),
+		packet(sftpClose, uint32(5), "1"),
+		packet(sftpStat, uint32(6), "/var/config/rest/downloads/sftp/app.crt"),
+		packet(sftpOpen, uint32(7), "/certs/Common/app.crt", uint32(0x1a), uint32(0)),
+		packet(sftpOpen, uint32(8), "notes.txt", uint32(0x01), uint32(0)),
+		packet(sftpRead, uint32(9), "2", uint64(6), uint32(100)),
+		packet(sftpRead, uint32(10), "2", uint64(100), uint32(100)),
+		packet(sftpOpen, uint32(11), "sftp/empty", uint32(0x1a), uint32(0)),
+		packet(sftpClose, uint32(12), "3"),
+		packet(sftpOpendir, uint32(13), "sftp"),
+		packet(sftpReaddir, uint32(14), "4"),
+		packet(sftpReaddir, uint32(15), "4"),
+		packet(sftpRemove, uint32(16), "sftp/empty"),
+		packet(sftpStat, uint32(17), "sftp/empty"),
+		packet(18, uint32(18), "a", "b"),
+		packet(sftpOpen, uint32(19), "sftp/large", uint32(0x1a), uint32(0)),
+		packet(sftpWrite, uint32(20), "5", uint64(math.MaxUint64), "lo"),
+		packet(sftpWrite, uint32(21), "5", uint64(sftpMaxFile+1), "lo"),
+	}
+	want := [][]byte{
+		packet(sftpVersion, uint32(3)),
+		packet(sftpName, uint32(1), uint32(1), "/var/config/rest/downloads", "/var/config/rest/downloads", uint32(0x05), uint64(0), uint32(0o040755)),
+		packet(sftpHandle, uint32(2), "1"),
+		status(3, sftpOK, "Success"),
+		status(4, sftpOK, "Success"),
+		status(5, sftpOK, "Success"),
+		packet(sftpAttrs, append([]any{uint32(6)}, attrs(5, 0o100644)...)...),
+		status(7, sftpPermissionDenied, "Permission denied"),
+		packet(sftpHandle, uint32(8), "2"),
+		packet(sftpData, uint32(9), "beta\ngamma\n"),
+		status(10, sftpEOF, "End of file"),
+		packet(sftpHandle, uint32(11), "3"),
+		status(12, sftpOK, "Success"),
+		packet(sftpHandle, uint32(13), "4"),
+		packet(sftpName, slices.Concat([]any{uint32(14), uint32(2)},
+			[]any{"app.crt", "-rw-r--r--    1 root     root            5 Jan  1 00:00 app.crt"}, attrs(5, 0o100644),
+			[]any{"empty", "-rw-r--r--    1 root     root            0 Jan  1 00:00 empty"}, attrs(0, 0o100644))...),
+		status(15, sftpEOF, "End of file"),
+		status(16, sftpOK, "Success"),
+		status(17, sftpNoSuchFile, "No such file"),
+		status(18, sftpOpUnsupported, "Operation unsupported"),
+		packet(sftpHandle, uint32(19), "5"),
+		status(20, sftpFailure, "File too large"),
+		status(21, sftpFailure, "File too large"),
+	}
+
+	s := newTestShell(t, nil, nil)
+	var out bytes.Buffer
+	err := s.ServeSFTP(struct {
+		io.Reader
+		io.Writer
+	}{bytes.NewReader(bytes.Join(requests, nil)), &out})
+	require.NoError(t, err)
+
+	for i, response := range want {
+		require.Equal(t, response, out.Next(len(response)), "response %d", i)
+	}
+	require.Zero(t, out.Len())
+
+	content, err := s.Store.Fs.ReadFile("/var/config/rest/downloads/sftp/app.crt")
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(content))
+
+	s.Restricted = true
+	require.Error(t, s.ServeSFTP(struct {
+		io.Reader
+		io.Writer
+	}{bytes.NewReader(nil), &out}))
+}
+
+func TestInteractTmsh(t *testing.T) {
+	s := newTestShell(t, nil, nil)
+	input := "show sys version\nbash\ncd /var/config/rest/downloads\npwd\nexit\nlist ltm pool\n"
+
+	var out strings.Builder
+	require.NoError(t, s.InteractTmsh(strings.NewReader(input), &out, "admin"))
+
+	tmshPrompt := "admin@(localhost)(cfg-sync Standalone)(Active)(/Common)(tmos)# "
+	require.Equal(t, tmshPrompt+
+		"\nSys::Version\nMain Package\n  Product   BIG-IP\n  Version   17.0.0\n  Build     0.0.1\n  Edition   Final\n\n"+
+		tmshPrompt+
+		"[admin@localhost:Active:Standalone] / # "+
+		"[admin@localhost:Active:Standalone] downloads # /var/config/rest/downloads\n"+
+		"[admin@localhost:Active:Standalone] downloads # "+
+		tmshPrompt+"ltm pool /Common/api { }\n\nltm pool /Common/web { }\n\n"+
+		tmshPrompt+"\n", out.String())
+
+	s.Restricted = true
+	out.Reset()
+	require.NoError(t, s.InteractTmsh(strings.NewReader("bash\nquit\n"), &out, "operator"))
+	operatorPrompt := "operator@(localhost)(cfg-sync Standalone)(Active)(/Common)(tmos)# "
+	require.Equal(t, operatorPrompt+"Access denied: user (operator) does not have bash access\n"+operatorPrompt, out.String())
+}
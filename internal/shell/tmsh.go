@@ -0,0 +1,485 @@
+package shell
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/iilun/f5-mock/pkg/cache"
+)
+
+// tmsh runs a tmsh command given as arguments, or with -c as a single string
+func tmsh(s *Shell, args []string, io *stdio) int {
+	for len(args) > 0 && args[0] == "-q" {
+		args = args[1:]
+	}
+	if len(args) >= 1 && args[0] == "-c" {
+		if len(args) != 2 {
+			io.errorf("tmsh: -c requires a single command")
+			return 1
+		}
+		words, err := SplitWords(args[1])
+		if err != nil {
+			io.errorf("Syntax Error: %v", err)
+			return 1
+		}
+		args = words
+	}
+	if len(args) == 0 {
+		io.errorf("tmsh: interactive mode is not supported")
+		return 1
+	}
+
+	err := s.tmsh(args, &io.stdout)
+	if err != nil {
+		io.errorf("%v", err)
+		return 1
+	}
+	return 0
+}
+
+// RunTmsh runs a tmsh command, returning its output and exit status
+func (s *Shell) RunTmsh(args []string) (string, int) {
+	io := &stdio{}
+	status := tmsh(s, args, io)
+	return io.stderr.String() + io.stdout.String(), status
+}
+
+func (s *Shell) tmsh(args []string, out *strings.Builder) error {
+	switch args[0] {
+	case "list":
+		return s.tmshList(args[1:], out)
+	case "show":
+		return s.tmshShow(args[1:], out)
+	case "create":
+		return s.tmshCreate(args[1:])
+	case "modify":
+		return s.tmshModify(args[1:])
+	case "delete":
+		return s.tmshDelete(args[1:])
+	case "install":
+		return s.tmshInstall(args[1:])
+	case "save":
+		return s.tmshSave(args[1:], out)
+	case "load":
+		return s.tmshLoad(args[1:], out)
+	default:
+		return fmt.Errorf("Syntax Error: \"%s\" unknown command", args[0])
+	}
+}
+
+// tmshKind splits the words of a command into the modeled type they select, which may be a module like ltm,
+// and the object names. full reports whether the type is a complete one, that can be followed by names.
+func tmshKind(words []string) (kind string, names []string, full bool, err error) {
+	i := 0
+	for ; i < len(words); i++ {
+		candidate := strings.TrimPrefix(strings.Join(append(slices.Clone(words[:i]), words[i]), " "), "/")
+		if !slices.ContainsFunc(cache.TmshKinds, func(k string) bool { return k == candidate || strings.HasPrefix(k, candidate+" ") }) {
+			break
+		}
+		kind = candidate
+	}
+	full = slices.Contains(cache.TmshKinds, kind)
+	if i < len(words) && !full {
+		return "", nil, false, fmt.Errorf("Syntax Error: \"%s\" unknown property", words[i])
+	}
+	return kind, words[i:], full, nil
+}
+
+// tmshList renders objects as tmsh configuration
+func (s *Shell) tmshList(words []string, out *strings.Builder) error {
+	// Every property is always listed
+	words = slices.DeleteFunc(slices.Clone(words), func(w string) bool { return w == "all-properties" })
+
+	kind, names, _, err := tmshKind(words)
+	if err != nil {
+		return err
+	}
+
+	// tmsh fails on the first requested object that does not exist
+	for _, name := range names {
+		count, err := s.Store.ListTmsh(&strings.Builder{}, kind, name)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("01020036:3: The requested %s (%s) was not found.", kind, tmshFullPath(name))
+		}
+	}
+
+	_, err = s.Store.ListTmsh(out, kind, names...)
+	return err
+}
+
+// tmshShow displays runtime information, only sys version being modeled
+func (s *Shell) tmshShow(words []string, out *strings.Builder) error {
+	if len(words) != 2 || words[0] != "sys" || words[1] != "version" {
+		return fmt.Errorf("Syntax Error: \"%s\" unknown property", strings.Join(words, " "))
+	}
+
+	version, build := s.Version, "0.0.1"
+	if parts := strings.Split(s.Version, "."); len(parts) > 3 {
+		version = strings.Join(parts[:3], ".")
+		build = parts[3] + ".0.1"
+	}
+
+	out.WriteString("\nSys::Version\nMain Package\n")
+	fmt.Fprintf(out, "  %-9s %s\n", "Product", "BIG-IP")
+	fmt.Fprintf(out, "  %-9s %s\n", "Version", version)
+	fmt.Fprintf(out, "  %-9s %s\n", "Build", build)
+	fmt.Fprintf(out, "  %-9s %s\n", "Edition", "Final")
+	out.WriteString("\n")
+	return nil
+}
+
+// lookupTmshType returns the type selected by the first words of a command that changes objects, and the remaining words
+func lookupTmshType(verb string, words []string) (tmshType, []string, error) {
+	for n := min(3, len(words)); n > 0; n-- {
+		kind := strings.TrimPrefix(strings.Join(words[:n], " "), "/")
+		if t, found := tmshTypes[kind]; found {
+			return t, words[n:], nil
+		}
+		if slices.Contains(cache.TmshKinds, kind) {
+			return tmshType{}, nil, fmt.Errorf("%s objects cannot be %s in the mock", kind, verbPastTenses[verb])
+		}
+	}
+	if len(words) == 0 {
+		return tmshType{}, nil, fmt.Errorf("Syntax Error: \"%s\" requires an object type", verb)
+	}
+	return tmshType{}, nil, fmt.Errorf("Syntax Error: \"%s\" unknown property", strings.Join(words[:min(2, len(words))], " "))
+}
+
+var verbPastTenses = map[string]string{"create": "created", "modify": "modified", "delete": "deleted"}
+
+// lookupTmshObject returns the type and name of the object of a create or modify command, and its properties
+func lookupTmshObject(verb string, words []string) (tmshType, string, []tmshItem, error) {
+	t, words, err := lookupTmshType(verb, words)
+	if err != nil {
+		return tmshType{}, "", nil, err
+	}
+
+	name := ""
+	if t.name != nil {
+		if len(words) == 0 || strings.ContainsAny(words[0], "{}") {
+			return tmshType{}, "", nil, fmt.Errorf("Syntax Error: %s requires an object name", verb)
+		}
+		name, words = words[0], words[1:]
+	}
+
+	items, err := parseTmshItems(words)
+	if err != nil {
+		return tmshType{}, "", nil, err
+	}
+	return t, name, items, nil
+}
+
+func (s *Shell) tmshCreate(words []string) error {
+	t, name, items, err := lookupTmshObject("create", words)
+	if err != nil {
+		return err
+	}
+	if t.name == nil {
+		return fmt.Errorf("Syntax Error: \"create\" is not supported for this object")
+	}
+
+	restName, _ := t.name(name)
+	body := map[string]any{"name": restName}
+	err = s.applyTmshProperties(t, items, body, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.request(http.MethodPost, t.collection, body)
+	return err
+}
+
+func (s *Shell) tmshModify(words []string) error {
+	t, name, items, err := lookupTmshObject("modify", words)
+	if err != nil {
+		return err
+	}
+
+	uri := t.collection
+	if t.name != nil {
+		_, uriName := t.name(name)
+		uri += "/" + uriName
+	}
+
+	// add and delete change the current collections
+	var current map[string]any
+	if slices.ContainsFunc(items, func(item tmshItem) bool { return item.word == "add" || item.word == "delete" }) {
+		response, err := s.request(http.MethodGet, uri, nil)
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(response, &current)
+		if err != nil {
+			return err
+		}
+	}
+
+	body := map[string]any{}
+	err = s.applyTmshProperties(t, items, body, current)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.request(http.MethodPatch, uri, body)
+	return err
+}
+
+func (s *Shell) tmshDelete(words []string) error {
+	t, names, err := lookupTmshType("delete", words)
+	if err != nil {
+		return err
+	}
+	if t.name == nil {
+		return fmt.Errorf("Syntax Error: \"delete\" is not supported for this object")
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("Syntax Error: delete requires an object name")
+	}
+
+	for _, name := range names {
+		_, uriName := t.name(name)
+		_, err = s.request(http.MethodDelete, t.collection+"/"+uriName, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTmshProperties sets the REST fields of properties in body. Collections are changed from the current
+// REST object for add and delete.
+func (s *Shell) applyTmshProperties(t tmshType, items []tmshItem, body map[string]any, current map[string]any) error {
+	for i := 0; i < len(items); i++ {
+		name := items[i].word
+		property, found := t.properties[name]
+		if !found || items[i].braced && property.collection == nil {
+			return fmt.Errorf("Syntax Error: \"%s\" unknown property", name)
+		}
+
+		// Collections accept a bare block, or an operation followed by a block
+		var value tmshItem
+		if items[i].braced {
+			value = tmshItem{word: "replace-all-with", block: items[i].block, braced: true}
+		} else {
+			if i+1 >= len(items) {
+				return fmt.Errorf("Syntax Error: \"%s\" requires a value", name)
+			}
+			i++
+			value = items[i]
+		}
+
+		if property.field == "" {
+			continue
+		}
+
+		if property.collection == nil {
+			if value.braced {
+				return fmt.Errorf("Syntax Error: invalid value for \"%s\"", name)
+			}
+			converted, err := property.scalar(value.word)
+			if err != nil {
+				return err
+			}
+			body[property.field] = converted
+			continue
+		}
+
+		collection, err := tmshCollectionValue(property, value, current)
+		if err != nil {
+			return err
+		}
+		body[property.field] = collection
+	}
+	return nil
+}
+
+// tmshCollectionValue returns the REST items of a collection property after an operation
+func tmshCollectionValue(property tmshProperty, value tmshItem, current map[string]any) ([]map[string]any, error) {
+	if value.word == "none" && !value.braced {
+		return []map[string]any{}, nil
+	}
+	if !value.braced {
+		return nil, fmt.Errorf("Syntax Error: invalid value for \"%s\"", value.word)
+	}
+
+	switch value.word {
+	case "replace-all-with":
+		return property.collection.items(value)
+	case "add", "delete":
+		// Existing items are keyed by their full path when they have one, like cipher rule references
+		var existing []map[string]any
+		if items, ok := current[property.field].([]any); ok {
+			for _, item := range items {
+				if restItem, ok := item.(map[string]any); ok {
+					if fullPath, ok := restItem["fullPath"].(string); ok {
+						restItem["name"] = fullPath
+					}
+					existing = append(existing, restItem)
+				}
+			}
+		}
+
+		var changed []map[string]any
+		if value.word == "add" {
+			var err error
+			changed, err = property.collection.items(value)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			for _, name := range value.words() {
+				changed = append(changed, map[string]any{"name": name})
+			}
+		}
+		for _, item := range changed {
+			key := property.collection.key(fmt.Sprint(item["name"]))
+			existing = slices.DeleteFunc(existing, func(e map[string]any) bool {
+				return property.collection.key(fmt.Sprint(e["name"])) == key
+			})
+		}
+		if value.word == "add" {
+			existing = append(existing, changed...)
+		}
+		if existing == nil {
+			existing = []map[string]any{}
+		}
+		return existing, nil
+	default:
+		return nil, fmt.Errorf("Syntax Error: \"%s\" unknown operation", value.word)
+	}
+}
+
+// tmshInstall installs certificates and keys from local files, like install sys crypto cert
+func (s *Shell) tmshInstall(words []string) error {
+	if len(words) < 3 || words[0] != "sys" || words[1] != "crypto" || (words[2] != "cert" && words[2] != "key") {
+		return fmt.Errorf("Syntax Error: \"%s\" unknown property", strings.Join(words, " "))
+	}
+	if len(words) < 4 {
+		return fmt.Errorf("Syntax Error: install requires an object name")
+	}
+
+	body := map[string]any{"command": "install", "name": words[3]}
+	for i := 4; i < len(words); i += 2 {
+		if i+1 >= len(words) {
+			return fmt.Errorf("Syntax Error: \"%s\" requires a value", words[i])
+		}
+		switch words[i] {
+		case "from-local-file":
+			body["from-local-file"] = s.abs(words[i+1])
+		case "passphrase":
+			body["passphrase"] = words[i+1]
+		default:
+			return fmt.Errorf("Syntax Error: \"%s\" unknown property", words[i])
+		}
+	}
+
+	_, err := s.request(http.MethodPost, "/mgmt/tm/sys/crypto/"+words[2], body)
+	return err
+}
+
+// configFiles are the files tmsh save and load sys config report writing and reading
+var configFiles = []string{"/config/bigip.conf", "/config/bigip_base.conf", "/config/bigip_user.conf"}
+
+// tmshSave saves the running configuration to /config/bigip.conf, or to a UCS archive with save sys ucs
+func (s *Shell) tmshSave(words []string, out *strings.Builder) error {
+	switch {
+	case len(words) == 2 && words[0] == "sys" && words[1] == "config":
+		_, err := s.request(http.MethodPost, "/mgmt/tm/sys/config", map[string]any{"command": "save"})
+		if err != nil {
+			return err
+		}
+
+		out.WriteString("Saving running configuration...\n")
+		for _, file := range configFiles {
+			fmt.Fprintf(out, "  %s\n", file)
+		}
+		return nil
+	case len(words) == 3 && words[0] == "sys" && words[1] == "ucs":
+		_, err := s.request(http.MethodPost, "/mgmt/tm/sys/ucs", map[string]any{"command": "save", "name": words[2]})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Saving active configuration...\n%s is saved.\n", ucsFile(words[2]))
+		return nil
+	default:
+		return fmt.Errorf("Syntax Error: \"%s\" unknown property", strings.Join(words, " "))
+	}
+}
+
+// tmshLoad reverts the running configuration to the saved one, or loads a UCS archive with load sys ucs
+func (s *Shell) tmshLoad(words []string, out *strings.Builder) error {
+	switch {
+	case len(words) == 2 && words[0] == "sys" && words[1] == "config":
+		_, err := s.request(http.MethodPost, "/mgmt/tm/sys/config", map[string]any{"command": "load"})
+		if err != nil {
+			return err
+		}
+
+		out.WriteString("Loading configuration...\n")
+		for _, file := range configFiles {
+			fmt.Fprintf(out, "  %s\n", file)
+		}
+		return nil
+	case len(words) == 3 && words[0] == "sys" && words[1] == "ucs":
+		_, err := s.request(http.MethodPost, "/mgmt/tm/sys/ucs", map[string]any{"command": "load", "name": words[2]})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "%s is loaded.\n", ucsFile(words[2]))
+		return nil
+	default:
+		return fmt.Errorf("Syntax Error: \"%s\" unknown property", strings.Join(words, " "))
+	}
+}
+
+// ucsFile returns the path of a UCS archive, the name given to tmsh being relative to /var/local/ucs and
+// the .ucs extension being optional
+func ucsFile(name string) string {
+	if !strings.HasPrefix(name, "/") {
+		name = cache.UCSDir + "/" + name
+	}
+	if !strings.HasSuffix(name, ".ucs") {
+		name += ".ucs"
+	}
+	return name
+}
+
+// request sends a REST request, returning the body of successful responses and the message of errors
+func (s *Shell) request(method, uri string, body any) ([]byte, error) {
+	if s.Requester == nil {
+		return nil, errors.New("configuration changes are not available in this shell")
+	}
+
+	var content []byte
+	if body != nil {
+		var err error
+		content, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.Version != "" {
+		uri += "?ver=" + s.Version
+	}
+
+	status, response := s.Requester(method, uri, content)
+	if status >= http.StatusBadRequest {
+		var f5Err struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(response, &f5Err) == nil && f5Err.Message != "" {
+			return nil, errors.New(f5Err.Message)
+		}
+		return nil, errors.New(http.StatusText(status))
+	}
+	return response, nil
+}
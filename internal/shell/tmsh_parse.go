@@ -0,0 +1,99 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tmshItem is a word of a tmsh command, with the { } block following it if any
+type tmshItem struct {
+	word  string
+	block []tmshItem
+	// braced is set for items followed by a block, which may be empty
+	braced bool
+}
+
+// parseTmshItems groups the arguments of a tmsh command into words and blocks. Braces are split from the
+// words they are glued to, like in {a b}.
+func parseTmshItems(args []string) ([]tmshItem, error) {
+	var words []string
+	for _, arg := range args {
+		for arg != "" {
+			i := strings.IndexAny(arg, "{}")
+			switch {
+			case i < 0:
+				words = append(words, arg)
+				arg = ""
+			case i > 0:
+				words = append(words, arg[:i])
+				arg = arg[i:]
+			default:
+				words = append(words, arg[:1])
+				arg = arg[1:]
+			}
+		}
+	}
+
+	items, rest, err := parseTmshBlock(words)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("Syntax Error: mismatched braces")
+	}
+	return items, nil
+}
+
+// parseTmshBlock parses words up to the closing brace of the current block, returning the remaining words
+// after it
+func parseTmshBlock(words []string) ([]tmshItem, []string, error) {
+	var items []tmshItem
+	for len(words) > 0 {
+		word := words[0]
+		words = words[1:]
+		switch word {
+		case "}":
+			return items, append([]string{"}"}, words...), nil
+		case "{":
+			block, rest, err := parseTmshBlock(words)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(rest) == 0 {
+				return nil, nil, fmt.Errorf("Syntax Error: mismatched braces")
+			}
+			words = rest[1:]
+			if len(items) == 0 || items[len(items)-1].braced {
+				// Anonymous blocks hold lists, like the value of allow { a b }
+				items = append(items, tmshItem{block: block, braced: true})
+				continue
+			}
+			items[len(items)-1].block = block
+			items[len(items)-1].braced = true
+		default:
+			items = append(items, tmshItem{word: word})
+		}
+	}
+	return items, nil, nil
+}
+
+// words returns the words of a block, like the names of a list
+func (item tmshItem) words() []string {
+	var words []string
+	for _, i := range item.block {
+		words = append(words, i.word)
+	}
+	return words
+}
+
+// properties returns the name and value pairs of a block, like the role of a partition access
+func (item tmshItem) properties() (map[string]string, error) {
+	properties := map[string]string{}
+	for i := 0; i < len(item.block); i += 2 {
+		if i+1 >= len(item.block) || item.block[i].braced || item.block[i+1].braced {
+			return nil, fmt.Errorf("Syntax Error: invalid value for \"%s\"", item.word)
+		}
+		properties[item.block[i].word] = item.block[i+1].word
+	}
+	return properties, nil
+}
@@ -0,0 +1,199 @@
+package shell
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tmshType maps a tmsh object type to its iControl REST collection, tmsh commands being run as REST requests
+type tmshType struct {
+	collection string
+	// name returns the REST name of an object, and its name in item URIs. Singletons have no name.
+	name       func(name string) (restName, uriName string)
+	properties map[string]tmshProperty
+}
+
+// tmshProperty maps a tmsh property to a REST field. Properties of collection items only have a scalar value.
+type tmshProperty struct {
+	// field is the REST field, properties without field being accepted and ignored
+	field string
+	// scalar converts single word values
+	scalar func(word string) (any, error)
+	// collection converts { } values, which can be added to or deleted from with add and delete
+	collection *tmshCollection
+}
+
+// tmshCollection converts the blocks of a collection property into REST items, each having a name
+type tmshCollection struct {
+	items func(block tmshItem) ([]map[string]any, error)
+	// key normalizes names to compare items
+	key func(name string) string
+}
+
+// tmshFullPath returns the full path of an object, names without partition being in /Common like in the
+// default tmsh context
+func tmshFullPath(name string) string {
+	if strings.HasPrefix(name, "/") {
+		return name
+	}
+	return "/Common/" + name
+}
+
+func objectName(name string) (string, string) {
+	fullPath := tmshFullPath(name)
+	return fullPath, strings.ReplaceAll(fullPath, "/", "~")
+}
+
+func plainName(name string) (string, string) {
+	return name, name
+}
+
+func stringValue(word string) (any, error) {
+	return word, nil
+}
+
+func intValue(word string) (any, error) {
+	value, err := strconv.Atoi(word)
+	if err != nil {
+		return nil, fmt.Errorf("Syntax Error: \"%s\" invalid number", word)
+	}
+	return value, nil
+}
+
+// referenceValue returns the full path of a referenced object, none being kept
+func referenceValue(word string) (any, error) {
+	if word == "none" {
+		return word, nil
+	}
+	return tmshFullPath(word), nil
+}
+
+// referenceList is a collection of objects given by name, like the cipher rules of a group
+var referenceList = &tmshCollection{
+	items: func(block tmshItem) ([]map[string]any, error) {
+		var items []map[string]any
+		for _, item := range block.block {
+			if item.braced {
+				return nil, fmt.Errorf("Syntax Error: invalid value for \"%s\"", item.word)
+			}
+			items = append(items, map[string]any{"name": tmshFullPath(item.word)})
+		}
+		return items, nil
+	},
+	key: tmshFullPath,
+}
+
+// namedCollection is a collection of items holding properties, like name { prop value }
+func namedCollection(properties map[string]tmshProperty) *tmshCollection {
+	return &tmshCollection{
+		items: func(block tmshItem) ([]map[string]any, error) {
+			var items []map[string]any
+			for _, item := range block.block {
+				values, err := item.properties()
+				if err != nil {
+					return nil, err
+				}
+				restItem := map[string]any{"name": item.word}
+				for name, word := range values {
+					property, found := properties[name]
+					if !found {
+						return nil, fmt.Errorf("Syntax Error: \"%s\" unknown property", name)
+					}
+					restItem[property.field], err = property.scalar(word)
+					if err != nil {
+						return nil, err
+					}
+				}
+				items = append(items, restItem)
+			}
+			return items, nil
+		},
+		key: func(name string) string { return name },
+	}
+}
+
+// tmshTypes are the types that can be created, modified and deleted. Their properties follow the tmsh names.
+var tmshTypes = map[string]tmshType{
+	"ltm profile client-ssl": {
+		collection: "/mgmt/tm/ltm/profile/client-ssl",
+		name:       objectName,
+		properties: map[string]tmshProperty{
+			"cert":          {field: "cert", scalar: referenceValue},
+			"key":           {field: "key", scalar: referenceValue},
+			"passphrase":    {field: "passphrase", scalar: stringValue},
+			"ciphers":       {field: "ciphers", scalar: stringValue},
+			"cipher-group":  {field: "cipherGroup", scalar: referenceValue},
+			"defaults-from": {field: "defaultsFrom", scalar: referenceValue},
+			"cert-key-chain": {field: "certKeyChain", collection: namedCollection(map[string]tmshProperty{
+				"cert":       {field: "cert", scalar: referenceValue},
+				"key":        {field: "key", scalar: referenceValue},
+				"chain":      {field: "chain", scalar: referenceValue},
+				"passphrase": {field: "passphrase", scalar: stringValue},
+			})},
+		},
+	},
+	"ltm cipher rule": {
+		collection: "/mgmt/tm/ltm/cipher/rule",
+		name:       objectName,
+		properties: map[string]tmshProperty{
+			"cipher":               {field: "cipher", scalar: stringValue},
+			"description":          {field: "description", scalar: stringValue},
+			"dh-groups":            {field: "dhGroups", scalar: stringValue},
+			"signature-algorithms": {field: "signatureAlgorithms", scalar: stringValue},
+		},
+	},
+	"ltm cipher group": {
+		collection: "/mgmt/tm/ltm/cipher/group",
+		name:       objectName,
+		properties: map[string]tmshProperty{
+			"allow":       {field: "allow", collection: referenceList},
+			"exclude":     {field: "exclude", collection: referenceList},
+			"require":     {field: "require", collection: referenceList},
+			"ordering":    {field: "ordering", scalar: stringValue},
+			"description": {field: "description", scalar: stringValue},
+		},
+	},
+	"auth user": {
+		collection: "/mgmt/tm/auth/user",
+		name:       plainName,
+		properties: map[string]tmshProperty{
+			"password":    {field: "password", scalar: stringValue},
+			"description": {field: "description", scalar: stringValue},
+			"partition-access": {field: "partitionAccess", collection: namedCollection(map[string]tmshProperty{
+				"role": {field: "role", scalar: stringValue},
+			})},
+			// Users always get the shell of the mock
+			"shell": {},
+		},
+	},
+	"auth partition": {
+		collection: "/mgmt/tm/auth/partition",
+		name:       plainName,
+		properties: map[string]tmshProperty{
+			"description":          {field: "description", scalar: stringValue},
+			"default-route-domain": {field: "defaultRouteDomain", scalar: intValue},
+		},
+	},
+	"auth password-policy": {
+		collection: "/mgmt/tm/auth/password-policy",
+		properties: map[string]tmshProperty{
+			"policy-enforcement": {field: "policyEnforcement", scalar: stringValue},
+			"minimum-length":     {field: "minimumLength", scalar: intValue},
+			"required-lowercase": {field: "requiredLowercase", scalar: intValue},
+			"required-uppercase": {field: "requiredUppercase", scalar: intValue},
+			"required-numeric":   {field: "requiredNumeric", scalar: intValue},
+			"required-special":   {field: "requiredSpecial", scalar: intValue},
+			"max-duration":       {field: "maxDuration", scalar: intValue},
+			"max-login-failures": {field: "maxLoginFailures", scalar: intValue},
+			"lockout-duration":   {field: "lockoutDuration", scalar: intValue},
+		},
+	},
+	"sys folder": {
+		collection: "/mgmt/tm/sys/folder",
+		name:       objectName,
+		properties: map[string]tmshProperty{
+			"description": {field: "description", scalar: stringValue},
+		},
+	},
+}
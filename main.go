@@ -1,53 +1,161 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"github.com/iilun/f5-mock/internal/config"
 	"github.com/iilun/f5-mock/internal/handlers"
 	"github.com/iilun/f5-mock/internal/log"
+	"github.com/iilun/f5-mock/internal/reload"
+	"github.com/iilun/f5-mock/internal/server"
+	"github.com/iilun/f5-mock/internal/shell"
 	"github.com/iilun/f5-mock/pkg/cache"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
 )
 
 func main() {
 
-	logger := log.New(os.Getenv("F5_DEBUG") != "")
+	configPath := os.Getenv("F5_CONFIG_FILE")
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.New(false).Fatal(err.Error())
+	}
+
+	logger := log.New(cfg.Debug)
 	defer logger.Close()
 
-	_, err := cache.New(os.Getenv("F5_SEED_FILE"))
+	_, err = cache.New("")
 	if err != nil {
 		logger.Fatal(err.Error())
 	}
 
-	handlers.RegisterHandler(handlers.LoginHandler{}, logger)
-	handlers.RegisterHandler(handlers.AS3Handler{}, logger)
-	handlers.RegisterHandler(handlers.ClientSSLListHandler{}, logger)
-	handlers.RegisterHandler(handlers.ClientSSLHandler{}, logger)
-	handlers.RegisterHandler(handlers.UploadHandler{}, logger)
-	handlers.RegisterHandler(handlers.CryptoCertHandler{}, logger)
-	handlers.RegisterHandler(handlers.CryptoKeyHandler{}, logger)
-	handlers.RegisterHandler(handlers.SSLCertHandler{}, logger)
-	handlers.RegisterHandler(handlers.CipherGroupHandler{}, logger)
+	if cfg.SeedFile != "" {
+		seed, err := cache.LoadSeedData(cfg.SeedFile)
+		if err != nil {
+			logger.Fatal("invalid seed file: %v", err)
+		}
+		for _, warning := range seed.Warnings {
+			logger.Info("Seed file: %s", warning)
+		}
+		cache.GlobalCache.ApplySeed(seed, true)
+	}
+
+	// The seeded configuration is the saved one, which tmsh load sys config reverts to
+	err = cache.GlobalCache.SaveConfig(cfg.DefaultVersion)
+	if err != nil {
+		logger.Fatal("could not save configuration: %v", err)
+	}
 
-	certFilePath := os.Getenv("F5_CERT_PATH")
-	if certFilePath == "" {
-		certFilePath = "/etc/ssl/f5/cert.pem"
+	// The tmsh subcommand runs commands against the seeded store instead of serving it, without request logs
+	runTmsh := len(os.Args) > 1 && os.Args[1] == "tmsh"
+	if runTmsh {
+		logger = log.Nop()
 	}
 
-	keyFilePath := os.Getenv("F5_KEY_PATH")
-	if keyFilePath == "" {
-		keyFilePath = "/etc/ssl/f5/key.pem"
+	handlers.RegisterHandler(handlers.LoginHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.TokenListHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.TokenHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.AS3Handler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.ClientSSLListHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.ClientSSLHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.UploadHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.CryptoCertHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.CryptoKeyHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.CryptoCSRHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.CryptoCSRItemHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.CryptoPKCS12Handler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.SSLCertListHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.SSLCertHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.SSLCertBundleHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.CipherGroupListHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.CipherGroupHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.CipherRuleListHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.CipherRuleHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.UserListHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.UserHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.PasswordPolicyHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.PartitionListHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.PartitionHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.FolderListHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.FolderHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.ConfigExportHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.CipherEvaluationHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.UtilBashHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.SysConfigHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.UCSListHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.UCSHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.UCSDownloadHandler{}, logger, &cfg)
+	handlers.RegisterHandler(handlers.UCSUploadHandler{}, logger, &cfg)
+
+	if runTmsh {
+		os.Exit(tmsh(&cfg, os.Args[2:]))
 	}
 
-	port := os.Getenv("F5_PORT")
-	if port == "" {
-		port = "443"
+	listeners := cfg.ParsedListeners()
+
+	var tlsConfig *tls.Config
+	if slices.ContainsFunc(listeners, func(l config.Listener) bool { return l.Scheme == config.SchemeHTTPS }) {
+		tlsConfig, err = server.NewTLSConfig(cfg.TLS, logger)
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
 	}
 
-	hostname := os.Getenv("F5_HOST")
+	shutdownTimeout := time.Duration(cfg.ShutdownTimeout) * time.Second
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	err = http.ListenAndServeTLS(fmt.Sprintf("%s:%s", hostname, port), certFilePath, keyFilePath, nil)
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	reloader := reload.New(configPath, &cfg, logger)
+	go reloader.Watch(ctx, time.Duration(cfg.ReloadInterval)*time.Second, hangups)
+
+	if cfg.SSH.Listen != "" {
+		hostKey, err := server.LoadSSHHostKey(cfg.SSH.HostKeyPath, logger)
+		if err != nil {
+			logger.Fatal("invalid SSH host key: %v", err)
+		}
+		login := func(username, password string) (*shell.Shell, error) {
+			return handlers.LoginShell(&cfg, username, password)
+		}
+		go func() {
+			err := server.ServeSSH(ctx, cfg.SSH.Listen, hostKey, login, logger)
+			if err != nil {
+				logger.Fatal(err.Error())
+			}
+		}()
+	}
+
+	err = server.Serve(ctx, listeners, http.DefaultServeMux, tlsConfig, shutdownTimeout, logger)
 	if err != nil {
 		logger.Fatal(err.Error())
 	}
 }
+
+// tmsh runs the tmsh command given as arguments as the administrator, or reads commands from the standard input
+// without arguments. It returns the exit status.
+func tmsh(cfg *config.Config, args []string) int {
+	admin := handlers.AdminUser(cfg.Auth)
+	sh := shell.New(cache.GlobalCache, cfg.DefaultVersion)
+	sh.Requester = handlers.NewRequester(admin)
+
+	if len(args) > 0 {
+		output, status := sh.RunTmsh(args)
+		fmt.Print(output)
+		return status
+	}
+
+	err := sh.InteractTmsh(os.Stdin, os.Stdout, admin.Name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -9,10 +9,24 @@ import (
 )
 
 type MemoryCaches struct {
+	// Mutex serializes API requests with seed reloads
+	sync.Mutex
+
 	AuthTokens        *bigcache.BigCache
 	ClientSSLProfiles []*models.ClientSSLProfile
-	CipherGroups      []string
+	CipherRules       []*models.CipherRule
+	CipherGroups      []*models.CipherGroup
+	Users             []*models.User
+	LoginProviders    []*models.LoginProvider
+	PasswordPolicy    models.PasswordPolicy
+	Partitions        []*models.Partition
+	Folders           []*models.Folder
+	VirtualServers    []*models.VirtualServer
+	Pools             []*models.Pool
 	Fs                *MemoryFS
+
+	// savedConfig is the configuration saved last, nil until one is saved
+	savedConfig *Snapshot
 }
 
 var once sync.Once
@@ -25,25 +39,24 @@ func New(seedDatapath string) (*MemoryCaches, error) {
 	once.Do(func() {
 		var authCache *bigcache.BigCache
 
-		authCache, err = bigcache.New(context.Background(), bigcache.DefaultConfig(20*time.Minute))
+		authCache, err = bigcache.New(context.Background(), bigcache.DefaultConfig(MaxTokenTimeout*time.Second))
 		if err != nil {
 			return
 		}
 
 		var seedData SeedData
 		if seedDatapath != "" {
-			seedData, err = loadSeedData(seedDatapath)
+			seedData, err = LoadSeedData(seedDatapath)
 			if err != nil {
 				return
 			}
 		}
 
 		GlobalCache = &MemoryCaches{
-			AuthTokens:        authCache,
-			Fs:                NewFS(),
-			ClientSSLProfiles: seedData.ClientSSLProfiles,
-			CipherGroups:      seedData.CipherGroups,
+			AuthTokens: authCache,
+			Fs:         NewFS(),
 		}
+		GlobalCache.ApplySeed(seedData, true)
 	})
 	return GlobalCache, err
 }
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/iilun/f5-mock/internal/ciphers"
+	"github.com/iilun/f5-mock/pkg/models"
+)
+
+// Built-in cipher rules and groups exist in Common on every BIG-IP and cannot be modified
+var (
+	builtinCipherRules = []*models.CipherRule{
+		{Name: "f5-default", Partition: CommonPartition, Description: "Default cipher rule", Cipher: "DEFAULT"},
+		{Name: "f5-secure", Partition: CommonPartition, Description: "Secure cipher rule", Cipher: "ECDHE:RSA:!SSLV3:!RC4:!EXP:!DES:!3DES"},
+		{Name: "f5-ecc", Partition: CommonPartition, Description: "ECC cipher rule", Cipher: "ECDHE:ECDHE_ECDSA:!SSLV3:!RC4:!EXP:!DES:!3DES"},
+		{Name: "f5-aes", Partition: CommonPartition, Description: "AES cipher rule", Cipher: "AES:!SSLV3:!RC4:!EXP:!DES:!3DES"},
+	}
+	builtinCipherGroups = []*models.CipherGroup{
+		{Name: "f5-default", Partition: CommonPartition, Description: "Default cipher group", Ordering: "default", Allow: []models.CipherReference{{Name: "/Common/f5-default"}}},
+		{Name: "f5-secure", Partition: CommonPartition, Description: "Secure cipher group", Ordering: "default", Allow: []models.CipherReference{{Name: "/Common/f5-secure"}}},
+		{Name: "f5-ecc", Partition: CommonPartition, Description: "ECC cipher group", Ordering: "default", Allow: []models.CipherReference{{Name: "/Common/f5-ecc"}}},
+		{Name: "f5-aes", Partition: CommonPartition, Description: "AES cipher group", Ordering: "default", Allow: []models.CipherReference{{Name: "/Common/f5-aes"}}},
+	}
+)
+
+// CipherPath returns the full path of a cipher rule or group, names without partition being in Common
+func CipherPath(name string) string {
+	return tmshPath(name)
+}
+
+func cipherRulePath(rule *models.CipherRule) string {
+	return models.FullPath(partitionOrCommon(rule.Partition), "", rule.Name)
+}
+
+func cipherGroupPath(group *models.CipherGroup) string {
+	return models.FullPath(partitionOrCommon(group.Partition), "", group.Name)
+}
+
+func partitionOrCommon(partition string) string {
+	if partition == "" {
+		return CommonPartition
+	}
+	return partition
+}
+
+// IsBuiltinCipherRule reports whether a cipher rule, given by name or full path, is built-in
+func IsBuiltinCipherRule(name string) bool {
+	return slices.ContainsFunc(builtinCipherRules, func(rule *models.CipherRule) bool {
+		return cipherRulePath(rule) == CipherPath(name)
+	})
+}
+
+// IsBuiltinCipherGroup reports whether a cipher group, given by name or full path, is built-in
+func IsBuiltinCipherGroup(name string) bool {
+	return slices.ContainsFunc(builtinCipherGroups, func(group *models.CipherGroup) bool {
+		return cipherGroupPath(group) == CipherPath(name)
+	})
+}
+
+// ListCipherRules returns the built-in cipher rules followed by the other rules
+func (c *MemoryCaches) ListCipherRules() []*models.CipherRule {
+	return append(slices.Clone(builtinCipherRules), c.CipherRules...)
+}
+
+// FindCipherRule returns the cipher rule of the given name or full path, or nil
+func (c *MemoryCaches) FindCipherRule(name string) *models.CipherRule {
+	for _, rule := range c.ListCipherRules() {
+		if cipherRulePath(rule) == CipherPath(name) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// ListCipherGroups returns the built-in cipher groups followed by the other groups
+func (c *MemoryCaches) ListCipherGroups() []*models.CipherGroup {
+	return append(slices.Clone(builtinCipherGroups), c.CipherGroups...)
+}
+
+// FindCipherGroup returns the cipher group of the given name or full path, or nil
+func (c *MemoryCaches) FindCipherGroup(name string) *models.CipherGroup {
+	for _, group := range c.ListCipherGroups() {
+		if cipherGroupPath(group) == CipherPath(name) {
+			return group
+		}
+	}
+	return nil
+}
+
+// CipherRuleUsers returns the full paths of the cipher groups referencing a cipher rule
+func (c *MemoryCaches) CipherRuleUsers(name string) []string {
+	var users []string
+	for _, group := range c.CipherGroups {
+		for _, ref := range slices.Concat(group.Allow, group.Exclude, group.Require) {
+			if CipherPath(ref.Name) == CipherPath(name) {
+				users = append(users, cipherGroupPath(group))
+				break
+			}
+		}
+	}
+	return users
+}
+
+// CipherGroupUsers returns the full paths of the client-ssl profiles using a cipher group
+func (c *MemoryCaches) CipherGroupUsers(name string) []string {
+	var users []string
+	for _, profile := range c.ClientSSLProfiles {
+		if profile.CipherGroup != "" && CipherPath(profile.CipherGroup) == CipherPath(name) {
+			users = append(users, models.FullPath(profile.Partition, profile.SubPath, profile.Name))
+		}
+	}
+	return users
+}
+
+// EvaluateCipherRule expands a cipher rule into the suites it offers on a TMOS major version
+func (c *MemoryCaches) EvaluateCipherRule(name string, version int) ([]ciphers.Entry, error) {
+	rule := c.FindCipherRule(name)
+	if rule == nil {
+		return nil, fmt.Errorf("cipher rule %s does not exist", CipherPath(name))
+	}
+	entries, err := ciphers.Evaluate(rule.Cipher, version)
+	if err != nil {
+		return nil, fmt.Errorf("cipher rule %s: %w", CipherPath(name), err)
+	}
+	return entries, nil
+}
+
+// EvaluateCipherGroup expands a cipher group into the suites it offers on a TMOS major version: the suites of
+// the allowed rules, minus the excluded ones, that are in every required rule, sorted following the group ordering
+func (c *MemoryCaches) EvaluateCipherGroup(name string, version int) ([]ciphers.Entry, error) {
+	group := c.FindCipherGroup(name)
+	if group == nil {
+		return nil, fmt.Errorf("cipher group %s does not exist", CipherPath(name))
+	}
+
+	evaluate := func(refs []models.CipherReference) ([][]ciphers.Entry, error) {
+		var rules [][]ciphers.Entry
+		for _, ref := range refs {
+			entries, err := c.EvaluateCipherRule(ref.Name, version)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, entries)
+		}
+		return rules, nil
+	}
+
+	allowed, err := evaluate(group.Allow)
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := evaluate(group.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	required, err := evaluate(group.Require)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ciphers.Entry
+	for _, rule := range allowed {
+		entries = ciphers.Union(entries, rule)
+	}
+	for _, rule := range excluded {
+		entries = ciphers.Exclude(entries, rule)
+	}
+	for _, rule := range required {
+		entries = ciphers.Intersect(entries, rule)
+	}
+	ciphers.Sort(entries, group.Ordering)
+	return entries, nil
+}
+
+// EvaluateProfileCiphers expands the cipher group, or else the cipher string, of a client-ssl profile into the
+// suites it offers on a TMOS major version. Profiles without either use the DEFAULT cipher string.
+func (c *MemoryCaches) EvaluateProfileCiphers(profile *models.ClientSSLProfile, version int) ([]ciphers.Entry, error) {
+	if profile.CipherGroup != "" && profile.CipherGroup != "none" {
+		return c.EvaluateCipherGroup(profile.CipherGroup, version)
+	}
+	if profile.Ciphers != "" && profile.Ciphers != "none" {
+		return ciphers.Evaluate(profile.Ciphers, version)
+	}
+	return ciphers.Evaluate("DEFAULT", version)
+}
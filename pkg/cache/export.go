@@ -0,0 +1,334 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/iilun/f5-mock/pkg/models"
+)
+
+// TmshKinds are the types of the objects rendered as tmsh configuration
+var TmshKinds = []string{
+	"auth partition",
+	"auth password-policy",
+	"auth user",
+	"ltm cipher group",
+	"ltm cipher rule",
+	"ltm pool",
+	"ltm profile client-ssl",
+	"ltm virtual",
+	"sys folder",
+	"sys file ssl-cert",
+	"sys file ssl-key",
+}
+
+// ExportSCF renders the objects of the store as tmsh configuration, in the bigip.conf layout of the
+// emulated version. Stanzas are sorted like tmsh does, and read back by LoadSCF.
+func (c *MemoryCaches) ExportSCF(w io.Writer, version string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#TMSH-VERSION: %s\n\n", version)
+	for _, stanza := range c.tmshStanzas() {
+		stanza.render(&b, 0)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ListTmsh renders the objects of a type, like ltm profile client-ssl or ltm, as tmsh list does, every object
+// being rendered for an empty type. Names select objects by full path, or by name in Common. It returns the
+// number of objects rendered.
+func (c *MemoryCaches) ListTmsh(w io.Writer, kind string, names ...string) (int, error) {
+	var b strings.Builder
+	count := 0
+	for _, stanza := range c.tmshStanzas() {
+		header := strings.Join(stanza.words, " ")
+		if kind != "" && header != kind && !strings.HasPrefix(header, kind+" ") {
+			continue
+		}
+		name := stanza.words[len(stanza.words)-1]
+		if len(names) > 0 && !slices.ContainsFunc(names, func(n string) bool { return n == name || tmshPath(n) == name }) {
+			continue
+		}
+		stanza.render(&b, 0)
+		count++
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return count, err
+}
+
+// tmshStanzas returns the stanzas of the objects of the store, sorted by type then full path
+func (c *MemoryCaches) tmshStanzas() []*tmshEntry {
+	var partitions, policies, users, groups, rules, pools, profiles, virtuals, folders, certs, keys []*tmshEntry
+
+	for _, partition := range c.Partitions {
+		partitions = append(partitions, tmshStanza("auth partition "+partition.Name,
+			tmshProperty("default-route-domain", tmshRouteDomain(partition.DefaultRouteDomain)),
+			tmshProperty("description", partition.Description),
+		))
+	}
+	if c.PasswordPolicy != (models.PasswordPolicy{}) {
+		policies = append(policies, tmshPasswordPolicy(c.PasswordPolicy))
+	}
+	for _, user := range c.Users {
+		users = append(users, tmshUser(user))
+	}
+	for _, group := range c.CipherGroups {
+		groups = append(groups, tmshCipherGroup(group))
+	}
+	for _, rule := range c.CipherRules {
+		rules = append(rules, tmshStanza("ltm cipher rule "+cipherRulePath(rule),
+			tmshProperty("cipher", rule.Cipher),
+			tmshProperty("description", rule.Description),
+			tmshProperty("dh-groups", rule.DHGroups),
+			tmshProperty("signature-algorithms", rule.SignatureAlgorithms),
+		))
+	}
+	for _, pool := range c.Pools {
+		pools = append(pools, tmshPool(pool))
+	}
+	for _, profile := range c.ClientSSLProfiles {
+		profiles = append(profiles, tmshClientSSLProfile(profile))
+	}
+	for _, virtual := range c.VirtualServers {
+		virtuals = append(virtuals, tmshVirtualServer(virtual))
+	}
+	for _, folder := range c.Folders {
+		folders = append(folders, tmshStanza("sys folder "+folder.FullPath,
+			tmshProperty("description", folder.Description),
+		))
+	}
+	for _, certPath := range c.Fs.List(certsDir) {
+		fullPath := tmshPath(strings.TrimPrefix(certPath, certsDir))
+		certs = append(certs, tmshFileStanza("ssl-cert", fullPath, "certificate_d"))
+	}
+	for _, keyPath := range c.Fs.List(keysDir) {
+		fullPath := tmshPath(strings.TrimPrefix(keyPath, keysDir))
+		keys = append(keys, tmshFileStanza("ssl-key", fullPath, "certificate_key_d"))
+	}
+
+	var sorted []*tmshEntry
+	for _, stanzas := range [][]*tmshEntry{partitions, policies, users, groups, rules, pools, profiles, virtuals, folders, certs, keys} {
+		// Stanzas of a type are sorted by full path
+		slices.SortFunc(stanzas, func(a, b *tmshEntry) int {
+			return strings.Compare(a.words[len(a.words)-1], b.words[len(b.words)-1])
+		})
+		sorted = append(sorted, stanzas...)
+	}
+	return sorted
+}
+
+// render writes the entry and its block, entries with a non nil block being rendered with braces
+func (e *tmshEntry) render(b *strings.Builder, depth int) {
+	indent := strings.Repeat("    ", depth)
+
+	words := make([]string, 0, len(e.words))
+	for _, word := range e.words {
+		words = append(words, quoteTmsh(word))
+	}
+	b.WriteString(indent + strings.Join(words, " "))
+
+	switch {
+	case e.entries == nil:
+		b.WriteString("\n")
+	case len(e.entries) == 0:
+		b.WriteString(" { }\n")
+	default:
+		b.WriteString(" {\n")
+		for _, entry := range e.entries {
+			entry.render(b, depth+1)
+		}
+		b.WriteString(indent + "}\n")
+	}
+
+	// Top-level stanzas are separated by a blank line
+	if depth == 0 {
+		b.WriteString("\n")
+	}
+}
+
+// quoteTmsh quotes words that would not be read back as a single word
+func quoteTmsh(word string) string {
+	if word != "" && !strings.ContainsAny(word, " \t\r\n{}\"#\\") {
+		return word
+	}
+	return strconv.Quote(word)
+}
+
+// tmshPath returns the full path of an object name, names without partition belonging to Common
+func tmshPath(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if strings.Contains(name, "/") {
+		return "/" + name
+	}
+	return "/Common/" + name
+}
+
+// tmshRouteDomain renders non default route domains
+func tmshRouteDomain(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+// tmshStanza returns a top-level entry of the given type and name, nil properties being skipped
+func tmshStanza(header string, properties ...*tmshEntry) *tmshEntry {
+	return tmshBlock(strings.Fields(header), properties...)
+}
+
+func tmshBlock(words []string, properties ...*tmshEntry) *tmshEntry {
+	entry := &tmshEntry{words: words, entries: []*tmshEntry{}}
+	for _, property := range properties {
+		if property != nil {
+			entry.entries = append(entry.entries, property)
+		}
+	}
+	return entry
+}
+
+// tmshProperty returns a key value entry, or nil for empty values
+func tmshProperty(key, value string) *tmshEntry {
+	if value == "" {
+		return nil
+	}
+	return &tmshEntry{words: []string{key, value}}
+}
+
+// tmshPathProperty is a tmshProperty referencing another object by its full path
+func tmshPathProperty(key, name string) *tmshEntry {
+	if name == "" {
+		return nil
+	}
+	return tmshProperty(key, tmshPath(name))
+}
+
+func tmshPasswordPolicy(policy models.PasswordPolicy) *tmshEntry {
+	return tmshStanza("auth password-policy",
+		tmshProperty("lockout-duration", strconv.Itoa(policy.LockoutDuration)),
+		tmshProperty("max-duration", strconv.Itoa(policy.MaxDuration)),
+		tmshProperty("max-login-failures", strconv.Itoa(policy.MaxLoginFailures)),
+		tmshProperty("minimum-length", strconv.Itoa(policy.MinimumLength)),
+		tmshProperty("policy-enforcement", policy.PolicyEnforcement),
+		tmshProperty("required-lowercase", strconv.Itoa(policy.RequiredLowercase)),
+		tmshProperty("required-numeric", strconv.Itoa(policy.RequiredNumeric)),
+		tmshProperty("required-special", strconv.Itoa(policy.RequiredSpecial)),
+		tmshProperty("required-uppercase", strconv.Itoa(policy.RequiredUppercase)),
+	)
+}
+
+// tmshUser renders a user without its password, which tmsh only stores encrypted
+func tmshUser(user *models.User) *tmshEntry {
+	var access []*tmshEntry
+	for _, partitionAccess := range user.PartitionAccess {
+		access = append(access, tmshBlock([]string{partitionAccess.Name}, tmshProperty("role", partitionAccess.Role)))
+	}
+
+	return tmshStanza("auth user "+user.Name,
+		tmshProperty("description", user.Description),
+		tmshBlock([]string{"partition-access"}, access...),
+		tmshProperty("shell", "none"),
+	)
+}
+
+func tmshPool(pool *models.Pool) *tmshEntry {
+	var members []*tmshEntry
+	for _, member := range pool.Members {
+		members = append(members, tmshBlock([]string{tmshPath(member.Name)}, tmshProperty("address", member.Address)))
+	}
+
+	var membersBlock *tmshEntry
+	if len(members) > 0 {
+		membersBlock = tmshBlock([]string{"members"}, members...)
+	}
+
+	return tmshStanza("ltm pool "+tmshPath(models.FullPath(pool.Partition, pool.SubPath, pool.Name)),
+		tmshProperty("description", pool.Description),
+		tmshProperty("load-balancing-mode", pool.LoadBalancingMode),
+		membersBlock,
+		tmshPathProperty("monitor", pool.Monitor),
+	)
+}
+
+// tmshClientSSLProfile leaves the key passphrases out, the mock does not encrypt them like BIG-IP does
+func tmshClientSSLProfile(profile *models.ClientSSLProfile) *tmshEntry {
+	var chain []*tmshEntry
+	for _, element := range profile.CertKeyChain {
+		chain = append(chain, tmshBlock([]string{element.Name},
+			tmshPathProperty("cert", element.Cert),
+			tmshPathProperty("chain", element.Chain),
+			tmshPathProperty("key", element.Key),
+		))
+	}
+
+	var chainBlock *tmshEntry
+	if len(chain) > 0 {
+		chainBlock = tmshBlock([]string{"cert-key-chain"}, chain...)
+	}
+
+	return tmshStanza("ltm profile client-ssl "+tmshPath(models.FullPath(profile.Partition, profile.SubPath, profile.Name)),
+		tmshPathProperty("cert", profile.Cert),
+		chainBlock,
+		tmshPathProperty("cipher-group", profile.CipherGroup),
+		tmshProperty("ciphers", profile.Ciphers),
+		tmshPathProperty("defaults-from", profile.DefaultsFrom),
+		tmshPathProperty("key", profile.Key),
+	)
+}
+
+func tmshCipherGroup(group *models.CipherGroup) *tmshEntry {
+	references := func(key string, refs []models.CipherReference) *tmshEntry {
+		if len(refs) == 0 {
+			return nil
+		}
+		var entries []*tmshEntry
+		for _, ref := range refs {
+			entries = append(entries, tmshBlock([]string{CipherPath(ref.Name)}))
+		}
+		return tmshBlock([]string{key}, entries...)
+	}
+
+	return tmshStanza("ltm cipher group "+cipherGroupPath(group),
+		references("allow", group.Allow),
+		tmshProperty("description", group.Description),
+		references("exclude", group.Exclude),
+		tmshProperty("ordering", group.Ordering),
+		references("require", group.Require),
+	)
+}
+
+func tmshVirtualServer(virtual *models.VirtualServer) *tmshEntry {
+	var profiles []*tmshEntry
+	for _, profile := range virtual.Profiles {
+		profiles = append(profiles, tmshBlock([]string{tmshPath(profile)}))
+	}
+
+	var profilesBlock *tmshEntry
+	if len(profiles) > 0 {
+		profilesBlock = tmshBlock([]string{"profiles"}, profiles...)
+	}
+
+	return tmshStanza("ltm virtual "+tmshPath(models.FullPath(virtual.Partition, virtual.SubPath, virtual.Name)),
+		tmshProperty("description", virtual.Description),
+		tmshProperty("destination", virtual.Destination),
+		tmshProperty("ip-protocol", virtual.IPProtocol),
+		tmshPathProperty("pool", virtual.Pool),
+		profilesBlock,
+	)
+}
+
+// tmshFileStanza renders a sys file object, its contents living in the filestore of the device
+func tmshFileStanza(kind, fullPath, store string) *tmshEntry {
+	partition, subPath, name := splitTmshName(fullPath)
+	cachePath := fmt.Sprintf("/config/filestore/files_d/%s_d/%s/:%s:%s_1", partition, store, partition, strings.ReplaceAll(path.Join(subPath, name), "/", ":"))
+
+	return tmshStanza("sys file "+kind+" "+fullPath,
+		tmshProperty("cache-path", cachePath),
+		tmshProperty("revision", "1"),
+	)
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/iilun/f5-mock/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+const exportedConf = `#TMSH-VERSION: 17.0.0.0
+
+auth partition Sample_02 {
+    default-route-domain 2
+    description "Sample partition"
+}
+
+auth user certmgr {
+    partition-access {
+        Common {
+            role certificate-manager
+        }
+    }
+    shell none
+}
+
+ltm cipher group /Sample_02/secure {
+    allow {
+        /Common/f5-secure { }
+    }
+    exclude {
+        /Common/cbc { }
+    }
+    ordering strength
+}
+
+ltm cipher rule /Common/cbc {
+    cipher AES:!AESGCM
+    description "CBC ciphers"
+}
+
+ltm pool /Common/web_pool {
+    members {
+        /Common/10.0.0.1:80 {
+            address 10.0.0.1
+        }
+    }
+    monitor /Common/http
+}
+
+ltm profile client-ssl /Common/profile2 {
+    cert /Common/cert2.pem
+    key /Common/key2.pem
+}
+
+ltm profile client-ssl /Sample_02/A1 {
+    cert /Common/newCert22
+    cert-key-chain {
+        chain1 {
+            cert /Sample_02/chain-cert1.pem
+            key /Sample_02/chain-key1.pem
+        }
+    }
+    cipher-group /Sample_02/secure
+    defaults-from /Common/clientssl
+    key /Common/key1.pem
+}
+
+ltm virtual /Common/web_vs {
+    description "Main \"web\" virtual"
+    destination /Common/10.0.0.10:443
+    pool /Common/web_pool
+    profiles {
+        /Sample_02/A1 { }
+    }
+}
+
+sys folder /Sample_02/app.app { }
+
+sys file ssl-cert /Common/newCert22 {
+    cache-path /config/filestore/files_d/Common_d/certificate_d/:Common:newCert22_1
+    revision 1
+}
+
+sys file ssl-cert /Sample_02/chain-cert1.pem {
+    cache-path /config/filestore/files_d/Sample_02_d/certificate_d/:Sample_02:chain-cert1.pem_1
+    revision 1
+}
+
+sys file ssl-key /Common/key1.pem {
+    cache-path /config/filestore/files_d/Common_d/certificate_key_d/:Common:key1.pem_1
+    revision 1
+}
+
+`
+
+func TestExportSCF(t *testing.T) {
+	store := &MemoryCaches{Fs: NewFS()}
+	store.Partitions = []*models.Partition{{Name: "Sample_02", Description: "Sample partition", DefaultRouteDomain: 2}}
+	store.Folders = []*models.Folder{{FullPath: "/Sample_02/app.app"}}
+	store.Users = []*models.User{{Name: "certmgr", Password: "secret", PartitionAccess: []models.PartitionAccess{{Name: "Common", Role: models.RoleCertificateManager}}}}
+	store.CipherRules = []*models.CipherRule{{Name: "cbc", Description: "CBC ciphers", Cipher: "AES:!AESGCM"}}
+	store.CipherGroups = []*models.CipherGroup{{
+		Name:      "secure",
+		Partition: "Sample_02",
+		Ordering:  "strength",
+		Allow:     []models.CipherReference{{Name: "f5-secure"}},
+		Exclude:   []models.CipherReference{{Name: "/Common/cbc"}},
+	}}
+	store.Pools = []*models.Pool{{Name: "web_pool", Partition: "Common", Monitor: "/Common/http", Members: []models.PoolMember{{Name: "10.0.0.1:80", Address: "10.0.0.1"}}}}
+	store.ClientSSLProfiles = []*models.ClientSSLProfile{
+		{
+			Name:         "A1",
+			Partition:    "Sample_02",
+			Cert:         "newCert22",
+			Key:          "key1.pem",
+			CertKeyChain: []models.ChainElement{{Name: "chain1", Cert: "/Sample_02/chain-cert1.pem", Key: "/Sample_02/chain-key1.pem", Passphrase: "secret"}},
+			CipherGroup:  "/Sample_02/secure",
+			DefaultsFrom: "/Common/clientssl",
+		},
+		{Name: "profile2", Partition: "Common", Cert: "cert2.pem", Key: "key2.pem", Passphrase: "secret"},
+	}
+	store.VirtualServers = []*models.VirtualServer{{
+		Name:        "web_vs",
+		Partition:   "Common",
+		Description: `Main "web" virtual`,
+		Destination: "/Common/10.0.0.10:443",
+		Pool:        "web_pool",
+		Profiles:    []string{"/Sample_02/A1"},
+	}}
+	for _, file := range []string{"/certs/newCert22", "/certs/Sample_02/chain-cert1.pem", "/keys/key1.pem"} {
+		_, err := store.Fs.WriteFile(file, []byte("contents"))
+		require.NoError(t, err)
+	}
+
+	var conf strings.Builder
+	require.NoError(t, store.ExportSCF(&conf, "17.0.0.0"))
+	require.Equal(t, exportedConf, conf.String())
+}
+
+func TestExportSCF_RoundTrip(t *testing.T) {
+	seed, err := LoadSCF(filepath.Join("testdata", "bigip.conf"))
+	require.NoError(t, err)
+
+	store := &MemoryCaches{Fs: NewFS()}
+	store.ApplySeed(seed, true)
+
+	var conf strings.Builder
+	require.NoError(t, store.ExportSCF(&conf, "17.0.0.0"))
+
+	confPath := filepath.Join(t.TempDir(), "bigip.conf")
+	require.NoError(t, os.WriteFile(confPath, []byte(conf.String()), 0o600))
+
+	exported, err := LoadSCF(confPath)
+	require.NoError(t, err)
+	require.Empty(t, exported.Warnings)
+
+	require.Equal(t, seed.Partitions, exported.Partitions)
+	require.Equal(t, seed.Folders, exported.Folders)
+	require.Equal(t, seed.CipherRules, exported.CipherRules)
+	require.Equal(t, seed.CipherGroups, exported.CipherGroups)
+	require.Equal(t, seed.ClientSSLProfiles, exported.ClientSSLProfiles)
+	require.Equal(t, seed.VirtualServers, exported.VirtualServers)
+	require.Equal(t, seed.Pools, exported.Pools)
+	require.ElementsMatch(t, slices.Collect(maps.Keys(seed.files)), slices.Collect(maps.Keys(exported.files)))
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/iilun/f5-mock/pkg/models"
+)
+
+// CommonPartition always exists and cannot be deleted, like on a real BIG-IP
+const CommonPartition = "Common"
+
+// ListPartitions returns the Common partition followed by the other partitions
+func (c *MemoryCaches) ListPartitions() []*models.Partition {
+	partitions := []*models.Partition{c.FindPartition(CommonPartition)}
+	for _, partition := range c.Partitions {
+		if partition.Name != CommonPartition {
+			partitions = append(partitions, partition)
+		}
+	}
+	return partitions
+}
+
+// FindPartition returns the partition of the given name, or nil
+func (c *MemoryCaches) FindPartition(name string) *models.Partition {
+	for _, partition := range c.Partitions {
+		if partition.Name == name {
+			return partition
+		}
+	}
+	if name == CommonPartition {
+		return &models.Partition{Name: CommonPartition, Description: "Repository for system objects and shared objects."}
+	}
+	return nil
+}
+
+// FindFolder returns the folder of the given full path, or nil. Partitions are not returned.
+func (c *MemoryCaches) FindFolder(fullPath string) *models.Folder {
+	for _, folder := range c.Folders {
+		if folder.FullPath == fullPath {
+			return folder
+		}
+	}
+	return nil
+}
+
+// FolderExists reports whether a partition or a folder exists, given its full path like /Sample_02/app.app
+func (c *MemoryCaches) FolderExists(fullPath string) bool {
+	fullPath = path.Clean("/" + fullPath)
+	if strings.Count(fullPath, "/") == 1 {
+		return c.FindPartition(strings.TrimPrefix(fullPath, "/")) != nil
+	}
+	return c.FindFolder(fullPath) != nil
+}
+
+// FolderContents describes the folders and objects under a partition or a folder, sorted by full path
+func (c *MemoryCaches) FolderContents(fullPath string) []string {
+	prefix := path.Clean("/"+fullPath) + "/"
+
+	type object struct {
+		kind     string
+		fullPath string
+	}
+	var objects []object
+
+	for _, folder := range c.Folders {
+		objects = append(objects, object{"sys folder", folder.FullPath})
+	}
+	for _, profile := range c.ClientSSLProfiles {
+		objects = append(objects, object{"ltm profile client-ssl", models.FullPath(profile.Partition, profile.SubPath, profile.Name)})
+	}
+	for _, rule := range c.CipherRules {
+		objects = append(objects, object{"ltm cipher rule", cipherRulePath(rule)})
+	}
+	for _, group := range c.CipherGroups {
+		objects = append(objects, object{"ltm cipher group", cipherGroupPath(group)})
+	}
+	for _, virtual := range c.VirtualServers {
+		objects = append(objects, object{"ltm virtual", models.FullPath(virtual.Partition, virtual.SubPath, virtual.Name)})
+	}
+	for _, pool := range c.Pools {
+		objects = append(objects, object{"ltm pool", models.FullPath(pool.Partition, pool.SubPath, pool.Name)})
+	}
+	for _, certPath := range c.Fs.List(certsDir) {
+		objects = append(objects, object{"sys file ssl-cert", tmshPath(strings.TrimPrefix(certPath, certsDir))})
+	}
+	for _, keyPath := range c.Fs.List(keysDir) {
+		objects = append(objects, object{"sys file ssl-key", tmshPath(strings.TrimPrefix(keyPath, keysDir))})
+	}
+
+	slices.SortStableFunc(objects, func(a, b object) int {
+		return strings.Compare(a.fullPath, b.fullPath)
+	})
+
+	var contents []string
+	for _, o := range objects {
+		if strings.HasPrefix(o.fullPath, prefix) {
+			contents = append(contents, o.kind+" "+o.fullPath)
+		}
+	}
+	return contents
+}
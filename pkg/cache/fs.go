@@ -1,8 +1,13 @@
 package cache
 
 import (
+	"bytes"
+	"fmt"
 	"io/fs"
+	"maps"
 	"path/filepath"
+	"slices"
+	"strings"
 )
 
 type MemoryFS struct {
@@ -34,3 +39,76 @@ func (f *MemoryFS) WriteFile(path string, content []byte) (int, error) {
 	f.files[filepath.Clean(path)] = content
 	return len(content), nil
 }
+
+// Remove deletes a file
+func (f *MemoryFS) Remove(path string) error {
+	path = filepath.Clean(path)
+	if _, found := f.files[path]; !found {
+		return fs.ErrNotExist
+	}
+	delete(f.files, path)
+	return nil
+}
+
+// Rename moves a file, replacing the destination when it exists
+func (f *MemoryFS) Rename(oldPath, newPath string) error {
+	oldPath = filepath.Clean(oldPath)
+	content, found := f.files[oldPath]
+	if !found {
+		return fs.ErrNotExist
+	}
+	delete(f.files, oldPath)
+	f.files[filepath.Clean(newPath)] = content
+	return nil
+}
+
+// IsDir reports whether files exist under dir, directories only existing through their files
+func (f *MemoryFS) IsDir(dir string) bool {
+	return filepath.Clean(dir) == "/" || len(f.List(dir)) > 0
+}
+
+// List returns the sorted paths of all the files under dir
+func (f *MemoryFS) List(dir string) []string {
+	prefix := strings.TrimSuffix(filepath.Clean(dir), "/") + "/"
+
+	var paths []string
+	for path := range f.files {
+		if strings.HasPrefix(path, prefix) {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// applySeed writes seeded files, generated ones being kept when they already exist. When replacing,
+// files missing from the seed are removed.
+func (f *MemoryFS) applySeed(files map[string]seedContent, replace bool) []string {
+	var changes []string
+
+	if replace {
+		for _, path := range f.List("/") {
+			if _, seeded := files[path]; !seeded {
+				delete(f.files, path)
+				changes = append(changes, fmt.Sprintf("removed file %s", path))
+			}
+		}
+	}
+
+	paths := slices.Sorted(maps.Keys(files))
+	for _, path := range paths {
+		seeded := files[path]
+		existing, found := f.files[path]
+		switch {
+		case !found:
+			changes = append(changes, fmt.Sprintf("added file %s", path))
+		case seeded.generated || bytes.Equal(existing, seeded.content):
+			continue
+		default:
+			changes = append(changes, fmt.Sprintf("updated file %s", path))
+		}
+		f.files[path] = seeded.content
+	}
+
+	return changes
+}
@@ -2,26 +2,307 @@ package cache
 
 import (
 	"fmt"
+	"github.com/iilun/f5-mock/internal/ciphers"
 	"github.com/iilun/f5-mock/pkg/models"
 	"gopkg.in/yaml.v3"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 )
 
 type SeedData struct {
+	Partitions        []*models.Partition        `yaml:"partitions"`
+	Folders           []*models.Folder           `yaml:"folders"`
+	Certificates      []SeedCertificate          `yaml:"certificates"`
+	Keys              []SeedKey                  `yaml:"keys"`
+	Files             []SeedFile                 `yaml:"files"`
 	ClientSSLProfiles []*models.ClientSSLProfile `yaml:"client_ssl_profiles"`
-	CipherGroups      []string                   `yaml:"cipher_groups"`
+	CipherRules       []*models.CipherRule       `yaml:"cipher_rules"`
+	CipherGroups      []*models.CipherGroup      `yaml:"cipher_groups"`
+	Users             []*models.User             `yaml:"users"`
+	LoginProviders    []*models.LoginProvider    `yaml:"login_providers"`
+	PasswordPolicy    models.PasswordPolicy      `yaml:"password_policy"`
+	VirtualServers    []*models.VirtualServer    `yaml:"virtual_servers"`
+	Pools             []*models.Pool             `yaml:"pools"`
+
+	// Warnings report the parts of the seed file that were ignored
+	Warnings []string `yaml:"-"`
+
+	// files are the contents of the seeded certificates, keys and files by path
+	files map[string]seedContent
 }
 
-func loadSeedData(path string) (SeedData, error) {
+// LoadSeedData reads and validates a seed file, errors giving the line of the invalid object.
+// Files with a .conf or .scf extension are read as tmsh configuration.
+func LoadSeedData(path string) (SeedData, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".conf", ".scf":
+		return LoadSCF(path)
+	}
+
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return SeedData{}, fmt.Errorf("failed to read file: %w", err)
 	}
 
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return SeedData{}, fmt.Errorf("failed to unmarshal yaml: %w", err)
+	}
+
 	var seed SeedData
-	if err := yaml.Unmarshal(data, &seed); err != nil {
+	if err := root.Decode(&seed); err != nil {
 		return SeedData{}, fmt.Errorf("failed to unmarshal yaml: %w", err)
 	}
 
+	// Built-in groups listed by name, as older seed files did, are always available
+	seed.CipherGroups = slices.DeleteFunc(seed.CipherGroups, func(group *models.CipherGroup) bool {
+		return IsBuiltinCipherGroup(cipherGroupPath(group)) && group.Allow == nil && group.Exclude == nil && group.Require == nil
+	})
+
+	err = seed.checkObjects(&root)
+	if err != nil {
+		return SeedData{}, err
+	}
+
+	err = seed.resolveFiles(&root, filepath.Dir(path))
+	if err != nil {
+		return SeedData{}, err
+	}
+
+	for i, provider := range seed.LoginProviders {
+		if provider.CredentialsFile == "" {
+			continue
+		}
+
+		credentialsFile := provider.CredentialsFile
+		if !filepath.IsAbs(credentialsFile) {
+			credentialsFile = filepath.Join(filepath.Dir(path), credentialsFile)
+		}
+		users, err := loadCredentialsFile(credentialsFile)
+		if err != nil {
+			return SeedData{}, fmt.Errorf("line %d: login provider %s: %w", seedLine(&root, "login_providers", i), provider.Name, err)
+		}
+		provider.Users = append(provider.Users, users...)
+	}
+
 	return seed, nil
 }
+
+// checkObjects validates the objects of a seed document, root being the document they were decoded from
+func (s *SeedData) checkObjects(root *yaml.Node) error {
+	err := checkSeedItems(root, "partitions", "partition", s.Partitions, func(p *models.Partition) string {
+		return p.Name
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	err = checkSeedItems(root, "folders", "folder", s.Folders, func(f *models.Folder) string {
+		return f.FullPath
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	err = checkSeedItems(root, "client_ssl_profiles", "client-ssl profile", s.ClientSSLProfiles, func(p *models.ClientSSLProfile) string {
+		return models.FullPath(p.Partition, p.SubPath, p.Name)
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	err = checkSeedItems(root, "cipher_rules", "cipher rule", s.CipherRules, cipherRulePath, func(rule *models.CipherRule) error {
+		if IsBuiltinCipherRule(cipherRulePath(rule)) {
+			return fmt.Errorf("built-in cipher rules cannot be redefined")
+		}
+		rule.Partition = partitionOrCommon(rule.Partition)
+		return ciphers.Validate(rule.Cipher)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkSeedItems(root, "cipher_groups", "cipher group", s.CipherGroups, cipherGroupPath, func(group *models.CipherGroup) error {
+		if IsBuiltinCipherGroup(cipherGroupPath(group)) {
+			return fmt.Errorf("built-in cipher groups cannot be redefined")
+		}
+		group.Partition = partitionOrCommon(group.Partition)
+		return s.checkCipherReferences(group)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkSeedItems(root, "users", "user", s.Users, func(u *models.User) string {
+		return u.Name
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	err = checkSeedItems(root, "login_providers", "login provider", s.LoginProviders, func(provider *models.LoginProvider) string {
+		return provider.Name
+	}, func(provider *models.LoginProvider) error {
+		// Like for local users, the admin role is only granted on all partitions
+		for _, mapping := range provider.RoleMapping {
+			if mapping.Role == models.RoleAdmin && mapping.Partition != "" && mapping.Partition != models.AllPartitions {
+				return fmt.Errorf("role admin of group %s can only be mapped to all partitions", mapping.Group)
+			}
+		}
+		if provider.DefaultRole == models.RoleAdmin && provider.DefaultPartition != "" && provider.DefaultPartition != models.AllPartitions {
+			return fmt.Errorf("default role admin can only be granted on all partitions")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkSeedItems(root, "virtual_servers", "virtual server", s.VirtualServers, func(v *models.VirtualServer) string {
+		return models.FullPath(v.Partition, v.SubPath, v.Name)
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	err = checkSeedItems(root, "pools", "pool", s.Pools, func(p *models.Pool) string {
+		return models.FullPath(p.Partition, p.SubPath, p.Name)
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := seedValidator.Struct(s.PasswordPolicy); err != nil {
+		return fmt.Errorf("line %d: password policy: %w", seedLine(root, "password_policy", -1), err)
+	}
+
+	return nil
+}
+
+// checkCipherReferences checks that the rules of a seeded cipher group are built-in or seeded
+func (s *SeedData) checkCipherReferences(group *models.CipherGroup) error {
+	for _, ref := range slices.Concat(group.Allow, group.Exclude, group.Require) {
+		seeded := slices.ContainsFunc(s.CipherRules, func(rule *models.CipherRule) bool {
+			return cipherRulePath(rule) == CipherPath(ref.Name)
+		})
+		if !seeded && !IsBuiltinCipherRule(ref.Name) {
+			return fmt.Errorf("cipher rule %s does not exist", ref.Name)
+		}
+	}
+	return nil
+}
+
+func loadCredentialsFile(path string) ([]*models.RemoteUser, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	var users []*models.RemoteUser
+	if err := yaml.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal credentials file: %w", err)
+	}
+
+	return users, nil
+}
+
+// ApplySeed applies seed data to the store, replacing the whole store or merging seeded objects
+// into it, and describes the changes made
+func (c *MemoryCaches) ApplySeed(seed SeedData, replace bool) []string {
+	var changes, kindChanges []string
+
+	c.Partitions, kindChanges = applyObjects("partition", c.Partitions, seed.Partitions, replace, func(p *models.Partition) string {
+		return p.Name
+	})
+	changes = append(changes, kindChanges...)
+
+	c.Folders, kindChanges = applyObjects("folder", c.Folders, seed.Folders, replace, func(f *models.Folder) string {
+		return f.FullPath
+	})
+	changes = append(changes, kindChanges...)
+
+	changes = append(changes, c.Fs.applySeed(seed.files, replace)...)
+
+	c.ClientSSLProfiles, kindChanges = applyObjects("client-ssl profile", c.ClientSSLProfiles, seed.ClientSSLProfiles, replace, func(p *models.ClientSSLProfile) string {
+		return models.FullPath(p.Partition, p.SubPath, p.Name)
+	})
+	changes = append(changes, kindChanges...)
+
+	c.CipherRules, kindChanges = applyObjects("cipher rule", c.CipherRules, seed.CipherRules, replace, cipherRulePath)
+	changes = append(changes, kindChanges...)
+
+	c.CipherGroups, kindChanges = applyObjects("cipher group", c.CipherGroups, seed.CipherGroups, replace, cipherGroupPath)
+	changes = append(changes, kindChanges...)
+
+	c.Users, kindChanges = applyObjects("user", c.Users, seed.Users, replace, func(u *models.User) string {
+		return u.Name
+	})
+	changes = append(changes, kindChanges...)
+
+	c.LoginProviders, kindChanges = applyObjects("login provider", c.LoginProviders, seed.LoginProviders, replace, func(p *models.LoginProvider) string {
+		return p.Name
+	})
+	changes = append(changes, kindChanges...)
+
+	c.VirtualServers, kindChanges = applyObjects("virtual server", c.VirtualServers, seed.VirtualServers, replace, func(v *models.VirtualServer) string {
+		return models.FullPath(v.Partition, v.SubPath, v.Name)
+	})
+	changes = append(changes, kindChanges...)
+
+	c.Pools, kindChanges = applyObjects("pool", c.Pools, seed.Pools, replace, func(p *models.Pool) string {
+		return models.FullPath(p.Partition, p.SubPath, p.Name)
+	})
+	changes = append(changes, kindChanges...)
+
+	if !reflect.DeepEqual(c.PasswordPolicy, seed.PasswordPolicy) {
+		c.PasswordPolicy = seed.PasswordPolicy
+		changes = append(changes, "updated password policy")
+	}
+
+	return changes
+}
+
+// applyObjects merges seeded objects into current ones, matching them by key. When replacing,
+// objects missing from the seed are removed.
+func applyObjects[T any](kind string, current, seeded []T, replace bool, key func(T) string) ([]T, []string) {
+	var changes []string
+
+	result := make([]T, 0, len(current)+len(seeded))
+	seededKeys := make(map[string]bool, len(seeded))
+	for _, object := range seeded {
+		seededKeys[key(object)] = true
+	}
+
+	currentKeys := make(map[string]T, len(current))
+	for _, object := range current {
+		currentKeys[key(object)] = object
+		if seededKeys[key(object)] {
+			// Taken from the seed below
+			continue
+		}
+		if replace {
+			changes = append(changes, fmt.Sprintf("removed %s %s", kind, key(object)))
+			continue
+		}
+		result = append(result, object)
+	}
+
+	for _, object := range seeded {
+		existing, found := currentKeys[key(object)]
+		switch {
+		case !found:
+			changes = append(changes, fmt.Sprintf("added %s %s", kind, key(object)))
+		case reflect.DeepEqual(existing, object):
+			// Keep the object referenced by the running state
+			object = existing
+		default:
+			changes = append(changes, fmt.Sprintf("updated %s %s", kind, key(object)))
+		}
+		result = append(result, object)
+	}
+
+	return result, changes
+}
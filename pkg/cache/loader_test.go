@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iilun/f5-mock/internal/crypto"
+	"github.com/iilun/f5-mock/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSeedData(t *testing.T) {
+	dir := t.TempDir()
+
+	key, err := crypto.GenerateKey(crypto.KeyTypeEC, 0, "")
+	require.NoError(t, err)
+	cert, err := crypto.CreateSelfSignedCertificate(key, crypto.Subject{CommonName: "disk"}, crypto.SubjectAlternativeNames{}, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "disk.crt"), cert, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "disk.key"), key, 0o600))
+
+	indent := func(content []byte) string {
+		lines := ""
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			lines += "      " + line + "\n"
+		}
+		return lines
+	}
+
+	tests := []struct {
+		name      string
+		seed      string
+		wantErr   string
+		wantFiles []string
+	}{
+		{
+			name: "certificates, keys and files",
+			seed: `
+partitions:
+  - name: Sample_02
+certificates:
+  - name: /Common/inline.crt
+    pem: |
+` + indent(cert) + `
+  - name: /Common/disk.crt
+    path: disk.crt
+  - name: /Sample_02/generated.crt
+    generate:
+      common_name: www.example.com
+      key_type: ec-private
+      key_name: /Sample_02/generated.key
+keys:
+  - name: /Common/disk.key
+    path: disk.key
+files:
+  - name: upload.txt
+    content: hello
+client_ssl_profiles:
+  - name: profile
+    partition: Common
+    cert: /Common/disk.crt
+    key: /Common/disk.key
+`,
+			wantFiles: []string{
+				"/certs/Common/disk.crt",
+				"/certs/Common/inline.crt",
+				"/certs/Sample_02/generated.crt",
+				"/keys/Common/disk.key",
+				"/keys/Sample_02/generated.key",
+				"/var/config/rest/downloads/upload.txt",
+			},
+		},
+		{
+			name:    "syntax error",
+			seed:    "users:\n  - name: [\n",
+			wantErr: "failed to unmarshal yaml: yaml: line 2",
+		},
+		{
+			name:    "type error",
+			seed:    "users:\n  - name: admin\n    partition_access: all\n",
+			wantErr: "failed to unmarshal yaml: yaml: unmarshal errors:\n  line 3",
+		},
+		{
+			name:    "invalid certificate",
+			seed:    "certificates:\n  - name: good.crt\n    path: disk.crt\n  - name: bad.crt\n    pem: not a certificate\n",
+			wantErr: "line 4: certificate bad.crt: invalid certificate",
+		},
+		{
+			name:    "missing certificate file",
+			seed:    "certificates:\n  - name: missing.crt\n    path: missing.crt\n",
+			wantErr: "line 2: certificate missing.crt: failed to read file",
+		},
+		{
+			name:    "certificate without contents",
+			seed:    "certificates:\n  - name: empty.crt\n",
+			wantErr: "line 2: certificate empty.crt: contents or path are required",
+		},
+		{
+			name:    "certificate with several sources",
+			seed:    "certificates:\n  - name: both.crt\n    path: disk.crt\n    generate:\n      common_name: both\n",
+			wantErr: "line 2: certificate both.crt: only one of pem, path or generate can be set",
+		},
+		{
+			name:    "invalid generated key type",
+			seed:    "certificates:\n  - name: gen.crt\n    generate:\n      common_name: gen\n      key_type: dsa\n",
+			wantErr: "line 2: certificate gen.crt:",
+		},
+		{
+			name:    "invalid key",
+			seed:    "keys:\n  - name: bad.key\n    pem: not a key\n",
+			wantErr: "line 2: key bad.key: invalid key",
+		},
+		{
+			name:    "invalid user",
+			seed:    "users:\n  - name: ok\n    partition_access: [{name: Common, role: guest}]\n  - name: norole\n    partition_access: [{name: Common}]\n",
+			wantErr: "line 4: user norole:",
+		},
+		{
+			name:    "invalid mapped role",
+			seed:    "login_providers:\n  - name: corp-ldap\n    role_mapping: [{group: ops, role: superuser}]\n",
+			wantErr: "line 2: login provider corp-ldap:",
+		},
+		{
+			name:    "invalid default role",
+			seed:    "login_providers:\n  - name: corp-ldap\n    default_role: root\n",
+			wantErr: "line 2: login provider corp-ldap:",
+		},
+		{
+			name:    "admin role mapped to a partition",
+			seed:    "login_providers:\n  - name: corp-ldap\n    role_mapping: [{group: ops, role: admin, partition: Prod}]\n",
+			wantErr: "line 2: login provider corp-ldap: role admin of group ops can only be mapped to all partitions",
+		},
+		{
+			name:    "default admin role on a partition",
+			seed:    "login_providers:\n  - name: corp-ldap\n    default_role: admin\n    default_partition: Prod\n",
+			wantErr: "line 2: login provider corp-ldap: default role admin can only be granted on all partitions",
+		},
+		{
+			name:    "invalid profile",
+			seed:    "client_ssl_profiles:\n  - name: nopartition\n",
+			wantErr: "line 2: client-ssl profile /",
+		},
+		{
+			name:    "invalid folder",
+			seed:    "folders:\n  - full_path: /Sample_02/app.app\n  - full_path: relative\n",
+			wantErr: "line 3: folder relative:",
+		},
+		{
+			name:    "invalid cipher string",
+			seed:    "cipher_rules:\n  - name: gcm\n    cipher: ECDHE+AESGCM\n  - name: bad\n    cipher: ECDHE:BOGUS\n",
+			wantErr: "line 4: cipher rule /Common/bad: invalid cipher string 'ECDHE:BOGUS': unknown keyword 'BOGUS'",
+		},
+		{
+			name:    "unknown cipher rule",
+			seed:    "cipher_groups:\n  - name: secure\n    exclude: [missing]\n",
+			wantErr: "line 2: cipher group /Common/secure: cipher rule missing does not exist",
+		},
+		{
+			name:    "invalid password policy",
+			seed:    "password_policy:\n  policy_enforcement: sometimes\n",
+			wantErr: "line 2: password policy:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seedPath := filepath.Join(dir, "seed.yaml")
+			require.NoError(t, os.WriteFile(seedPath, []byte(tt.seed), 0o600))
+
+			seed, err := LoadSeedData(seedPath)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			fs := NewFS()
+			fs.applySeed(seed.files, true)
+			require.Equal(t, tt.wantFiles, fs.List("/"))
+
+			generated, err := fs.ReadFile("/certs/Sample_02/generated.crt")
+			require.NoError(t, err)
+			generatedCert, err := crypto.ParsePemCertificate(generated)
+			require.NoError(t, err)
+			require.Equal(t, "www.example.com", generatedCert.Subject.CommonName)
+		})
+	}
+}
+
+func TestLoadSeedData_CipherGroups(t *testing.T) {
+	seedPath := filepath.Join(t.TempDir(), "seed.yaml")
+	seedContent := `
+cipher_rules:
+  - name: gcm
+    cipher: ECDHE+AESGCM
+cipher_groups:
+  - f5-default
+  - name: secure
+    partition: Common
+    allow: [gcm, /Common/f5-secure]
+`
+	require.NoError(t, os.WriteFile(seedPath, []byte(seedContent), 0o600))
+
+	seed, err := LoadSeedData(seedPath)
+	require.NoError(t, err)
+
+	require.Equal(t, []*models.CipherRule{{Name: "gcm", Partition: "Common", Cipher: "ECDHE+AESGCM"}}, seed.CipherRules)
+	// Built-in groups listed by name are dropped, being always available
+	require.Equal(t, []*models.CipherGroup{{
+		Name:      "secure",
+		Partition: "Common",
+		Allow:     []models.CipherReference{{Name: "gcm"}, {Name: "/Common/f5-secure"}},
+	}}, seed.CipherGroups)
+}
+
+func TestApplySeedFiles(t *testing.T) {
+	fs := NewFS()
+	_, _ = fs.WriteFile("/certs/runtime.crt", []byte("runtime"))
+	_, _ = fs.WriteFile("/certs/changed.crt", []byte("old"))
+	_, _ = fs.WriteFile("/certs/generated.crt", []byte("first"))
+
+	files := map[string]seedContent{
+		"/certs/changed.crt":   {content: []byte("new")},
+		"/certs/generated.crt": {content: []byte("second"), generated: true},
+		"/certs/added.crt":     {content: []byte("added")},
+	}
+
+	changes := fs.applySeed(files, false)
+	require.Equal(t, []string{"added file /certs/added.crt", "updated file /certs/changed.crt"}, changes)
+
+	generated, _ := fs.ReadFile("/certs/generated.crt")
+	require.Equal(t, "first", string(generated))
+	require.True(t, fs.Exists("/certs/runtime.crt"))
+
+	changes = fs.applySeed(files, true)
+	require.Equal(t, []string{"removed file /certs/runtime.crt"}, changes)
+}
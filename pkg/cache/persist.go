@@ -0,0 +1,327 @@
+package cache
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ConfigFile is where the running configuration is saved, as tmsh configuration
+	ConfigFile = "/config/bigip.conf"
+	// UCSDir holds the UCS archives
+	UCSDir = "/var/local/ucs"
+
+	// ucsObjects is the archive entry holding the objects of the store as a seed document, from which they are loaded
+	ucsObjects = "config/mock.yaml"
+	// ucsVersion is the archive entry describing the version the archive was created on
+	ucsVersion = "VERSION"
+	// ucsMaxSize bounds the uncompressed size of the files of an archive, which can be uploaded
+	ucsMaxSize = 256 << 20
+)
+
+// unsavedDirs hold the files which are not part of the configuration, like uploads and archives, kept as is when
+// loading one
+var unsavedDirs = []string{"/config", UCSDir, UploadsDir}
+
+func isUnsaved(filePath string) bool {
+	return slices.ContainsFunc(unsavedDirs, func(dir string) bool { return strings.HasPrefix(filePath, dir+"/") })
+}
+
+// Snapshot is a configuration of the store, made of its objects as a seed document and of its files by path
+type Snapshot struct {
+	Objects []byte
+	Files   map[string][]byte
+}
+
+func (s Snapshot) equal(other Snapshot) bool {
+	return bytes.Equal(s.Objects, other.Objects) && maps.EqualFunc(s.Files, other.Files, bytes.Equal)
+}
+
+// Snapshot serializes the running configuration
+func (c *MemoryCaches) Snapshot() (Snapshot, error) {
+	objects, err := yaml.Marshal(SeedData{
+		Partitions:        c.Partitions,
+		Folders:           c.Folders,
+		ClientSSLProfiles: c.ClientSSLProfiles,
+		CipherRules:       c.CipherRules,
+		CipherGroups:      c.CipherGroups,
+		Users:             c.Users,
+		LoginProviders:    c.LoginProviders,
+		PasswordPolicy:    c.PasswordPolicy,
+		VirtualServers:    c.VirtualServers,
+		Pools:             c.Pools,
+	})
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	files := map[string][]byte{}
+	for _, filePath := range c.Fs.List("/") {
+		if !isUnsaved(filePath) {
+			files[filePath], _ = c.Fs.ReadFile(filePath)
+		}
+	}
+
+	return Snapshot{Objects: objects, Files: files}, nil
+}
+
+// Restore replaces the running configuration with a snapshot, describing the changes made
+func (c *MemoryCaches) Restore(snapshot Snapshot) ([]string, error) {
+	var root yaml.Node
+	err := yaml.Unmarshal(snapshot.Objects, &root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	var seed SeedData
+	err = root.Decode(&seed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	// Archives can be uploaded, so their objects are checked like seeded ones
+	err = seed.checkObjects(&root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	seed.files = make(map[string]seedContent, len(snapshot.Files))
+	for filePath, content := range snapshot.Files {
+		if !isUnsaved(filePath) {
+			seed.files[filePath] = seedContent{content: content}
+		}
+	}
+	for _, filePath := range c.Fs.List("/") {
+		if isUnsaved(filePath) {
+			content, _ := c.Fs.ReadFile(filePath)
+			seed.files[filePath] = seedContent{content: content}
+		}
+	}
+
+	return c.ApplySeed(seed, true), nil
+}
+
+// SaveConfig saves the running configuration, which LoadConfig reverts to, writing it to /config/bigip.conf
+func (c *MemoryCaches) SaveConfig(version string) error {
+	snapshot, err := c.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	var conf bytes.Buffer
+	err = c.ExportSCF(&conf, version)
+	if err != nil {
+		return err
+	}
+	err = c.replaceFile(ConfigFile, conf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	c.savedConfig = &snapshot
+	return nil
+}
+
+// LoadConfig reverts the running configuration to the last saved one, describing the changes made
+func (c *MemoryCaches) LoadConfig() ([]string, error) {
+	if c.savedConfig == nil {
+		return nil, errors.New("no configuration was saved")
+	}
+	return c.Restore(*c.savedConfig)
+}
+
+// UnsavedChanges reports whether the running configuration differs from the last saved one
+func (c *MemoryCaches) UnsavedChanges() (bool, error) {
+	if c.savedConfig == nil {
+		return true, nil
+	}
+	snapshot, err := c.Snapshot()
+	if err != nil {
+		return false, err
+	}
+	return !snapshot.equal(*c.savedConfig), nil
+}
+
+func (c *MemoryCaches) replaceFile(filePath string, content []byte) error {
+	if c.Fs.Exists(filePath) {
+		err := c.Fs.Remove(filePath)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := c.Fs.WriteFile(filePath, content)
+	return err
+}
+
+// UCS describes a UCS archive
+type UCS struct {
+	Path    string
+	Size    int
+	Created time.Time
+	// Version is the version the archive was created on, empty for invalid archives
+	Version string
+}
+
+// CreateUCS saves the running configuration, then archives it at filePath as a gzipped tar holding the saved
+// bigip.conf, the objects of the store and its files, like a UCS archive
+func (c *MemoryCaches) CreateUCS(filePath, version string) error {
+	err := c.SaveConfig(version)
+	if err != nil {
+		return err
+	}
+	conf, err := c.Fs.ReadFile(ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	shortVersion, build := version, "0.0.1"
+	if parts := strings.Split(version, "."); len(parts) > 3 {
+		shortVersion = strings.Join(parts[:3], ".")
+		build = parts[3] + ".0.1"
+	}
+
+	entries := map[string][]byte{
+		ucsVersion:          fmt.Appendf(nil, "Product: BIG-IP\nVersion: %s\nBuild: %s\nEdition: Final\n", shortVersion, build),
+		"config/bigip.conf": conf,
+		ucsObjects:          c.savedConfig.Objects,
+	}
+	for name, content := range c.savedConfig.Files {
+		entries[strings.TrimPrefix(name, "/")] = content
+	}
+
+	var archive bytes.Buffer
+	zw := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(zw)
+	now := time.Now()
+	for _, name := range slices.Sorted(maps.Keys(entries)) {
+		err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(entries[name])), ModTime: now, Typeflag: tar.TypeReg})
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(entries[name])
+		if err != nil {
+			return err
+		}
+	}
+	err = errors.Join(tw.Close(), zw.Close())
+	if err != nil {
+		return err
+	}
+
+	return c.replaceFile(filePath, archive.Bytes())
+}
+
+// LoadUCS replaces the running configuration with the one of a UCS archive, which becomes the saved configuration.
+// It describes the changes made.
+func (c *MemoryCaches) LoadUCS(filePath, version string) ([]string, error) {
+	content, err := c.Fs.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := Snapshot{Files: map[string][]byte{}}
+	err = readUCS(content, func(header *tar.Header, content []byte) bool {
+		switch {
+		case header.Name == ucsObjects:
+			snapshot.Objects = content
+		case header.Name == ucsVersion:
+		default:
+			snapshot.Files["/"+header.Name] = content
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Objects == nil {
+		return nil, fmt.Errorf("%s is missing from the archive", ucsObjects)
+	}
+
+	changes, err := c.Restore(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return changes, c.SaveConfig(version)
+}
+
+// ListUCS describes the UCS archives of /var/local/ucs
+func (c *MemoryCaches) ListUCS() []UCS {
+	var archives []UCS
+	for _, filePath := range c.Fs.List(UCSDir) {
+		if path.Ext(filePath) != ".ucs" {
+			continue
+		}
+		content, _ := c.Fs.ReadFile(filePath)
+		archive := UCS{Path: filePath, Size: len(content)}
+
+		// Invalid archives, like uploaded ones, are still listed. Archives created by the mock start with their
+		// version, the other files being left unread.
+		_ = readUCS(content, func(header *tar.Header, content []byte) bool {
+			archive.Created = header.ModTime
+			if header.Name != ucsVersion {
+				return true
+			}
+			lines := bufio.NewScanner(bytes.NewReader(content))
+			for lines.Scan() {
+				if version, found := strings.CutPrefix(lines.Text(), "Version: "); found {
+					archive.Version = version
+				}
+			}
+			return false
+		})
+		archives = append(archives, archive)
+	}
+	return archives
+}
+
+// readUCS calls entry for each file of a UCS archive, until it returns false. Archives whose files are larger than
+// ucsMaxSize are invalid.
+func readUCS(archive []byte, entry func(header *tar.Header, content []byte) bool) error {
+	zr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return fmt.Errorf("invalid UCS archive: %w", err)
+	}
+	tr := tar.NewReader(zr)
+	remaining := int64(ucsMaxSize)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid UCS archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("invalid UCS archive: unexpected file %s", header.Name)
+		}
+		header.Name = name
+
+		// Files are read up to the remaining size, a larger one making the archive invalid
+		content, err := io.ReadAll(io.LimitReader(tr, remaining+1))
+		if err != nil {
+			return fmt.Errorf("invalid UCS archive: %w", err)
+		}
+		remaining -= int64(len(content))
+		if remaining < 0 {
+			return fmt.Errorf("invalid UCS archive: files are larger than %d bytes", ucsMaxSize)
+		}
+		if !entry(header, content) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package cache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/iilun/f5-mock/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveLoadConfig(t *testing.T) {
+	store := &MemoryCaches{Fs: NewFS()}
+	store.Partitions = []*models.Partition{{Name: "Common"}}
+	store.Users = []*models.User{{Name: "bob", Password: "secret", PartitionAccess: []models.PartitionAccess{{Name: "Common", Role: models.RoleGuest}}}}
+	_, _ = store.Fs.WriteFile("/certs/app.crt", []byte("saved"))
+
+	unsaved, err := store.UnsavedChanges()
+	require.NoError(t, err)
+	require.True(t, unsaved)
+
+	_, err = store.LoadConfig()
+	require.ErrorContains(t, err, "no configuration was saved")
+
+	require.NoError(t, store.SaveConfig("17.0.0.0"))
+	conf, err := store.Fs.ReadFile(ConfigFile)
+	require.NoError(t, err)
+	require.Contains(t, string(conf), "auth user bob {")
+
+	unsaved, err = store.UnsavedChanges()
+	require.NoError(t, err)
+	require.False(t, unsaved)
+
+	// Uploads are not part of the configuration
+	_, _ = store.Fs.WriteFile("/var/config/rest/downloads/upload.crt", []byte("uploaded"))
+	unsaved, err = store.UnsavedChanges()
+	require.NoError(t, err)
+	require.False(t, unsaved)
+
+	store.Users[0].Description = "changed"
+	store.Pools = []*models.Pool{{Name: "pool", Partition: "Common"}}
+	require.NoError(t, store.Fs.Remove("/certs/app.crt"))
+	_, _ = store.Fs.WriteFile("/certs/app.crt", []byte("changed"))
+	unsaved, err = store.UnsavedChanges()
+	require.NoError(t, err)
+	require.True(t, unsaved)
+
+	changes, err := store.LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, []string{"updated file /certs/app.crt", "updated user bob", "removed pool /Common/pool"}, changes)
+
+	require.Empty(t, store.Users[0].Description)
+	require.Empty(t, store.Pools)
+	content, _ := store.Fs.ReadFile("/certs/app.crt")
+	require.Equal(t, "saved", string(content))
+	require.True(t, store.Fs.Exists("/var/config/rest/downloads/upload.crt"))
+
+	unsaved, err = store.UnsavedChanges()
+	require.NoError(t, err)
+	require.False(t, unsaved)
+}
+
+func TestUCS(t *testing.T) {
+	store := &MemoryCaches{Fs: NewFS()}
+	store.Partitions = []*models.Partition{{Name: "Common"}, {Name: "Prod"}}
+	_, _ = store.Fs.WriteFile("/certs/app.crt", []byte("certificate"))
+
+	const backup = UCSDir + "/backup.ucs"
+	require.NoError(t, store.CreateUCS(backup, "17.0.0.0"))
+
+	unsaved, err := store.UnsavedChanges()
+	require.NoError(t, err)
+	require.False(t, unsaved)
+
+	// The archive is a gzipped tar of the configuration
+	archive, err := store.Fs.ReadFile(backup)
+	require.NoError(t, err)
+	zr, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	tr := tar.NewReader(zr)
+	entries := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		entries[header.Name] = string(content)
+	}
+	require.Equal(t, []string{"VERSION", "certs/app.crt", "config/bigip.conf", "config/mock.yaml"}, slices.Sorted(maps.Keys(entries)))
+	require.Equal(t, "Product: BIG-IP\nVersion: 17.0.0\nBuild: 0.0.1\nEdition: Final\n", entries["VERSION"])
+	require.Contains(t, entries["config/bigip.conf"], "auth partition Prod {")
+	require.Equal(t, "certificate", entries["certs/app.crt"])
+
+	_, _ = store.Fs.WriteFile(UCSDir+"/invalid.ucs", []byte("not an archive"))
+	archives := store.ListUCS()
+	require.Len(t, archives, 2)
+	require.Equal(t, backup, archives[0].Path)
+	require.Equal(t, len(archive), archives[0].Size)
+	require.Equal(t, "17.0.0", archives[0].Version)
+	require.False(t, archives[0].Created.IsZero())
+	require.Equal(t, UCS{Path: UCSDir + "/invalid.ucs", Size: 14}, archives[1])
+
+	store.Partitions = store.Partitions[:1]
+	require.NoError(t, store.Fs.Remove("/certs/app.crt"))
+	require.NoError(t, store.SaveConfig("17.0.0.0"))
+
+	changes, err := store.LoadUCS(backup, "17.0.0.0")
+	require.NoError(t, err)
+	require.Equal(t, []string{"added partition Prod", "added file /certs/app.crt"}, changes)
+	require.True(t, store.Fs.Exists(UCSDir+"/invalid.ucs"))
+
+	// The loaded configuration is saved
+	unsaved, err = store.UnsavedChanges()
+	require.NoError(t, err)
+	require.False(t, unsaved)
+	conf, _ := store.Fs.ReadFile(ConfigFile)
+	require.Contains(t, string(conf), "auth partition Prod {")
+
+	_, err = store.LoadUCS(UCSDir+"/invalid.ucs", "17.0.0.0")
+	require.ErrorContains(t, err, "invalid UCS archive")
+	_, err = store.LoadUCS(UCSDir+"/missing.ucs", "17.0.0.0")
+	require.Error(t, err)
+}
+
+func TestRestore_InvalidObjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects string
+		wantErr string
+	}{
+		{
+			name:    "invalid cipher rule",
+			objects: "cipher_rules:\n  - name: weak\n    cipher: NOT-A-CIPHER\n",
+			wantErr: "line 2: cipher rule /Common/weak",
+		},
+		{
+			name:    "admin role mapped to a partition",
+			objects: "partitions:\n  - name: Common\nlogin_providers:\n  - name: ldap\n    type: ldap\n    role_mapping:\n      - group: ops\n        role: admin\n        partition: Common\n",
+			wantErr: "line 4: login provider ldap: role admin of group ops can only be mapped to all partitions",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MemoryCaches{Fs: NewFS()}
+			store.Partitions = []*models.Partition{{Name: "Common"}, {Name: "Prod"}}
+
+			_, err := store.Restore(Snapshot{Objects: []byte(tt.objects)})
+			require.ErrorContains(t, err, tt.wantErr)
+			require.Len(t, store.Partitions, 2)
+		})
+	}
+}
+
+func TestReadUCS_UnsafePaths(t *testing.T) {
+	for _, name := range []string{"../etc/passwd", "/etc/passwd", "config/../../etc/passwd"} {
+		var archive bytes.Buffer
+		zw := gzip.NewWriter(&archive)
+		tw := tar.NewWriter(zw)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: 1, Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte("x"))
+		require.NoError(t, err)
+		require.NoError(t, tw.Close())
+		require.NoError(t, zw.Close())
+
+		err = readUCS(archive.Bytes(), func(*tar.Header, []byte) bool { return true })
+		require.ErrorContains(t, err, "unexpected file "+name)
+	}
+}
+
+func TestReadUCS_Size(t *testing.T) {
+	var archive bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&archive, gzip.BestSpeed)
+	require.NoError(t, err)
+	tw := tar.NewWriter(zw)
+	version := []byte("Product: BIG-IP\nVersion: 17.0.0\n")
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: ucsVersion, Mode: 0o644, Size: int64(len(version)), Typeflag: tar.TypeReg}))
+	_, err = tw.Write(version)
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "certs/large.crt", Mode: 0o644, Size: ucsMaxSize + 1, Typeflag: tar.TypeReg}))
+	_, err = io.CopyN(tw, zeroReader{}, ucsMaxSize+1)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+
+	err = readUCS(archive.Bytes(), func(*tar.Header, []byte) bool { return true })
+	require.ErrorContains(t, err, "invalid UCS archive: files are larger than")
+
+	store := &MemoryCaches{Fs: NewFS()}
+	_, _ = store.Fs.WriteFile(UCSDir+"/large.ucs", archive.Bytes())
+	_, err = store.LoadUCS(UCSDir+"/large.ucs", "17.0.0.0")
+	require.ErrorContains(t, err, "invalid UCS archive")
+
+	// Listing stops at the version
+	archives := store.ListUCS()
+	require.Len(t, archives, 1)
+	require.Equal(t, "17.0.0", archives[0].Version)
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,471 @@
+package cache
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iilun/f5-mock/internal/ciphers"
+	"github.com/iilun/f5-mock/internal/crypto"
+	"github.com/iilun/f5-mock/pkg/models"
+)
+
+// tmshEntry is a line of tmsh configuration, optionally followed by a block of nested entries.
+// When rendering, lines without block have nil entries.
+type tmshEntry struct {
+	words   []string
+	entries []*tmshEntry
+	line    int
+}
+
+type tmshToken struct {
+	value string
+	// quoted values are never braces nor newlines
+	quoted bool
+	line   int
+}
+
+func (t tmshToken) is(value string) bool {
+	return !t.quoted && t.value == value
+}
+
+// tokenizeTmsh splits tmsh configuration into words, quoted strings, braces and newlines, dropping comments
+func tokenizeTmsh(data string) ([]tmshToken, error) {
+	var tokens []tmshToken
+	line := 1
+
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '\n':
+			tokens = append(tokens, tmshToken{value: "\n", line: line})
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case c == '{' || c == '}':
+			tokens = append(tokens, tmshToken{value: string(c), line: line})
+			i++
+		case c == '"':
+			start := line
+			var value strings.Builder
+			for i++; ; i++ {
+				if i >= len(data) {
+					return nil, fmt.Errorf("line %d: unterminated quoted string", start)
+				}
+				if data[i] == '\\' && i+1 < len(data) {
+					i++
+				} else if data[i] == '"' {
+					i++
+					break
+				}
+				if data[i] == '\n' {
+					line++
+				}
+				value.WriteByte(data[i])
+			}
+			tokens = append(tokens, tmshToken{value: value.String(), quoted: true, line: start})
+		default:
+			start := i
+			for i < len(data) && !strings.ContainsRune(" \t\r\n{}\"", rune(data[i])) {
+				i++
+			}
+			tokens = append(tokens, tmshToken{value: data[start:i], line: line})
+		}
+	}
+
+	return tokens, nil
+}
+
+// parseTmsh parses tmsh configuration, like bigip.conf or SCF files, into entries
+func parseTmsh(data string) ([]*tmshEntry, error) {
+	tokens, err := tokenizeTmsh(data)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, rest, err := parseTmshEntries(tokens, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("line %d: unexpected }", rest[0].line)
+	}
+	return entries, nil
+}
+
+// parseTmshEntries parses entries up to the closing brace of the block opened on openLine,
+// or to the end for top-level entries, and returns the remaining tokens starting at that brace
+func parseTmshEntries(tokens []tmshToken, openLine int) ([]*tmshEntry, []tmshToken, error) {
+	var entries []*tmshEntry
+	var current *tmshEntry
+
+	for len(tokens) > 0 {
+		token := tokens[0]
+		tokens = tokens[1:]
+
+		switch {
+		case token.is("\n"):
+			current = nil
+		case token.is("}"):
+			if openLine == 0 {
+				return entries, append([]tmshToken{token}, tokens...), nil
+			}
+			return entries, tokens, nil
+		case token.is("{"):
+			if current == nil {
+				current = &tmshEntry{line: token.line}
+				entries = append(entries, current)
+			}
+
+			var err error
+			current.entries, tokens, err = parseTmshEntries(tokens, token.line)
+			if err != nil {
+				return nil, nil, err
+			}
+			// Entries following a block on the same line are distinct entries
+			current = nil
+		default:
+			if current == nil {
+				current = &tmshEntry{line: token.line}
+				entries = append(entries, current)
+			}
+			current.words = append(current.words, token.value)
+		}
+	}
+
+	if openLine != 0 {
+		return nil, nil, fmt.Errorf("line %d: missing }", openLine)
+	}
+	return entries, nil, nil
+}
+
+// property returns the value of a key, none values being empty
+func (e *tmshEntry) property(key string) string {
+	for _, entry := range e.entries {
+		// Several properties can share a line
+		for i := 0; i+1 < len(entry.words); i += 2 {
+			if entry.words[i] == key && entry.words[i+1] != "none" {
+				return entry.words[i+1]
+			}
+		}
+	}
+	return ""
+}
+
+// block returns the nested entries of a key
+func (e *tmshEntry) block(key string) []*tmshEntry {
+	for _, entry := range e.entries {
+		if len(entry.words) == 1 && entry.words[0] == key {
+			return entry.entries
+		}
+	}
+	return nil
+}
+
+// blockNames returns the names of the items of a key block, like profiles or members
+func (e *tmshEntry) blockNames(key string) []string {
+	var names []string
+	for _, entry := range e.block(key) {
+		if len(entry.words) > 0 {
+			names = append(names, entry.words[0])
+		}
+	}
+	return names
+}
+
+// splitTmshName splits /Partition/subPath/name, names without partition belonging to Common
+func splitTmshName(fullPath string) (string, string, string) {
+	segments := strings.Split(strings.TrimPrefix(fullPath, "/"), "/")
+	if len(segments) == 1 {
+		return CommonPartition, "", segments[0]
+	}
+	return segments[0], path.Join(segments[1 : len(segments)-1]...), segments[len(segments)-1]
+}
+
+// LoadSCF reads the objects the mock models from a bigip.conf or SCF file. Unsupported stanzas
+// are reported as warnings. Certificate and key contents are read from their source path when
+// it exists, and generated otherwise.
+func LoadSCF(filePath string) (SeedData, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return SeedData{}, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	entries, err := parseTmsh(string(data))
+	if err != nil {
+		return SeedData{}, fmt.Errorf("failed to parse tmsh configuration: %w", err)
+	}
+
+	seed := SeedData{files: map[string]seedContent{}}
+	var certs, keys []*tmshEntry
+
+	for _, entry := range entries {
+		words := entry.words
+		if len(words) == 0 {
+			continue
+		}
+		name := words[len(words)-1]
+		partition, subPath, shortName := splitTmshName(name)
+
+		switch strings.Join(words[:len(words)-1], " ") {
+		case "auth partition":
+			routeDomain, _ := strconv.Atoi(entry.property("default-route-domain"))
+			authPartition := &models.Partition{Name: name, Description: entry.property("description"), DefaultRouteDomain: routeDomain}
+			if err := checkSCFItem(entry, "partition", name, authPartition, nil); err != nil {
+				return SeedData{}, err
+			}
+			seed.Partitions = append(seed.Partitions, authPartition)
+		case "sys folder":
+			folder := &models.Folder{FullPath: name, Description: entry.property("description")}
+			if err := checkSCFItem(entry, "folder", name, folder, nil); err != nil {
+				return SeedData{}, err
+			}
+			seed.Folders = append(seed.Folders, folder)
+		case "ltm profile client-ssl":
+			profile := scfClientSSLProfile(entry, partition, subPath, shortName)
+			err := checkSCFItem(entry, "client-ssl profile", name, profile, func() error {
+				// Like with the API, none stands for no cipher string
+				if profile.Ciphers == "" || profile.Ciphers == "none" {
+					return nil
+				}
+				if err := ciphers.Validate(profile.Ciphers); err != nil {
+					return fmt.Errorf("ciphers: %w", err)
+				}
+				return nil
+			})
+			if err != nil {
+				return SeedData{}, err
+			}
+			hasPassphrase := slices.ContainsFunc(entry.block("cert-key-chain"), func(element *tmshEntry) bool {
+				return element.property("passphrase") != ""
+			})
+			if hasPassphrase || entry.property("passphrase") != "" {
+				seed.Warnings = append(seed.Warnings, fmt.Sprintf("line %d: passphrases of client-ssl profile %s are dropped, keys being imported unencrypted", entry.line, name))
+			}
+			seed.ClientSSLProfiles = append(seed.ClientSSLProfiles, profile)
+		case "ltm cipher rule":
+			rule := &models.CipherRule{
+				Name:                shortName,
+				Partition:           partition,
+				Description:         entry.property("description"),
+				Cipher:              entry.property("cipher"),
+				DHGroups:            entry.property("dh-groups"),
+				SignatureAlgorithms: entry.property("signature-algorithms"),
+			}
+			err := checkSCFItem(entry, "cipher rule", name, rule, func() error {
+				return ciphers.Validate(rule.Cipher)
+			})
+			if err != nil {
+				return SeedData{}, err
+			}
+			seed.CipherRules = append(seed.CipherRules, rule)
+		case "ltm cipher group":
+			if IsBuiltinCipherGroup(name) {
+				continue
+			}
+			group := &models.CipherGroup{
+				Name:        shortName,
+				Partition:   partition,
+				Description: entry.property("description"),
+				Ordering:    entry.property("ordering"),
+				Allow:       scfCipherReferences(entry.blockNames("allow")),
+				Exclude:     scfCipherReferences(entry.blockNames("exclude")),
+				Require:     scfCipherReferences(entry.blockNames("require")),
+			}
+			if err := checkSCFItem(entry, "cipher group", name, group, nil); err != nil {
+				return SeedData{}, err
+			}
+			seed.CipherGroups = append(seed.CipherGroups, group)
+		case "ltm virtual":
+			virtual := &models.VirtualServer{
+				Name:        shortName,
+				Partition:   partition,
+				SubPath:     subPath,
+				Description: entry.property("description"),
+				Destination: entry.property("destination"),
+				IPProtocol:  entry.property("ip-protocol"),
+				Pool:        entry.property("pool"),
+				Profiles:    entry.blockNames("profiles"),
+			}
+			if err := checkSCFItem(entry, "virtual server", name, virtual, nil); err != nil {
+				return SeedData{}, err
+			}
+			seed.VirtualServers = append(seed.VirtualServers, virtual)
+		case "ltm pool":
+			pool := &models.Pool{
+				Name:              shortName,
+				Partition:         partition,
+				SubPath:           subPath,
+				Description:       entry.property("description"),
+				LoadBalancingMode: entry.property("load-balancing-mode"),
+				Monitor:           entry.property("monitor"),
+			}
+			for _, member := range entry.block("members") {
+				if len(member.words) > 0 {
+					pool.Members = append(pool.Members, models.PoolMember{Name: member.words[0], Address: member.property("address")})
+				}
+			}
+			if err := checkSCFItem(entry, "pool", name, pool, nil); err != nil {
+				return SeedData{}, err
+			}
+			seed.Pools = append(seed.Pools, pool)
+		case "sys file ssl-cert":
+			certs = append(certs, entry)
+		case "sys file ssl-key":
+			keys = append(keys, entry)
+		default:
+			seed.Warnings = append(seed.Warnings, fmt.Sprintf("line %d: unsupported stanza %s", entry.line, strings.Join(words, " ")))
+		}
+	}
+
+	err = seed.importSCFFiles(certs, keys)
+	if err != nil {
+		return SeedData{}, err
+	}
+
+	return seed, nil
+}
+
+// checkSCFItem validates an imported object like checkSeedItems does for seed files, reporting the line of its stanza.
+// check may be nil when struct validation is enough.
+func checkSCFItem(entry *tmshEntry, kind, name string, item any, check func() error) error {
+	err := seedValidator.Struct(item)
+	if err == nil && check != nil {
+		err = check()
+	}
+	if err != nil {
+		return fmt.Errorf("line %d: %s %s: %w", entry.line, kind, name, err)
+	}
+	return nil
+}
+
+func scfClientSSLProfile(entry *tmshEntry, partition, subPath, name string) *models.ClientSSLProfile {
+	profile := &models.ClientSSLProfile{
+		Name:         name,
+		Partition:    partition,
+		SubPath:      subPath,
+		Cert:         entry.property("cert"),
+		Key:          entry.property("key"),
+		Ciphers:      entry.property("ciphers"),
+		DefaultsFrom: entry.property("defaults-from"),
+	}
+	profile.CipherGroup = entry.property("cipher-group")
+
+	for _, element := range entry.block("cert-key-chain") {
+		if len(element.words) == 0 || element.property("cert") == "" {
+			continue
+		}
+		profile.CertKeyChain = append(profile.CertKeyChain, models.ChainElement{
+			Name:  element.words[0],
+			Cert:  element.property("cert"),
+			Key:   element.property("key"),
+			Chain: element.property("chain"),
+		})
+	}
+
+	return profile
+}
+
+func scfCipherReferences(names []string) []models.CipherReference {
+	var refs []models.CipherReference
+	for _, name := range names {
+		refs = append(refs, models.CipherReference{Name: name})
+	}
+	return refs
+}
+
+// importSCFFiles reads or generates ssl-key and ssl-cert contents, generated certificates being
+// signed by the key they are paired with in client-ssl profiles
+func (s *SeedData) importSCFFiles(certs, keys []*tmshEntry) error {
+	pairs := map[string]string{}
+	for _, profile := range s.ClientSSLProfiles {
+		if profile.Cert != "" && profile.Key != "" {
+			pairs[profile.Cert] = profile.Key
+		}
+		for _, element := range profile.CertKeyChain {
+			if element.Key != "" {
+				pairs[element.Cert] = element.Key
+			}
+		}
+	}
+
+	keyContents := map[string][]byte{}
+	for _, entry := range keys {
+		name := entry.words[len(entry.words)-1]
+
+		content, found := s.readSCFSource(entry, "ssl-key", name)
+		if !found {
+			keySize, _ := strconv.Atoi(entry.property("key-size"))
+			var err error
+			content, err = crypto.GenerateKey(entry.property("key-type"), keySize, entry.property("curve-name"))
+			if err != nil {
+				return fmt.Errorf("line %d: ssl-key %s: %w", entry.line, name, err)
+			}
+		}
+
+		keyContents[name] = content
+		s.files[path.Join(keysDir, tmshPath(name))] = seedContent{content: content, generated: !found}
+	}
+
+	for _, entry := range certs {
+		name := entry.words[len(entry.words)-1]
+
+		content, found := s.readSCFSource(entry, "ssl-cert", name)
+		if !found {
+			key, paired := keyContents[pairs[name]]
+			if !paired {
+				var err error
+				key, err = crypto.GenerateKey(crypto.KeyTypeRSA, 0, "")
+				if err != nil {
+					return fmt.Errorf("line %d: ssl-cert %s: %w", entry.line, name, err)
+				}
+			}
+
+			var err error
+			content, err = crypto.CreateSelfSignedCertificate(key, crypto.Subject{CommonName: scfCommonName(entry, name)}, crypto.SubjectAlternativeNames{}, 365*24*time.Hour)
+			if err != nil {
+				return fmt.Errorf("line %d: ssl-cert %s: %w", entry.line, name, err)
+			}
+		}
+
+		s.files[path.Join(certsDir, tmshPath(name))] = seedContent{content: content, generated: !found}
+	}
+
+	return nil
+}
+
+// readSCFSource reads the contents of a file object from its source path. Unreadable source files are reported as
+// warnings, the contents being generated instead.
+func (s *SeedData) readSCFSource(entry *tmshEntry, kind, name string) ([]byte, bool) {
+	source, err := url.Parse(entry.property("source-path"))
+	if err != nil || source.Scheme != "file" {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(source.Path)
+	if err != nil {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("line %d: %s %s: source-path is unreadable, its contents are generated: %v", entry.line, kind, name, err))
+		return nil, false
+	}
+	return content, true
+}
+
+// scfCommonName returns the common name of the subject of a certificate object, its name by default
+func scfCommonName(entry *tmshEntry, name string) string {
+	for _, attribute := range strings.Split(entry.property("subject"), ",") {
+		if value, found := strings.CutPrefix(strings.TrimSpace(attribute), "CN="); found {
+			return value
+		}
+	}
+	return strings.TrimSuffix(path.Base(name), path.Ext(name))
+}
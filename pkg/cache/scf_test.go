@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iilun/f5-mock/internal/crypto"
+	"github.com/iilun/f5-mock/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSCF(t *testing.T) {
+	seed, err := LoadSeedData(filepath.Join("testdata", "bigip.conf"))
+	require.NoError(t, err)
+
+	require.Equal(t, []*models.Partition{{Name: "Sample_02", Description: "Sample partition", DefaultRouteDomain: 2}}, seed.Partitions)
+	require.Equal(t, []*models.Folder{{FullPath: "/Sample_02/app.app", Description: "iApp folder"}}, seed.Folders)
+	require.Equal(t, []*models.CipherRule{{Name: "no-sha1", Partition: "Common", Cipher: "ECDHE:!SHA1", Description: "No SHA1 suites"}}, seed.CipherRules)
+	require.Equal(t, []*models.CipherGroup{{
+		Name:      "f5-secure-custom",
+		Partition: "Common",
+		Ordering:  "speed",
+		Allow:     []models.CipherReference{{Name: "/Common/f5-secure"}},
+		Require:   []models.CipherReference{{Name: "/Common/no-sha1"}},
+	}}, seed.CipherGroups)
+
+	require.Equal(t, []*models.ClientSSLProfile{{
+		Name:         "web_clientssl",
+		Partition:    "Sample_02",
+		Cert:         "/Common/web.crt",
+		Key:          "/Common/web.key",
+		CertKeyChain: []models.ChainElement{{Name: "web", Cert: "/Common/web.crt", Key: "/Common/web.key"}},
+		CipherGroup:  "/Common/f5-secure-custom",
+		DefaultsFrom: "/Common/clientssl",
+	}}, seed.ClientSSLProfiles)
+
+	require.Equal(t, []*models.VirtualServer{{
+		Name:        "web_vs",
+		Partition:   "Common",
+		Description: `Main "web" virtual`,
+		Destination: "/Common/10.0.0.10:443",
+		IPProtocol:  "tcp",
+		Pool:        "/Common/web_pool",
+		Profiles:    []string{"/Common/http", "/Sample_02/web_clientssl", "/Common/tcp"},
+	}}, seed.VirtualServers)
+
+	require.Equal(t, []*models.Pool{{
+		Name:              "web_pool",
+		Partition:         "Common",
+		LoadBalancingMode: "least-connections-member",
+		Monitor:           "/Common/http",
+		Members:           []models.PoolMember{{Name: "/Common/10.0.0.1:80", Address: "10.0.0.1"}, {Name: "/Common/10.0.0.2:80", Address: "10.0.0.2"}},
+	}}, seed.Pools)
+
+	require.Equal(t, []string{
+		"line 29: passphrases of client-ssl profile /Sample_02/web_clientssl are dropped, keys being imported unencrypted",
+		"line 43: unsupported stanza ltm rule /Common/redirect",
+		"line 64: ssl-cert /Common/web.crt: source-path is unreadable, its contents are generated: open /nonexistent/web.crt: no such file or directory",
+	}, seed.Warnings)
+
+	// Missing sources are generated, certificates being signed by their paired key
+	cert, err := crypto.ParsePemCertificate(seed.files["/certs/Common/web.crt"].content)
+	require.NoError(t, err)
+	require.Equal(t, "www.example.com", cert.Subject.CommonName)
+
+	key, err := crypto.ParsePemPrivateKey(seed.files["/keys/Common/web.key"].content)
+	require.NoError(t, err)
+	require.True(t, crypto.KeyMatchesCertificate(cert, key))
+	keyInfo, err := crypto.GetKeyInfo(key)
+	require.NoError(t, err)
+	require.Equal(t, crypto.KeyTypeEC, keyInfo.Type)
+}
+
+func TestLoadSCF_SourcePath(t *testing.T) {
+	dir := t.TempDir()
+
+	key, err := crypto.GenerateKey(crypto.KeyTypeRSA, 2048, "")
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, "source.key")
+	require.NoError(t, os.WriteFile(keyPath, key, 0o600))
+
+	confPath := filepath.Join(dir, "bigip.conf")
+	require.NoError(t, os.WriteFile(confPath, []byte("sys file ssl-key /Common/source.key {\n    source-path file://"+keyPath+"\n}\n"), 0o600))
+
+	seed, err := LoadSCF(confPath)
+	require.NoError(t, err)
+	require.Equal(t, seedContent{content: key}, seed.files["/keys/Common/source.key"])
+}
+
+func TestLoadSCF_InvalidObjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    string
+		wantErr string
+	}{
+		{"partition", "auth partition Sample_02 { }\nauth partition Bad~Name { }\n", "line 2: partition Bad~Name:"},
+		{"folder", "sys folder relative { }\n", "line 1: folder relative:"},
+		{"client-ssl profile", "ltm profile client-ssl /Common/ {\n    cert /Common/app.crt\n}\n", "line 1: client-ssl profile /Common/:"},
+		{"client-ssl profile ciphers", "ltm profile client-ssl /Common/app {\n    ciphers ECDHE:BOGUS\n}\n", "line 1: client-ssl profile /Common/app: ciphers: invalid cipher string 'ECDHE:BOGUS'"},
+		{"cipher rule", "ltm cipher rule /Common/bad {\n    cipher ECDHE:BOGUS\n}\n", "line 1: cipher rule /Common/bad: invalid cipher string 'ECDHE:BOGUS'"},
+		{"virtual server", "ltm virtual /Common/ {\n    destination /Common/10.0.0.1:443\n}\n", "line 1: virtual server /Common/:"},
+		{"pool member", "ltm pool /Common/p { }\nltm pool /Common/web {\n    members {\n        \"\" { }\n    }\n}\n", "line 2: pool /Common/web:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confPath := filepath.Join(t.TempDir(), "bigip.conf")
+			require.NoError(t, os.WriteFile(confPath, []byte(tt.conf), 0o600))
+
+			_, err := LoadSCF(confPath)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParseTmsh_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    string
+		wantErr string
+	}{
+		{"missing brace", "ltm pool /Common/p {\n    members {\n}\n", "line 1: missing }"},
+		{"unexpected brace", "ltm pool /Common/p {\n}\n}\n", "line 3: unexpected }"},
+		{"unterminated string", "ltm pool /Common/p {\n    description \"open\n}\n", "line 2: unterminated quoted string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTmsh(tt.conf)
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
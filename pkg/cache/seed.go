@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/iilun/f5-mock/internal/crypto"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// UploadsDir holds uploaded files, from which files are installed
+	UploadsDir = "/var/config/rest/downloads"
+
+	certsDir = "/certs"
+	keysDir  = "/keys"
+)
+
+var seedValidator = validator.New(validator.WithRequiredStructEnabled())
+
+// SeedCertificate is a certificate stored under /certs, given inline, read from disk or generated
+type SeedCertificate struct {
+	Name     string               `yaml:"name" validate:"required"`
+	PEM      string               `yaml:"pem"`
+	Path     string               `yaml:"path"`
+	Generate *SeedCertificateSpec `yaml:"generate"`
+}
+
+// SeedCertificateSpec describes a self-signed certificate generated on load, along with its key
+type SeedCertificateSpec struct {
+	CommonName string `yaml:"common_name" validate:"required"`
+	// KeyName is the name of the generated key, the certificate name by default
+	KeyName      string `yaml:"key_name"`
+	KeyType      string `yaml:"key_type" validate:"omitempty,oneof=rsa-private ec-private"`
+	KeySize      int    `yaml:"key_size"`
+	Curve        string `yaml:"curve"`
+	LifetimeDays int    `yaml:"lifetime_days" validate:"gte=0"`
+}
+
+// SeedKey is a private key stored under /keys, given inline or read from disk
+type SeedKey struct {
+	Name string `yaml:"name" validate:"required"`
+	PEM  string `yaml:"pem"`
+	Path string `yaml:"path"`
+}
+
+// SeedFile is an uploaded file stored under /var/config/rest/downloads, given inline or read from disk
+type SeedFile struct {
+	Name    string `yaml:"name" validate:"required"`
+	Content string `yaml:"content"`
+	Path    string `yaml:"path"`
+}
+
+type seedContent struct {
+	content []byte
+	// generated contents are kept when the file already exists, instead of changing on every reload
+	generated bool
+}
+
+// resolveFiles validates the seeded certificates, keys and files and computes their contents,
+// relative paths being read from dir
+func (s *SeedData) resolveFiles(root *yaml.Node, dir string) error {
+	s.files = map[string]seedContent{}
+
+	err := checkSeedItems(root, "certificates", "certificate", s.Certificates, func(cert SeedCertificate) string {
+		return cert.Name
+	}, func(cert SeedCertificate) error {
+		return s.resolveCertificate(cert, dir)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkSeedItems(root, "keys", "key", s.Keys, func(key SeedKey) string {
+		return key.Name
+	}, func(key SeedKey) error {
+		content, err := readSeedSource(key.PEM, key.Path, dir)
+		if err != nil {
+			return err
+		}
+		if !crypto.IsEncryptedPemKey(content) {
+			if _, err := crypto.ParsePemPrivateKey(content); err != nil {
+				return fmt.Errorf("invalid key: %w", err)
+			}
+		}
+		s.files[path.Join(keysDir, tmshPath(key.Name))] = seedContent{content: content}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return checkSeedItems(root, "files", "file", s.Files, func(file SeedFile) string {
+		return file.Name
+	}, func(file SeedFile) error {
+		content, err := readSeedSource(file.Content, file.Path, dir)
+		if err != nil {
+			return err
+		}
+		s.files[path.Join(UploadsDir, file.Name)] = seedContent{content: content}
+		return nil
+	})
+}
+
+func (s *SeedData) resolveCertificate(cert SeedCertificate, dir string) error {
+	if cert.Generate == nil {
+		content, err := readSeedSource(cert.PEM, cert.Path, dir)
+		if err != nil {
+			return err
+		}
+		if _, err := crypto.ParsePemCertificates(content); err != nil {
+			return fmt.Errorf("invalid certificate: %w", err)
+		}
+		s.files[path.Join(certsDir, tmshPath(cert.Name))] = seedContent{content: content}
+		return nil
+	}
+
+	if cert.PEM != "" || cert.Path != "" {
+		return errors.New("only one of pem, path or generate can be set")
+	}
+
+	spec := cert.Generate
+	key, err := crypto.GenerateKey(spec.KeyType, spec.KeySize, spec.Curve)
+	if err != nil {
+		return err
+	}
+
+	lifetime := spec.LifetimeDays
+	if lifetime == 0 {
+		lifetime = 365
+	}
+	content, err := crypto.CreateSelfSignedCertificate(key, crypto.Subject{CommonName: spec.CommonName}, crypto.SubjectAlternativeNames{DNSNames: []string{spec.CommonName}}, time.Duration(lifetime)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	keyName := spec.KeyName
+	if keyName == "" {
+		keyName = cert.Name
+	}
+	s.files[path.Join(certsDir, tmshPath(cert.Name))] = seedContent{content: content, generated: true}
+	s.files[path.Join(keysDir, tmshPath(keyName))] = seedContent{content: key, generated: true}
+	return nil
+}
+
+// readSeedSource returns inline contents, or the contents of a file relative to dir
+func readSeedSource(inline, filePath, dir string) ([]byte, error) {
+	switch {
+	case inline != "" && filePath != "":
+		return nil, errors.New("only one of the inline contents or path can be set")
+	case filePath != "":
+		if !filepath.IsAbs(filePath) {
+			filePath = filepath.Join(dir, filePath)
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		return content, nil
+	case inline != "":
+		return []byte(inline), nil
+	default:
+		return nil, errors.New("contents or path are required")
+	}
+}
+
+// checkSeedItems validates the items of a top-level seed list, reporting the line of the first invalid one
+// check may be nil when struct validation is enough.
+func checkSeedItems[T any](root *yaml.Node, key, kind string, items []T, name func(T) string, check func(T) error) error {
+	for i, item := range items {
+		err := seedValidator.Struct(item)
+		if err == nil && check != nil {
+			err = check(item)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %s %s: %w", seedLine(root, key, i), kind, name(item), err)
+		}
+	}
+	return nil
+}
+
+// seedLine returns the line of the i-th item of a top-level seed list, 0 when unknown
+func seedLine(root *yaml.Node, key string, i int) int {
+	if len(root.Content) == 0 {
+		return 0
+	}
+
+	mapping := root.Content[0]
+	for j := 0; j+1 < len(mapping.Content); j += 2 {
+		if mapping.Content[j].Value != key {
+			continue
+		}
+		value := mapping.Content[j+1]
+		if i < 0 {
+			return value.Line
+		}
+		if i < len(value.Content) {
+			return value.Content[i].Line
+		}
+	}
+	return 0
+}
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/google/uuid"
+	"github.com/iilun/f5-mock/pkg/models"
+)
+
+const (
+	// DefaultTokenTimeout is the lifetime in seconds of a new token
+	DefaultTokenTimeout = 1200
+	// MaxTokenTimeout is the maximum lifetime in seconds a token can be extended to
+	MaxTokenTimeout = 36000
+
+	tokenStartTimeFormat = "2006-01-02T15:04:05.000-0700"
+)
+
+var (
+	ErrTokenNotFound = errors.New("token not found")
+	ErrTokenExpired  = errors.New("token expired")
+)
+
+// NewAuthToken creates a token for userName valid for DefaultTokenTimeout seconds
+func NewAuthToken(userName, authProviderName, address string) models.AuthToken {
+	// startTime only has a millisecond precision
+	now := time.Now().Truncate(time.Millisecond)
+	token := uuid.New().String()
+
+	return models.AuthToken{
+		Token:            token,
+		Name:             token,
+		UserName:         userName,
+		AuthProviderName: authProviderName,
+		Address:          address,
+		Timeout:          DefaultTokenTimeout,
+		StartTime:        now.Format(tokenStartTimeFormat),
+		LastUpdateMicros: now.UnixMicro(),
+		ExpirationMicros: now.Add(DefaultTokenTimeout * time.Second).UnixMicro(),
+		Kind:             "shared:authz:tokens:authtokenitemstate",
+		SelfLink:         "https://localhost/mgmt/shared/authz/tokens/" + token,
+	}
+}
+
+// SetTokenTimeout changes the token lifetime, counted from its start time
+func SetTokenTimeout(token *models.AuthToken, timeout int) error {
+	startTime, err := time.Parse(tokenStartTimeFormat, token.StartTime)
+	if err != nil {
+		return err
+	}
+
+	token.Timeout = timeout
+	token.LastUpdateMicros = time.Now().UnixMicro()
+	token.ExpirationMicros = startTime.Add(time.Duration(timeout) * time.Second).UnixMicro()
+	return nil
+}
+
+// SetToken stores token, replacing any previous version of it
+func (c *MemoryCaches) SetToken(token models.AuthToken) error {
+	bytes, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return c.AuthTokens.Set(token.Token, bytes)
+}
+
+// GetToken returns the token, failing if it does not exist or has expired
+func (c *MemoryCaches) GetToken(token string) (models.AuthToken, error) {
+	bytes, err := c.AuthTokens.Get(token)
+	if err != nil {
+		if errors.Is(err, bigcache.ErrEntryNotFound) {
+			return models.AuthToken{}, ErrTokenNotFound
+		}
+		return models.AuthToken{}, err
+	}
+
+	var authToken models.AuthToken
+	err = json.Unmarshal(bytes, &authToken)
+	if err != nil {
+		return models.AuthToken{}, err
+	}
+
+	if time.Now().UnixMicro() > authToken.ExpirationMicros {
+		_ = c.AuthTokens.Delete(token)
+		return models.AuthToken{}, ErrTokenExpired
+	}
+
+	return authToken, nil
+}
+
+// DeleteToken revokes token
+func (c *MemoryCaches) DeleteToken(token string) error {
+	err := c.AuthTokens.Delete(token)
+	if errors.Is(err, bigcache.ErrEntryNotFound) {
+		return ErrTokenNotFound
+	}
+	return err
+}
+
+// ListTokens returns all the tokens that have not expired yet
+func (c *MemoryCaches) ListTokens() ([]models.AuthToken, error) {
+	tokens := []models.AuthToken{}
+
+	iterator := c.AuthTokens.Iterator()
+	for iterator.SetNext() {
+		entry, err := iterator.Value()
+		if err != nil {
+			return nil, err
+		}
+
+		authToken, err := c.GetToken(entry.Key())
+		if err != nil {
+			continue
+		}
+		tokens = append(tokens, authToken)
+	}
+
+	return tokens, nil
+}
@@ -1,17 +1,27 @@
 package models
 
+import (
+	"path"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
 type ChainElement struct {
-	Cert  string `json:"cert" yaml:"cert" validate:"required"`
-	Name  string `json:"name" yaml:"name"`
-	Key   string `json:"key" yaml:"key"`
-	Chain string `json:"chain,omitempty" yaml:"chain,omitempty"`
+	Cert       string `json:"cert" yaml:"cert" validate:"required"`
+	Name       string `json:"name" yaml:"name"`
+	Key        string `json:"key" yaml:"key"`
+	Chain      string `json:"chain,omitempty" yaml:"chain,omitempty"`
+	Passphrase string `json:"passphrase,omitempty" yaml:"passphrase,omitempty"`
 }
 
 type ClientSSLProfile struct {
 	Name         string         `json:"name" yaml:"name" validate:"required"`
 	Partition    string         `json:"partition" yaml:"partition" validate:"required"`
+	SubPath      string         `json:"subPath,omitempty" yaml:"sub_path"`
 	Cert         string         `json:"cert" yaml:"cert"`
 	Key          string         `json:"key" yaml:"key"`
+	Passphrase   string         `json:"passphrase,omitempty" yaml:"passphrase,omitempty"`
 	CertKeyChain []ChainElement `json:"certKeyChain" yaml:"cert_key_chain"`
 	CipherGroup  string         `json:"cipherGroup" yaml:"cipher_group"`
 	Ciphers      string         `json:"ciphers" yaml:"ciphers"`
@@ -19,7 +29,179 @@ type ClientSSLProfile struct {
 	SelfLink     string         `json:"selfLink"`
 }
 
+type VirtualServer struct {
+	Name        string   `json:"name" yaml:"name" validate:"required"`
+	Partition   string   `json:"partition" yaml:"partition" validate:"required"`
+	SubPath     string   `json:"subPath,omitempty" yaml:"sub_path"`
+	Description string   `json:"description,omitempty" yaml:"description"`
+	Destination string   `json:"destination" yaml:"destination"`
+	IPProtocol  string   `json:"ipProtocol,omitempty" yaml:"ip_protocol"`
+	Pool        string   `json:"pool,omitempty" yaml:"pool"`
+	Profiles    []string `json:"profiles,omitempty" yaml:"profiles"`
+}
+
+type Pool struct {
+	Name              string       `json:"name" yaml:"name" validate:"required"`
+	Partition         string       `json:"partition" yaml:"partition" validate:"required"`
+	SubPath           string       `json:"subPath,omitempty" yaml:"sub_path"`
+	Description       string       `json:"description,omitempty" yaml:"description"`
+	LoadBalancingMode string       `json:"loadBalancingMode,omitempty" yaml:"load_balancing_mode"`
+	Monitor           string       `json:"monitor,omitempty" yaml:"monitor"`
+	Members           []PoolMember `json:"members,omitempty" yaml:"members" validate:"dive"`
+}
+
+type PoolMember struct {
+	Name    string `json:"name" yaml:"name" validate:"required"`
+	Address string `json:"address" yaml:"address"`
+}
+
+// FullPath returns the /partition/subPath/name path of an object, the sub path being the optional
+// folders between the partition and the object, like app.app
+func FullPath(partition, subPath, name string) string {
+	return path.Join("/", partition, subPath, name)
+}
+
+// CipherRule is a named cipher string, with the DH groups and signature algorithms it allows
+type CipherRule struct {
+	Name                string `json:"name" yaml:"name" validate:"required,excludesall=/~ "`
+	Partition           string `json:"partition" yaml:"partition"`
+	Description         string `json:"description,omitempty" yaml:"description"`
+	Cipher              string `json:"cipher" yaml:"cipher" validate:"required"`
+	DHGroups            string `json:"dhGroups,omitempty" yaml:"dh_groups"`
+	SignatureAlgorithms string `json:"signatureAlgorithms,omitempty" yaml:"signature_algorithms"`
+}
+
+// CipherGroup combines cipher rules: the suites of the allowed rules, minus the excluded ones, that are in
+// every required rule
 type CipherGroup struct {
-	Kind string `json:"kind"`
-	Name string `json:"name"`
+	Name        string            `json:"name" yaml:"name" validate:"required,excludesall=/~ "`
+	Partition   string            `json:"partition" yaml:"partition"`
+	Description string            `json:"description,omitempty" yaml:"description"`
+	Ordering    string            `json:"ordering,omitempty" yaml:"ordering" validate:"omitempty,oneof=default speed strength"`
+	Allow       []CipherReference `json:"allow,omitempty" yaml:"allow" validate:"dive"`
+	Exclude     []CipherReference `json:"exclude,omitempty" yaml:"exclude" validate:"dive"`
+	Require     []CipherReference `json:"require,omitempty" yaml:"require" validate:"dive"`
+}
+
+// UnmarshalYAML also accepts the bare name of a group, as in seed files listing the available groups
+func (g *CipherGroup) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*g = CipherGroup{Name: node.Value}
+		return nil
+	}
+	type plain CipherGroup
+	return node.Decode((*plain)(g))
+}
+
+// CipherReference references a cipher rule by full path, or by name in Common
+type CipherReference struct {
+	Name string `json:"name" yaml:"name" validate:"required"`
+}
+
+// UnmarshalYAML also accepts the bare name of a rule
+func (ref *CipherReference) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		ref.Name = node.Value
+		return nil
+	}
+	type plain CipherReference
+	return node.Decode((*plain)(ref))
+}
+
+type AuthToken struct {
+	Token            string `json:"token"`
+	Name             string `json:"name"`
+	UserName         string `json:"userName"`
+	AuthProviderName string `json:"authProviderName"`
+	Address          string `json:"address"`
+	Timeout          int    `json:"timeout"`
+	StartTime        string `json:"startTime"`
+	LastUpdateMicros int64  `json:"lastUpdateMicros"`
+	ExpirationMicros int64  `json:"expirationMicros"`
+	Kind             string `json:"kind"`
+	SelfLink         string `json:"selfLink"`
+}
+
+const (
+	RoleAdmin              = "admin"
+	RoleManager            = "manager"
+	RoleCertificateManager = "certificate-manager"
+	RoleOperator           = "operator"
+	RoleGuest              = "guest"
+
+	// AllPartitions is the partition access name granting a role on every partition
+	AllPartitions = "all-partitions"
+)
+
+type Partition struct {
+	Name               string `json:"name" yaml:"name" validate:"required,excludesall=/~ "`
+	Description        string `json:"description,omitempty" yaml:"description"`
+	DefaultRouteDomain int    `json:"defaultRouteDomain" yaml:"default_route_domain" validate:"gte=0"`
+}
+
+// Folder is a folder under a partition, like the folder of an iApp
+type Folder struct {
+	// FullPath is the path of the folder, e.g. /Sample_02/app.app
+	FullPath    string `json:"fullPath" yaml:"full_path" validate:"required,startswith=/,excludesall=~ "`
+	Description string `json:"description,omitempty" yaml:"description"`
+}
+
+type PartitionAccess struct {
+	Name string `json:"name" yaml:"name" validate:"required"`
+	Role string `json:"role" yaml:"role" validate:"required,oneof=admin manager certificate-manager operator guest"`
+}
+
+type User struct {
+	Name            string            `json:"name" yaml:"name" validate:"required"`
+	Password        string            `json:"password,omitempty" yaml:"password"`
+	Description     string            `json:"description,omitempty" yaml:"description"`
+	PartitionAccess []PartitionAccess `json:"partitionAccess" yaml:"partition_access" validate:"required,min=1,dive"`
+	// PasswordExpired forces a password change on the next login
+	PasswordExpired bool `json:"passwordExpired,omitempty" yaml:"password_expired"`
+	// PasswordChanged is the start of the password max duration, passwords without it never expire
+	PasswordChanged time.Time `json:"-" yaml:"password_changed"`
+	Locked          bool      `json:"locked,omitempty" yaml:"locked"`
+	LockedUntil     time.Time `json:"-" yaml:"-"`
+	FailedLogins    int       `json:"-" yaml:"-"`
+}
+
+type PasswordPolicy struct {
+	PolicyEnforcement string `json:"policyEnforcement" yaml:"policy_enforcement" validate:"omitempty,oneof=enabled disabled"`
+	MinimumLength     int    `json:"minimumLength" yaml:"minimum_length" validate:"gte=0"`
+	RequiredLowercase int    `json:"requiredLowercase" yaml:"required_lowercase" validate:"gte=0"`
+	RequiredUppercase int    `json:"requiredUppercase" yaml:"required_uppercase" validate:"gte=0"`
+	RequiredNumeric   int    `json:"requiredNumeric" yaml:"required_numeric" validate:"gte=0"`
+	RequiredSpecial   int    `json:"requiredSpecial" yaml:"required_special" validate:"gte=0"`
+	// MaxDuration is the password lifetime in days, 0 to disable
+	MaxDuration int `json:"maxDuration" yaml:"max_duration" validate:"gte=0"`
+	// MaxLoginFailures locks accounts after that many failed logins, 0 to disable
+	MaxLoginFailures int `json:"maxLoginFailures" yaml:"max_login_failures" validate:"gte=0"`
+	// LockoutDuration is in seconds, 0 keeps accounts locked until an administrator unlocks them
+	LockoutDuration int `json:"lockoutDuration" yaml:"lockout_duration" validate:"gte=0"`
+}
+
+// LoginProvider is a stand-in for a remote authentication server (LDAP, RADIUS, TACACS+)
+type LoginProvider struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+	// CredentialsFile is a YAML list of RemoteUser, merged with Users
+	CredentialsFile  string        `yaml:"credentials_file"`
+	Users            []*RemoteUser `yaml:"users"`
+	RoleMapping      []RoleMapping `yaml:"role_mapping" validate:"dive"`
+	DefaultRole      string        `yaml:"default_role" validate:"omitempty,oneof=admin manager certificate-manager operator guest"`
+	DefaultPartition string        `yaml:"default_partition"`
+}
+
+type RemoteUser struct {
+	Name     string   `yaml:"name"`
+	Password string   `yaml:"password"`
+	Groups   []string `yaml:"groups"`
+	Locked   bool     `yaml:"locked"`
+}
+
+// RoleMapping grants a role to the members of a remote group, like remote-role role-info entries
+type RoleMapping struct {
+	Group     string `yaml:"group" validate:"required"`
+	Role      string `yaml:"role" validate:"required,oneof=admin manager certificate-manager operator guest"`
+	Partition string `yaml:"partition"`
 }